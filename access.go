@@ -0,0 +1,181 @@
+package yagaw
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Pho3b/tiny-logger/logs"
+	"github.com/Pho3b/tiny-logger/logs/log_level"
+)
+
+// ----------- ACCESS LOG -----------
+
+type accessLogSettings struct {
+	level   log_level.LogLvlName
+	exclude []string
+}
+
+// EnableAccessLog logs every request the server serves through Log once answered, 404s
+// and recovered panics included, with its method, path, matched route, status, body bytes,
+// remote address and duration. Requests are logged at the info level unless set otherwise,
+// Log must be at that level for them to show.
+func (s *Server) EnableAccessLog() *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessLogSettings()
+	s.composeHandler()
+	return s
+}
+
+// AccessLogLevel sets the level of the access log lines, e.g. log_level.DebugLvlName
+func (s *Server) AccessLogLevel(level log_level.LogLvlName) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessLogSettings().level = level
+	s.composeHandler()
+	return s
+}
+
+// AccessLogExclude leaves out of the access log the requests to the given paths, e.g. the
+// health endpoints polled by load balancers.
+func (s *Server) AccessLogExclude(paths ...string) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accessLog := s.accessLogSettings()
+	accessLog.exclude = append(accessLog.exclude, paths...)
+	s.composeHandler()
+	return s
+}
+
+func (s *Server) accessLogSettings() *accessLogSettings {
+	if s.accessLog == nil {
+		s.accessLog = &accessLogSettings{level: log_level.InfoLvlName}
+	}
+	return s.accessLog
+}
+
+// logAccess logs the requests served by next, the settings are the ones of the moment the
+// handler is composed.
+func (settings accessLogSettings) logAccess(next http.Handler) http.Handler {
+	level, exclude := settings.level, slices.Clone(settings.exclude)
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if slices.Contains(exclude, req.URL.Path) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+
+		entry := serveRecorded(next, rw, req)
+		route := entry.Route
+		if route == "" {
+			route = "-"
+		}
+		logAt(level, fmt.Sprintf("%s %s route=%s status=%d bytes=%d remote=%s duration=%s",
+			entry.Method, entry.Path, route, entry.Status, entry.Bytes, req.RemoteAddr, entry.Duration))
+	})
+}
+
+// AccessEntry describes an answered request, as given to the AccessLog formatter
+type AccessEntry struct {
+	Method string
+	// Path is the request URI as sent by the client, query included
+	Path string
+	// Route is the pattern of the matched route, empty when none matched
+	Route     string
+	Status    int
+	Bytes     int64
+	Duration  time.Duration
+	RemoteIP  string
+	UserAgent string
+}
+
+type accessLogMiddleware struct {
+	format func(entry AccessEntry) string
+	skip   []string
+}
+
+// AccessLogOption configures the middleware returned by AccessLog
+type AccessLogOption func(settings *accessLogMiddleware)
+
+// AccessLogFormatter sets the function turning the entries into log lines,
+// FormatAccessEntry unless set.
+func AccessLogFormatter(format func(entry AccessEntry) string) AccessLogOption {
+	return func(settings *accessLogMiddleware) {
+		settings.format = format
+	}
+}
+
+// AccessLogSkip leaves the requests to the given paths out, e.g. the health endpoints
+func AccessLogSkip(paths ...string) AccessLogOption {
+	return func(settings *accessLogMiddleware) {
+		settings.skip = append(settings.skip, paths...)
+	}
+}
+
+// AccessLog returns server middleware logging every request it wraps at the info level of
+// the logger, Log when nil, once answered. The client address is the one resolved through
+// the trusted proxies, and the route is the pattern matched by the router.
+func AccessLog(logger *logs.Logger, opts ...AccessLogOption) func(http.Handler) http.Handler {
+	settings := &accessLogMiddleware{format: FormatAccessEntry}
+	for _, opt := range opts {
+		opt(settings)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if slices.Contains(settings.skip, req.URL.Path) {
+				next.ServeHTTP(rw, req)
+				return
+			}
+			entry := serveRecorded(next, rw, req)
+			target := logger
+			if target == nil {
+				target = Log
+			}
+			target.Info(settings.format(entry))
+		})
+	}
+}
+
+// FormatAccessEntry formats the entry as `GET /users/42 route=/users/{id} status=200
+// bytes=7 duration=1.2ms ip=192.0.2.1 ua="curl/8.0"`, the route being `-` when none matched.
+func FormatAccessEntry(entry AccessEntry) string {
+	route := entry.Route
+	if route == "" {
+		route = "-"
+	}
+	return fmt.Sprintf("%s %s route=%s status=%d bytes=%d duration=%s ip=%s ua=%q",
+		entry.Method, entry.Path, route, entry.Status, entry.Bytes, entry.Duration, entry.RemoteIP, entry.UserAgent)
+}
+
+// serveRecorded serves the request and describes how it was answered
+func serveRecorded(next http.Handler, rw http.ResponseWriter, req *http.Request) AccessEntry {
+	started := time.Now()
+	recorder := NewStatusRecorder(rw)
+	next.ServeHTTP(recorder, req)
+
+	return AccessEntry{
+		Method:    req.Method,
+		Path:      req.URL.RequestURI(),
+		Route:     req.Pattern,
+		Status:    recorder.Status(),
+		Bytes:     recorder.BytesWritten(),
+		Duration:  time.Since(started),
+		RemoteIP:  ClientIP(req),
+		UserAgent: req.UserAgent(),
+	}
+}
+
+func logAt(level log_level.LogLvlName, line string) {
+	switch level {
+	case log_level.DebugLvlName:
+		Log.Debug(line)
+	case log_level.WarnLvlName:
+		Log.Warn(line)
+	case log_level.ErrorLvlName:
+		Log.Error(line)
+	default:
+		Log.Info(line)
+	}
+}
@@ -0,0 +1,182 @@
+package yagaw
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Pho3b/tiny-logger/logs/log_level"
+)
+
+// captureLog makes Log write to a file at the given level and returns a function reading it
+func captureLog(t *testing.T, level log_level.LogLvlName) func() string {
+	file, err := os.Create(filepath.Join(t.TempDir(), "yagaw.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	previous := Log
+	Log = InitLogger(level).EnableColors(false).SetLogFile(file)
+	t.Cleanup(func() {
+		Log = previous
+		file.Close()
+	})
+
+	return func() string {
+		content, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return string(content)
+	}
+}
+
+func TestServerAccessLog(t *testing.T) {
+	newServer := func() *Server {
+		server := NewServer("127.0.0.1", 0).EnableHealth("/healthz", "")
+		server.GetRouter().RegisterRoute(GET, "/users/{id:int}", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody("user " + PathParam(req, "id"))
+		})
+		server.GetRouter().RegisterRoute(GET, "/panic", func(req *http.Request, params Params) *HttpResponse {
+			panic("broken handler")
+		})
+		return server
+	}
+	serve := func(server *Server, path string) {
+		req := httptest.NewRequest(string(GET), path, nil)
+		req.RemoteAddr = "192.0.2.1:5555"
+		server.serveHTTP(httptest.NewRecorder(), req)
+	}
+	lineOf := func(t *testing.T, logged string, prefix string) string {
+		for _, line := range strings.Split(logged, "\n") {
+			if strings.Contains(line, "]: "+prefix) {
+				return line
+			}
+		}
+		t.Fatalf("expected a line for %q, got\n%s", prefix, logged)
+		return ""
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		read := captureLog(t, log_level.InfoLvlName)
+		server := newServer().EnableAccessLog()
+		for _, path := range []string{"/users/42?verbose=1", "/missing", "/panic"} {
+			serve(server, path)
+		}
+		logged := read()
+
+		tests := []struct {
+			request string
+			fields  []string
+		}{
+			{"GET /users/42?verbose=1 ", []string{"route=/users/{id:int}", "status=200", "bytes=7", "remote=192.0.2.1:5555", "duration="}},
+			{"GET /missing ", []string{"route=-", "status=404", "bytes=20", "remote=192.0.2.1:5555"}},
+			{"GET /panic ", []string{"route=/panic", "status=500"}},
+		}
+		for _, tt := range tests {
+			line := lineOf(t, logged, tt.request)
+			for _, field := range tt.fields {
+				if !strings.Contains(line, field) {
+					t.Errorf("expected %q in %q", field, line)
+				}
+			}
+		}
+	})
+
+	t.Run("excluded paths", func(t *testing.T) {
+		read := captureLog(t, log_level.InfoLvlName)
+		server := newServer().EnableAccessLog().AccessLogExclude("/healthz")
+		serve(server, "/healthz")
+		serve(server, "/users/1")
+		if logged := read(); strings.Contains(logged, "/healthz") || !strings.Contains(logged, "/users/1") {
+			t.Errorf("expected only the route to be logged, got\n%s", logged)
+		}
+	})
+
+	t.Run("level", func(t *testing.T) {
+		read := captureLog(t, log_level.InfoLvlName)
+		server := newServer().EnableAccessLog().AccessLogLevel(log_level.DebugLvlName)
+		serve(server, "/users/1")
+		if logged := read(); strings.Contains(logged, "/users/1") {
+			t.Errorf("expected debug lines to be filtered out at the info level, got\n%s", logged)
+		}
+
+		read = captureLog(t, log_level.DebugLvlName)
+		serve(server, "/users/2")
+		if logged := read(); !strings.Contains(logged, "GET /users/2 ") {
+			t.Errorf("expected the request at the debug level, got\n%s", logged)
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		read := captureLog(t, log_level.DebugLvlName)
+		serve(newServer(), "/users/1")
+		if logged := read(); strings.Contains(logged, "route=") {
+			t.Errorf("expected no access log, got\n%s", logged)
+		}
+	})
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	newServer := func(opts ...AccessLogOption) *Server {
+		server := NewServer("127.0.0.1", 0).Use(AccessLog(Log, opts...))
+		server.GetRouter().RegisterRoute(GET, "/users/{id:int}", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody("user " + PathParam(req, "id"))
+		})
+		server.GetRouter().Mount("/implicit", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte("no status"))
+		}))
+		server.GetRouter().RegisterRoute(GET, "/healthz", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK)
+		})
+		return server
+	}
+	serve := func(server *Server, path string) {
+		req := httptest.NewRequest(string(GET), path, nil)
+		req.RemoteAddr = "192.0.2.1:5555"
+		req.Header.Set("User-Agent", "curl/8.0")
+		server.serveHTTP(httptest.NewRecorder(), req)
+	}
+
+	t.Run("default format", func(t *testing.T) {
+		read := captureLog(t, log_level.InfoLvlName)
+		server := newServer()
+		for _, path := range []string{"/users/42?verbose=1", "/missing", "/implicit/page"} {
+			serve(server, path)
+		}
+		logged := read()
+
+		tests := []string{
+			`GET /users/42?verbose=1 route=/users/{id:int} status=200 bytes=7 duration=`,
+			`GET /missing route=- status=404 bytes=20 duration=`,
+			`GET /implicit/page route=/implicit status=200 bytes=9 duration=`,
+		}
+		for _, expected := range tests {
+			if !strings.Contains(logged, expected) {
+				t.Errorf("expected %q in\n%s", expected, logged)
+			}
+		}
+		if strings.Count(logged, `ip=192.0.2.1 ua="curl/8.0"`) != len(tests) {
+			t.Errorf("expected the client address and user agent on every line, got\n%s", logged)
+		}
+	})
+
+	t.Run("formatter and skip list", func(t *testing.T) {
+		read := captureLog(t, log_level.InfoLvlName)
+		server := newServer(
+			AccessLogSkip("/healthz"),
+			AccessLogFormatter(func(entry AccessEntry) string {
+				return fmt.Sprintf("custom %s %d %s", entry.Route, entry.Status, entry.RemoteIP)
+			}),
+		)
+		serve(server, "/healthz")
+		serve(server, "/users/1")
+		logged := read()
+		if strings.Contains(logged, "/healthz") || !strings.Contains(logged, "custom /users/{id:int} 200 192.0.2.1") {
+			t.Errorf("expected only the route in the custom format, got\n%s", logged)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package yagaw
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Alias registers another path for the route, requests matching it are served by the route
+// itself so that its middleware, matchers, name and options apply to both paths. The alias
+// must declare the same parameters as the route, it is listed under the route by Routes and
+// removed along with it.
+func (rt *Route) Alias(path string) *Route {
+	if rt.err != nil {
+		return rt
+	}
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+
+	for _, handlerPackage := range rt.handlerPackages {
+		if handlerPackage.expanded {
+			continue
+		}
+		if err := rt.router.addAlias(handlerPackage, path); err != nil {
+			rt.err = err
+			return rt
+		}
+	}
+	return rt
+}
+
+func (r *Router) addAlias(handlerPackage *RequestHandlerPackage, path string) error {
+	if err := validatePath(handlerPackage.method, path); err != nil {
+		return err
+	}
+	if err := sameParams(handlerPackage.Path, path); err != nil {
+		return fmt.Errorf("invalid alias `%s %s` of `%s`: %w", handlerPackage.method, path, handlerPackage.Path, err)
+	}
+
+	alias := &RequestHandlerPackage{
+		Path:          path,
+		anyMethod:     handlerPackage.anyMethod,
+		caseSensitive: handlerPackage.caseSensitive,
+		paramPattern:  handlerPackage.paramPattern,
+		aliasOf:       handlerPackage,
+	}
+	registered, err := r.addRoute(handlerPackage.method, alias, r.duplicatePolicy)
+	if len(registered) > 0 {
+		handlerPackage.aliases = append(handlerPackage.aliases, alias)
+	}
+	return err
+}
+
+// removeAliases unregisters the aliases of the route
+func (r *Router) removeAliases(handlerPackage *RequestHandlerPackage) {
+	isAlias := func(candidate *RequestHandlerPackage) bool { return candidate.aliasOf == handlerPackage }
+
+	for _, alias := range handlerPackage.aliases {
+		paths, _ := expandOptionalParam(r.normalizeSlash(alias.Path))
+		for _, path := range paths {
+			slot := r.findSlot(handlerPackage.method, path, alias.caseSensitive, alias.paramPattern)
+			if slot == nil {
+				continue
+			}
+			if isAlias(slot) {
+				r.removeRoute(handlerPackage.method, path)
+				continue
+			}
+			slot.candidates = slices.DeleteFunc(slot.candidates, isAlias)
+		}
+	}
+	handlerPackage.aliases = nil
+}
+
+// target returns the route serving the requests matched by the package, the route itself
+// unless the package is one of its aliases.
+func (p *RequestHandlerPackage) target() *RequestHandlerPackage {
+	if p.aliasOf != nil {
+		return p.aliasOf
+	}
+	return p
+}
+
+func (p *RequestHandlerPackage) aliasPaths() []string {
+	var paths []string
+	for _, alias := range p.aliases {
+		paths = append(paths, alias.Path)
+	}
+	return paths
+}
+
+// sameParams reports an error unless both paths declare the same parameter names
+func sameParams(path string, alias string) error {
+	pathNames, err := sortedParamNames(path)
+	if err != nil {
+		return err
+	}
+	aliasNames, err := sortedParamNames(alias)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(pathNames, aliasNames) {
+		return fmt.Errorf("parameters %v differ from %v", aliasNames, pathNames)
+	}
+	return nil
+}
+
+func sortedParamNames(path string) ([]string, error) {
+	paths, err := expandOptionalParam(path)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := parseRoutePath(paths[0], false, "")
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(slices.Values(parsed.paramNames)), nil
+}
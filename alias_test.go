@@ -0,0 +1,161 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestRouteAliases(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("org " + PathParam(req, "id"))
+	}
+	tagged := func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return next(req, params).SetHeader("X-Tagged", "yes")
+		}
+	}
+
+	router := NewRouter()
+	group := router.Group("/api")
+	route := group.RegisterRouteWith(GET, "/organisations/{id}", handler, tagged).
+		Alias("/api/organizations/{id}").
+		Alias("/api/orgs/{id:int}").
+		Name("org")
+	if err := route.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	group.Use(func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return next(req, params).SetHeader("X-Group", "yes")
+		}
+	})
+	route.MatchHeader("X-Tenant", "acme")
+
+	tests := []struct {
+		path   string
+		tenant string
+		status int
+		body   string
+	}{
+		{"/api/organisations/7", "acme", http.StatusOK, "org 7"},
+		{"/api/organizations/7", "acme", http.StatusOK, "org 7"},
+		{"/api/orgs/7", "acme", http.StatusOK, "org 7"},
+		{"/api/orgs/x", "acme", http.StatusNotFound, "404 - Page not found"},
+		{"/api/organizations/7", "other", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(string(GET), tt.path, nil)
+			req.Header.Set("X-Tenant", tt.tenant)
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Fatalf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+			if tt.status == http.StatusOK && (rw.Header().Get("X-Tagged") != "yes" || rw.Header().Get("X-Group") != "yes") {
+				t.Errorf("expected the route and group middleware to run, got %v", rw.Header())
+			}
+		})
+	}
+
+	routes := router.Routes()
+	if len(routes) != 1 || !slices.Equal(routes[0].Aliases, []string{"/api/organizations/{id}", "/api/orgs/{id:int}"}) {
+		t.Errorf("expected the aliases listed under the route, got %v", routes)
+	}
+	if url, err := router.URL("org", map[string]string{"id": "7"}); err != nil || url != "/api/organisations/7" {
+		t.Errorf("expected the url of the primary path, got %q (%v)", url, err)
+	}
+
+	router.UnregisterRoute(GET, "/api/organisations/{id}")
+	for _, path := range []string{"/api/organisations/7", "/api/organizations/7", "/api/orgs/7"} {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for %s once the route is removed, got %d", path, rw.Code)
+		}
+	}
+}
+
+func TestRouteAliasErrors(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/taken", handler)
+	tests := []struct {
+		name  string
+		alias string
+	}{
+		{"other param", "/organizations/{orgId}"},
+		{"missing param", "/organizations"},
+		{"malformed", "/organizations/{id"},
+		{"duplicate", "/taken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := router.RegisterRoute(GET, "/organisations/{id}", handler).Alias(tt.alias).Err(); err == nil {
+				t.Errorf("expected an error aliasing %q", tt.alias)
+			}
+		})
+	}
+}
+
+func TestRouteAliasUnregisteredAlone(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router := NewRouter()
+	router.Any("/old/{id}", handler).Alias("/new/{id}").Alias("/newer/{id}")
+	router.UnregisterRoute(GET, "/new/{id}")
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/new/1", nil))
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected the removed GET alias to 405, got %d", rw.Code)
+	}
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(POST), "/new/1", nil))
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected the alias to still serve POST, got %d", rw.Code)
+	}
+
+	for _, route := range router.Routes() {
+		expected := []string{"/new/{id}", "/newer/{id}"}
+		if route.Method == GET {
+			expected = []string{"/newer/{id}"}
+		}
+		if !slices.Equal(route.Aliases, expected) {
+			t.Errorf("expected %s aliases %v, got %v", route.Method, expected, route.Aliases)
+		}
+	}
+}
+
+func TestMergeKeepsRouteAliases(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "id"))
+	}
+
+	other := NewRouter()
+	other.RegisterRoute(GET, "/organisations/{id}", handler).Alias("/organizations/{id}")
+
+	router := NewRouter()
+	if err := router.MergeAt("/api", other); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/api/organizations/3", nil))
+	if rw.Code != http.StatusOK || rw.Body.String() != "3" {
+		t.Errorf("expected the merged alias to serve the route, got %d %q", rw.Code, rw.Body.String())
+	}
+	if routes := router.Routes(); len(routes) != 1 || !slices.Equal(routes[0].Aliases, []string{"/api/organizations/{id}"}) {
+		t.Errorf("expected the merged route with its alias, got %v", routes)
+	}
+}
@@ -0,0 +1,128 @@
+package yagaw
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// DefaultAutocertChallengeAddr is where the HTTP-01 challenges are answered
+const DefaultAutocertChallengeAddr = ":80"
+
+var ErrNoAutocertHosts = errors.New("autocert requires at least one host")
+
+type autocertSettings struct {
+	manager       *autocert.Manager
+	hosts         []string
+	challengeAddr string
+}
+
+// WithAutocert makes RunTLS obtain and renew the certificates of the given hosts from Let's
+// Encrypt, accepting its terms of service. HTTP-01 challenges are answered by a companion
+// listener on DefaultAutocertChallengeAddr, redirecting the other requests to HTTPS. RunTLS
+// refuses to start without hosts, the certificates are cached in the user cache directory.
+func (s *Server) WithAutocert(hosts ...string) *Server {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+	}
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		manager.Cache = autocert.DirCache(filepath.Join(cacheDir, "yagaw", "autocert"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.autocert = &autocertSettings{manager: manager, hosts: slices.Clone(hosts), challengeAddr: DefaultAutocertChallengeAddr}
+	return s
+}
+
+// AutocertCacheDir sets the directory the certificates obtained by WithAutocert are kept in
+func (s *Server) AutocertCacheDir(dir string) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.autocert != nil {
+		s.autocert.manager.Cache = autocert.DirCache(dir)
+	}
+	return s
+}
+
+// AutocertChallengeAddr sets the address of the listener answering the HTTP-01 challenges
+func (s *Server) AutocertChallengeAddr(addr string) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.autocert != nil {
+		s.autocert.challengeAddr = addr
+	}
+	return s
+}
+
+// AutocertManager returns the certificate manager set up by WithAutocert, nil without it.
+// Its fields can be changed before RunTLS, e.g. Client to use another ACME directory or a
+// fake one in tests, or Email to be notified of certificate problems.
+func (s *Server) AutocertManager() *autocert.Manager {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.autocert == nil {
+		return nil
+	}
+	return s.autocert.manager
+}
+
+// autocertTLSConfig makes the configuration get its certificates from the manager, as
+// autocert.Manager.TLSConfig does.
+func autocertTLSConfig(config *tls.Config, manager *autocert.Manager) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.GetCertificate = manager.GetCertificate
+	for _, proto := range manager.TLSConfig().NextProtos {
+		if !slices.Contains(config.NextProtos, proto) {
+			config.NextProtos = append(config.NextProtos, proto)
+		}
+	}
+	return config
+}
+
+// listenCompanion starts the companion listener of the run, answering the HTTP-01
+// challenges and redirecting to HTTPS, it is shut down along with the server.
+func (s *Server) listenCompanion() (*http.Server, error) {
+	s.mu.Lock()
+	settings, redirectPort, redirectAddr := s.autocert, s.redirectPort, s.redirectAddr()
+	errorLog := newErrorLog(s.suppressHandshakeErrors)
+	s.mu.Unlock()
+	if settings == nil && redirectPort == 0 {
+		return nil, nil
+	}
+	if settings != nil && len(settings.hosts) == 0 {
+		return nil, ErrNoAutocertHosts
+	}
+
+	var addr string
+	var handler http.Handler
+	switch {
+	case redirectPort == 0:
+		addr, handler = settings.challengeAddr, settings.manager.HTTPHandler(nil)
+	case settings == nil:
+		addr, handler = redirectAddr, http.HandlerFunc(s.redirectToHTTPS)
+	default:
+		addr, handler = redirectAddr, settings.manager.HTTPHandler(http.HandlerFunc(s.redirectToHTTPS))
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	companionServer := &http.Server{Addr: addr, Handler: handler, ErrorLog: errorLog}
+	go companionServer.Serve(listener)
+
+	s.mu.Lock()
+	s.companionServer = companionServer
+	s.mu.Unlock()
+	return companionServer, nil
+}
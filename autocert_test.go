@@ -0,0 +1,146 @@
+package yagaw
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// cacheCert stores a certificate for the host in the autocert cache as the manager does
+func cacheCert(t *testing.T, dir string, host string) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(30 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	cert, key := createCert(t, template, nil, nil)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	if err := autocert.DirCache(dir).Put(context.Background(), host, data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestServerAutocert(t *testing.T) {
+	t.Run("no hosts", func(t *testing.T) {
+		err := NewServer("127.0.0.1", 0).WithAutocert().RunTLS("", "")
+		if !errors.Is(err, ErrNoAutocertHosts) {
+			t.Errorf("expected ErrNoAutocertHosts, got %v", err)
+		}
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		if manager := NewServer("127.0.0.1", 0).AutocertManager(); manager != nil {
+			t.Errorf("expected no manager, got %v", manager)
+		}
+	})
+
+	// The fake ACME directory refuses every request, certificates must come from the cache
+	directoryCalls := atomic.Int32{}
+	directory := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		directoryCalls.Add(1)
+		http.Error(rw, "forbidden", http.StatusForbidden)
+	}))
+	defer directory.Close()
+
+	cacheDir := t.TempDir()
+	cacheCert(t, cacheDir, "cached.test")
+	// The challenge listener is up before the server is ready, its port is picked upfront
+	challengePort := freePort(t)
+	server := NewServer("127.0.0.1", 0).
+		WithAutocert("cached.test", "uncached.test").
+		AutocertCacheDir(cacheDir).
+		AutocertChallengeAddr(fmt.Sprintf("127.0.0.1:%d", challengePort))
+	server.AutocertManager().Client = &acme.Client{DirectoryURL: directory.URL}
+	server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("hello " + req.TLS.ServerName)
+	})
+
+	ran := make(chan error, 1)
+	go func() { ran <- server.RunTLS("", "") }()
+	address := awaitReady(t, server, ran)
+
+	get := func(serverName string) (string, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true, ServerName: serverName}}}
+		defer client.CloseIdleConnections()
+		res, err := client.Get("https://" + address + "/hello")
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return string(body), err
+	}
+
+	t.Run("cached certificate", func(t *testing.T) {
+		body, err := get("cached.test")
+		if err != nil || body != "hello cached.test" {
+			t.Errorf("expected the route to be served, got %q %v", body, err)
+		}
+	})
+
+	t.Run("host not whitelisted", func(t *testing.T) {
+		if _, err := get("other.test"); err == nil {
+			t.Error("expected the handshake to fail")
+		}
+	})
+
+	t.Run("certificate from the ACME client", func(t *testing.T) {
+		if _, err := get("uncached.test"); err == nil {
+			t.Error("expected the handshake to fail")
+		}
+		if directoryCalls.Load() == 0 {
+			t.Error("expected the injected ACME client to be used")
+		}
+	})
+
+	t.Run("challenge listener", func(t *testing.T) {
+		client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }}
+		req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/hello", challengePort), nil)
+		req.Host = "cached.test"
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusFound || res.Header.Get("Location") != "https://cached.test/hello" {
+			t.Errorf("expected a redirect to HTTPS, got %d %q", res.StatusCode, res.Header.Get("Location"))
+		}
+	})
+
+	t.Run("both listeners stop together", func(t *testing.T) {
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatalf("unexpected shutdown error: %v", err)
+		}
+		if err := <-ran; err != nil {
+			t.Errorf("expected RunTLS to return nil, got %v", err)
+		}
+		for _, stopped := range []string{address, fmt.Sprintf("127.0.0.1:%d", challengePort)} {
+			if _, err := net.Dial("tcp", stopped); err == nil {
+				t.Errorf("expected %s to be closed", stopped)
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package yagaw
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownPathParam = errors.New("unknown path parameter")
+
+// BindError reports a path parameter that could not be assigned to its struct field, it is
+// the client fault unless it wraps ErrUnknownPathParam or the field kind is unsupported.
+type BindError struct {
+	Field string
+	Param string
+	Value string
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	if errors.Is(e.Err, ErrUnknownPathParam) {
+		return fmt.Sprintf("cannot bind field `%s`: %v `%s`", e.Field, e.Err, e.Param)
+	}
+	return fmt.Sprintf("cannot bind path parameter `%s` value `%s` to field `%s`: %v", e.Param, e.Value, e.Field, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// BindPath assigns the path parameters of the request to the fields of the struct pointed
+// by target, following their `path` tag: `path:"id"` reads `{id}`, `path:"format,optional"`
+// leaves the field untouched when `{format?}` was not captured. Strings, integers, booleans,
+// floats, encoding.TextUnmarshaler values and pointers to them are supported, the first
+// failure is returned as a *BindError.
+func BindPath(req *http.Request, target any) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot bind path parameters to %T, a pointer to a struct is required", target)
+	}
+	return bindStruct(req, value.Elem())
+}
+
+func bindStruct(req *http.Request, target reflect.Value) error {
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		tag, tagged := field.Tag.Lookup("path")
+
+		// Embedded structs without a tag share the parameters of the outer one
+		if !tagged && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := bindStruct(req, target.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if !tagged || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		raw, found := LookupPathParam(req, name)
+		if !found && options == "optional" {
+			continue
+		}
+		if !found {
+			return &BindError{Field: field.Name, Param: name, Err: ErrUnknownPathParam}
+		}
+		if err := setField(target.Field(i), raw); err != nil {
+			return &BindError{Field: field.Name, Param: name, Value: raw, Err: err}
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	if field.Kind() == reflect.Pointer {
+		value := reflect.New(field.Type().Elem())
+		if err := setField(value.Elem(), raw); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	}
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+		field.SetFloat(parsed)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return numError(err)
+		}
+		field.SetBool(parsed)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// numError drops the value repeated by strconv errors, the BindError names it already
+func numError(err error) error {
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return numErr.Err
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package yagaw
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+type slug string
+
+func (s *slug) UnmarshalText(text []byte) error {
+	if strings.ToLower(string(text)) != string(text) {
+		return errors.New("slugs are lowercase")
+	}
+	*s = slug(text)
+	return nil
+}
+
+type pageParams struct {
+	Page uint16 `path:"page"`
+}
+
+type articleParams struct {
+	pageParams
+	ID      int        `path:"id"`
+	Slug    slug       `path:"slug"`
+	Draft   bool       `path:"draft"`
+	Score   float64    `path:"score"`
+	Origin  netip.Addr `path:"origin"`
+	Format  *string    `path:"format,optional"`
+	Ignored string     `path:"-"`
+	Plain   string
+}
+
+func servePath(t *testing.T, route string, path string, handler HttpRequestHandler) *httptest.ResponseRecorder {
+	t.Helper()
+	router := NewRouter()
+	if err := router.RegisterRoute(GET, route, handler).Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+	return rw
+}
+
+func TestBindPath(t *testing.T) {
+	route := "/articles/{id:int}/{slug}/{draft}/{score}/{origin}/{page}.{format?}"
+
+	var bound articleParams
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		bound = articleParams{Ignored: "kept", Plain: "kept"}
+		if err := BindPath(req, &bound); err != nil {
+			return NewHttpResponse(http.StatusBadRequest).SetBody(err.Error())
+		}
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	rw := servePath(t, route, "/articles/-42/hello-world/true/9.5/10.0.0.1/3.json", handler)
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %q", rw.Code, rw.Body.String())
+	}
+	if bound.ID != -42 || bound.Slug != "hello-world" || !bound.Draft || bound.Score != 9.5 || bound.Origin != netip.MustParseAddr("10.0.0.1") || bound.Page != 3 {
+		t.Errorf("unexpected bound values %+v", bound)
+	}
+	if bound.Format == nil || *bound.Format != "json" || bound.Ignored != "kept" || bound.Plain != "kept" {
+		t.Errorf("unexpected optional or untagged fields %+v", bound)
+	}
+
+	rw = servePath(t, route, "/articles/1/a/false/0/::1/1", handler)
+	if rw.Code != http.StatusOK || bound.Format != nil {
+		t.Errorf("expected the missing optional parameter to be skipped, got %d %v", rw.Code, bound.Format)
+	}
+}
+
+type (
+	intTarget struct {
+		ID int `path:"id"`
+	}
+	int8Target struct {
+		ID int8 `path:"id"`
+	}
+	uintTarget struct {
+		ID uint `path:"id"`
+	}
+	boolTarget struct {
+		Flag bool `path:"flag"`
+	}
+	floatTarget struct {
+		Score float32 `path:"score"`
+	}
+	slugTarget struct {
+		Slug slug `path:"slug"`
+	}
+	sliceTarget struct {
+		ID []int `path:"id"`
+	}
+	unknownTagTarget struct {
+		ID int `path:"userId"`
+	}
+)
+
+func TestBindPathErrors(t *testing.T) {
+	id := 0
+	tests := []struct {
+		name    string
+		route   string
+		path    string
+		target  any
+		message string
+		unknown bool
+	}{
+		{"int", "/{id}", "/abc", &intTarget{}, "cannot bind path parameter `id` value `abc` to field `ID`: invalid syntax", false},
+		{"int range", "/{id}", "/300", &int8Target{}, "value `300` to field `ID`: value out of range", false},
+		{"uint", "/{id}", "/-1", &uintTarget{}, "field `ID`: invalid syntax", false},
+		{"bool", "/{flag}", "/maybe", &boolTarget{}, "field `Flag`: invalid syntax", false},
+		{"float", "/{score}", "/high", &floatTarget{}, "field `Score`: invalid syntax", false},
+		{"text", "/{slug}", "/Hello", &slugTarget{}, "field `Slug`: slugs are lowercase", false},
+		{"unsupported", "/{id}", "/1", &sliceTarget{}, "unsupported field type []int", false},
+		{"unknown tag", "/{id}", "/1", &unknownTagTarget{}, "cannot bind field `ID`: unknown path parameter `userId`", true},
+		{"not a pointer", "/{id}", "/1", intTarget{}, "a pointer to a struct is required", false},
+		{"not a struct", "/{id}", "/1", &id, "a pointer to a struct is required", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			servePath(t, tt.route, tt.path, func(req *http.Request, params Params) *HttpResponse {
+				err = BindPath(req, tt.target)
+				return NewHttpResponse(http.StatusOK)
+			})
+
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Fatalf("expected an error containing %q, got %v", tt.message, err)
+			}
+			if errors.Is(err, ErrUnknownPathParam) != tt.unknown {
+				t.Errorf("expected errors.Is(err, ErrUnknownPathParam) to be %v", tt.unknown)
+			}
+		})
+	}
+}
+
+func TestBindPathWithTypedConstraint(t *testing.T) {
+	var id int64
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		target := struct {
+			ID int64 `path:"id"`
+		}{}
+		if err := BindPath(req, &target); err != nil {
+			t.Errorf("expected the int constraint to guarantee the conversion, got %v", err)
+		}
+		id = target.ID
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	if rw := servePath(t, "/users/{id:int}", "/users/abc", handler); rw.Code != http.StatusNotFound {
+		t.Errorf("expected the constraint to reject the value, got %d", rw.Code)
+	}
+	if rw := servePath(t, "/users/{id:int}", "/users/-7", handler); rw.Code != http.StatusOK || id != -7 {
+		t.Errorf("expected id -7, got %d %d", rw.Code, id)
+	}
+
+	var bindErr *BindError
+	rw := servePath(t, "/users/{id}", "/users/abc", func(req *http.Request, params Params) *HttpResponse {
+		if err := BindPath(req, &intTarget{}); errors.As(err, &bindErr) {
+			return NewHttpResponse(http.StatusBadRequest).SetBody(bindErr.Field + " " + bindErr.Value)
+		}
+		return NewHttpResponse(http.StatusOK)
+	})
+	if rw.Code != http.StatusBadRequest || rw.Body.String() != "ID abc" {
+		t.Errorf("expected a 400 naming the field, got %d %q", rw.Code, rw.Body.String())
+	}
+}
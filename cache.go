@@ -0,0 +1,86 @@
+package yagaw
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ----------- ROUTE CACHE -----------
+type routeCacheKey struct {
+	method HttpMethod
+	path   string
+}
+
+type routeCacheEntry struct {
+	key            routeCacheKey
+	handlerPackage *RequestHandlerPackage
+	values         []string
+}
+
+// routeCache is a bounded LRU of tree walks, it has its own lock since lookups only hold
+// the router read lock.
+type routeCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[routeCacheKey]*list.Element
+	order   *list.List
+}
+
+func newRouteCache(size int) *routeCache {
+	return &routeCache{size: size, entries: make(map[routeCacheKey]*list.Element, size), order: list.New()}
+}
+
+func (c *routeCache) get(method HttpMethod, path string) (*RequestHandlerPackage, []string, bool) {
+	if c == nil {
+		return nil, nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, found := c.entries[routeCacheKey{method, path}]
+	if !found {
+		return nil, nil, false
+	}
+	c.order.MoveToFront(element)
+	entry := element.Value.(*routeCacheEntry)
+	return entry.handlerPackage, entry.values, true
+}
+
+func (c *routeCache) add(method HttpMethod, path string, handlerPackage *RequestHandlerPackage, values []string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := routeCacheKey{method, path}
+	if element, found := c.entries[key]; found {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&routeCacheEntry{key: key, handlerPackage: handlerPackage, values: values})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*routeCacheEntry).key)
+	}
+}
+
+func (c *routeCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+}
+
+func (c *routeCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,126 @@
+package yagaw
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRouteCache(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + PathParam(req, "id"))
+		}
+	}
+	serve := func(router *Router, path string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(string(GET), path, nil)
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+
+	t.Run("removed routes are not served", func(t *testing.T) {
+		router := NewRouter().EnableRouteCache(8)
+		router.RegisterRoute(GET, "/users/{id}", handler("user "))
+
+		if rw := serve(router, "/users/7"); rw.Body.String() != "user 7" || router.cache.len() != 1 {
+			t.Fatalf("expected a cached `user 7`, got %q with %d entries", rw.Body.String(), router.cache.len())
+		}
+		router.UnregisterRoute(GET, "/users/{id}")
+		if rw := serve(router, "/users/7"); rw.Code != http.StatusNotFound {
+			t.Errorf("expected 404 after removal, got %d", rw.Code)
+		}
+	})
+
+	t.Run("new routes are served", func(t *testing.T) {
+		router := NewRouter().EnableRouteCache(8)
+		router.RegisterRoute(GET, "/users/{id}", handler("user "))
+		serve(router, "/users/me")
+		router.RegisterRoute(GET, "/users/{id:alpha}", handler("alpha "))
+
+		if rw := serve(router, "/users/me"); rw.Body.String() != "alpha me" {
+			t.Errorf("expected the new route to win, got %q", rw.Body.String())
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		router := NewRouter().EnableRouteCache(2)
+		router.RegisterRoute(GET, "/users/{id}", handler("user "))
+		for i := 0; i < 5; i++ {
+			if rw := serve(router, fmt.Sprintf("/users/%d", i)); rw.Body.String() != fmt.Sprintf("user %d", i) {
+				t.Errorf("expected `user %d`, got %q", i, rw.Body.String())
+			}
+		}
+		if router.cache.len() != 2 {
+			t.Errorf("expected 2 cached entries, got %d", router.cache.len())
+		}
+		if serve(router, "/missing"); router.cache.len() != 2 {
+			t.Error("expected misses not to be cached")
+		}
+	})
+
+	t.Run("matchers run on cached routes", func(t *testing.T) {
+		router := NewRouter().EnableRouteCache(8)
+		router.RegisterRoute(GET, "/hooks/{id}", handler("push ")).MatchHeader("X-Event", "push")
+		router.RegisterRoute(GET, "/hooks/{id}", handler("other "))
+
+		for _, tt := range []struct{ event, body string }{{"push", "push 1"}, {"ping", "other 1"}, {"push", "push 1"}} {
+			if rw := serve(router, "/hooks/1", "X-Event", tt.event); rw.Body.String() != tt.body {
+				t.Errorf("expected %q for event %s, got %q", tt.body, tt.event, rw.Body.String())
+			}
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		router := NewRouter().EnableRouteCache(4)
+		router.RegisterRoute(GET, "/users/{id}", handler("user "))
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					path := fmt.Sprintf("/users/%d", j%6)
+					if rw := serve(router, path); rw.Code != http.StatusOK {
+						t.Errorf("expected 200 for %s, got %d", path, rw.Code)
+					}
+					if j%25 == 0 {
+						router.RegisterRoute(GET, fmt.Sprintf("/other%d/{id}", i), handler(""))
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func BenchmarkServeHTTPHotPaths(b *testing.B) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	for _, cacheSize := range []int{0, 64} {
+		b.Run(fmt.Sprintf("cache=%d", cacheSize), func(b *testing.B) {
+			router := NewRouter().EnableRouteCache(cacheSize)
+			for i := 0; i < 200; i++ {
+				router.RegisterRoute(GET, fmt.Sprintf("/api/v1/resource%d/{id:int}/items/{item}", i), handler)
+			}
+			requests := make([]*http.Request, 10)
+			for i := range requests {
+				requests[i] = httptest.NewRequest(string(GET), fmt.Sprintf("/api/v1/resource%d/%d/items/item%d", i*20, i, i), nil)
+			}
+			rw := httptest.NewRecorder()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				router.ServeHTTP(rw, requests[i%len(requests)])
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// CleanPath sets whether request paths are cleaned before matching, enabled by default.
+// Empty, `.` and `..` segments are removed like path.Clean does, the trailing slash being
+// kept: GET and HEAD requests are redirected to the clean path with a 301, the others are
+// matched with it. Disable it when the path must reach the handlers as received.
+func (r *Router) CleanPath(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipCleanPath = !enable
+	return r
+}
+
+// cleanRequest returns the request with a clean path, or the redirect to send instead
+func (r *Router) cleanRequest(req *http.Request) (*http.Request, HttpRequestHandler) {
+	r.mu.RLock()
+	skip, useRawPath := r.skipCleanPath, r.useRawPath
+	r.mu.RUnlock()
+	if skip {
+		return req, nil
+	}
+
+	// Raw paths are cleaned escaped so that `%2F` is not taken for a separator, encoded dots
+	// are decoded first since `%2E%2E` is the same dot segment as `..`
+	requestPath := req.URL.Path
+	if useRawPath {
+		requestPath = decodeDots(req.URL.EscapedPath())
+	}
+	cleaned := cleanPath(requestPath)
+	if cleaned == requestPath {
+		return req, nil
+	}
+
+	method := HttpMethod(req.Method)
+	if method == GET || method == HEAD {
+		location := cleaned
+		if !useRawPath {
+			location = (&url.URL{Path: cleaned}).EscapedPath()
+		}
+		return req, redirectHandler(method, location)
+	}
+
+	cleanedReq := new(http.Request)
+	*cleanedReq = *req
+	cleanedReq.URL = new(url.URL)
+	*cleanedReq.URL = *req.URL
+	cleanedReq.URL.Path, cleanedReq.URL.RawPath = cleaned, ""
+	if useRawPath {
+		decoded, err := url.PathUnescape(cleaned)
+		if err != nil {
+			return req, nil
+		}
+		cleanedReq.URL.Path, cleanedReq.URL.RawPath = decoded, cleaned
+	}
+	return cleanedReq, nil
+}
+
+// cleanPath returns the canonical form of the path, paths without empty or dot segments
+// are returned as they are.
+func cleanPath(requestPath string) string {
+	if requestPath == "" {
+		return "/"
+	}
+	if requestPath[0] == '/' && !strings.Contains(requestPath, "//") && !strings.Contains(requestPath, "/.") {
+		return requestPath
+	}
+	if requestPath[0] != '/' {
+		requestPath = "/" + requestPath
+	}
+
+	cleaned := path.Clean(requestPath)
+	if strings.HasSuffix(requestPath, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// decodeDots replaces the encoded dots of the escaped path, dots being unreserved the path
+// keeps its meaning.
+func decodeDots(escapedPath string) string {
+	if !strings.Contains(escapedPath, "%2E") && !strings.Contains(escapedPath, "%2e") {
+		return escapedPath
+	}
+	return strings.NewReplacer("%2E", ".", "%2e", ".").Replace(escapedPath)
+}
@@ -0,0 +1,114 @@
+package yagaw
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCleanPath(t *testing.T) {
+	files := fstest.MapFS{
+		"css/site.css": {Data: []byte("body {}")},
+		"secret.txt":   {Data: []byte("top secret")},
+	}
+	static := func(req *http.Request, params Params) *HttpResponse {
+		name := PathParam(req, "file")
+		if strings.Contains(name, "..") {
+			return NewHttpResponse(http.StatusForbidden).SetBody("traversal")
+		}
+		data, err := fs.ReadFile(files, "css/"+name)
+		if err != nil {
+			return NewHttpResponse(http.StatusNotFound).SetBody("no file")
+		}
+		return NewHttpResponse(http.StatusOK).SetBody(string(data))
+	}
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + " " + req.URL.Path)
+		}
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/static/{*file}", static)
+	router.RegisterRoute(GET, "/users/", handler("users"))
+	router.RegisterRoute(POST, "/admin/users", handler("admin"))
+	router.Mount("/internal", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("internal " + req.URL.Path))
+	}), StripPrefix())
+
+	tests := []struct {
+		method   HttpMethod
+		path     string
+		status   int
+		location string
+		body     string
+	}{
+		{GET, "/static/site.css", http.StatusOK, "", "body {}"},
+		{GET, "/static/../secret.txt", http.StatusMovedPermanently, "/secret.txt", ""},
+		{GET, "/static/css/../../secret.txt", http.StatusMovedPermanently, "/secret.txt", ""},
+		{GET, "/static/./site.css", http.StatusMovedPermanently, "/static/site.css", ""},
+		{HEAD, "/static//site.css", http.StatusMovedPermanently, "/static/site.css", ""},
+		{GET, "//users/", http.StatusMovedPermanently, "/users/", ""},
+		{GET, "/./users/?page=2", http.StatusMovedPermanently, "/users/?page=2", ""},
+		{GET, "/users/../admin/users", http.StatusMovedPermanently, "/admin/users", ""},
+		{POST, "/users/../admin/users", http.StatusOK, "", "admin /admin/users"},
+		{POST, "/public/../internal/jobs", http.StatusOK, "", "internal /jobs"},
+		{POST, "/users/..", http.StatusNotFound, "", "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method)+" "+tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d %q", tt.status, rw.Code, rw.Body.String())
+			}
+			if location := rw.Header().Get("Location"); location != tt.location {
+				t.Errorf("expected Location %q, got %q", tt.location, location)
+			}
+			if tt.body != "" && rw.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		router.CleanPath(false)
+		defer router.CleanPath(true)
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/static/../secret.txt", nil))
+		if rw.Code != http.StatusForbidden || rw.Header().Get("Location") != "" {
+			t.Errorf("expected the raw path to reach the handler, got %d %q", rw.Code, rw.Body.String())
+		}
+
+		rw = httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "//users/", nil))
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("expected 404 for an unclean path, got %d", rw.Code)
+		}
+	})
+}
+
+func TestCleanPathKeepsEncodedSlashesWithRawPath(t *testing.T) {
+	router := NewRouter().UseRawPath(true)
+	router.RegisterRoute(POST, "/repos/{name:.+}", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "name"))
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(POST), "/repos/./org%2Fproject", nil))
+	if rw.Code != http.StatusOK || rw.Body.String() != "org/project" {
+		t.Errorf("expected org/project, got %d %q", rw.Code, rw.Body.String())
+	}
+
+	rw = httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/repos//org%2Fproject", nil))
+	if location := rw.Header().Get("Location"); rw.Code != http.StatusMovedPermanently || location != "/repos/org%2Fproject" {
+		t.Errorf("expected a redirect to /repos/org%%2Fproject, got %d %q", rw.Code, location)
+	}
+}
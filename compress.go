@@ -0,0 +1,287 @@
+package yagaw
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ----------- RESPONSE COMPRESSION -----------
+
+// CompressMinSize is the size under which responses are sent uncompressed, compressing
+// them costing more than it saves.
+const CompressMinSize = 1024
+
+// DefaultCompressTypes are the content types compressed when Compress is given none
+var DefaultCompressTypes = []string{
+	"text/html", "text/plain", "text/css", "text/csv", "text/javascript", "text/xml",
+	"application/javascript", "application/json", "application/xml", "image/svg+xml",
+}
+
+// EncodingWriter compresses what is written to it into the writer it was reset with, as
+// *gzip.Writer does.
+type EncodingWriter interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Encoder provides the writers of a content coding for CompressWith, GzipEncoder for gzip.
+// Encoders for other codings can be plugged in without the core depending on them, like
+// the ones of the compress/brotli and compress/zstd packages.
+type Encoder struct {
+	// Name is the content coding token, e.g. `br`
+	Name string
+	// NewWriter creates a writer, it is reset with the response before each use
+	NewWriter func() EncodingWriter
+}
+
+// GzipEncoder returns the gzip encoder at the given compress/gzip level, it panics when the
+// level is invalid.
+func GzipEncoder(level int) Encoder {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic(fmt.Errorf("invalid compression level: %w", err))
+	}
+	return Encoder{Name: "gzip", NewWriter: func() EncodingWriter {
+		writer, _ := gzip.NewWriterLevel(io.Discard, level)
+		return writer
+	}}
+}
+
+// CompressOptions configures the middleware returned by CompressWith
+type CompressOptions struct {
+	// Encoders are the codings offered, in the order the server prefers them when the
+	// client accepts several equally
+	Encoders []Encoder
+	// Types are the content types compressed, DefaultCompressTypes unless set
+	Types []string
+	// MinSize is the body size from which responses are compressed, CompressMinSize unless set
+	MinSize int
+}
+
+type compressor struct {
+	types    []string
+	minSize  int
+	names    []string
+	encoders map[string]*sync.Pool
+}
+
+// Compress returns server middleware gzipping the responses of the clients accepting it,
+// at the given compress/gzip level, when their content type is one of the types, or of
+// DefaultCompressTypes without any, and their body reaches CompressMinSize. A type like
+// `text/*` matches all its subtypes. Responses to HEAD requests, 204s, 304s and responses
+// already encoded by the handler are left alone, and flushing a response sends what was
+// compressed so far. It panics when the level is invalid.
+func Compress(level int, types ...string) func(http.Handler) http.Handler {
+	return CompressWith(CompressOptions{Encoders: []Encoder{GzipEncoder(level)}, Types: types})
+}
+
+// CompressWith is like Compress but negotiates the coding among the encoders from the
+// q-values of Accept-Encoding, e.g. br over gzip, the writers of every encoder being pooled.
+func CompressWith(options CompressOptions) func(http.Handler) http.Handler {
+	types := options.Types
+	if len(types) == 0 {
+		types = DefaultCompressTypes
+	}
+	c := &compressor{minSize: options.MinSize, encoders: map[string]*sync.Pool{}}
+	if c.minSize <= 0 {
+		c.minSize = CompressMinSize
+	}
+	for _, contentType := range types {
+		c.types = append(c.types, strings.ToLower(contentType))
+	}
+	for _, encoder := range options.Encoders {
+		name := strings.ToLower(encoder.Name)
+		if _, found := c.encoders[name]; found {
+			continue
+		}
+		c.names = append(c.names, name)
+		c.encoders[name] = &sync.Pool{New: func() any { return encoder.NewWriter() }}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"), c.names)
+			if req.Method == http.MethodHead || encoding == "" {
+				next.ServeHTTP(rw, req)
+				return
+			}
+			// A panicking handler leaves the response to the recovery, nothing more is sent
+			// than what was compressed so far, the encoder being released all the same
+			writer := &compressWriter{ResponseWriter: rw, compressor: c, encoding: encoding}
+			defer writer.release()
+			next.ServeHTTP(writer, req)
+			if !writer.decided {
+				writer.decide(false)
+			}
+		})
+	}
+}
+
+// negotiateEncoding returns the coding among the offered ones the Accept-Encoding header
+// gives the highest q-value, the first offered on ties, and an empty string when none is
+// acceptable. Codings the header doesn't name get the q-value of `*`.
+func negotiateEncoding(acceptEncoding string, offered []string) string {
+	qValues := map[string]float64{}
+	for _, accepted := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(accepted, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, isQ := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); isQ {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		qValues[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, name := range offered {
+		q, named := qValues[name]
+		if !named {
+			q = qValues["*"]
+		}
+		if q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+func (c *compressor) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(c.types, func(allowed string) bool {
+		prefix, isWildcard := strings.CutSuffix(allowed, "*")
+		if isWildcard {
+			return strings.HasPrefix(mediaType, prefix)
+		}
+		return mediaType == allowed
+	})
+}
+
+// compressWriter holds the response back until it knows whether to compress it: once the
+// body reaches the minimum size, is flushed or is complete.
+type compressWriter struct {
+	http.ResponseWriter
+	compressor *compressor
+	encoding   string
+	status     int
+	buffer     []byte
+	decided    bool
+	encoder    EncodingWriter
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	// Informational responses may precede the final one
+	if w.decided || status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buffer = append(w.buffer, data...)
+		if len(w.buffer) < w.compressor.minSize {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// decide starts the response, compressed when it qualifies, and writes what was held back
+func (w *compressWriter) decide(largeEnough bool) error {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	headers := w.Header()
+	if headers.Get("Content-Type") == "" && len(w.buffer) > 0 {
+		headers.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+
+	if largeEnough && w.status != http.StatusNoContent && w.status != http.StatusNotModified &&
+		headers.Get("Content-Encoding") == "" && w.compressor.compressible(headers.Get("Content-Type")) {
+		headers.Del("Content-Length")
+		headers.Set("Content-Encoding", w.encoding)
+		w.encoder = w.compressor.encoders[w.encoding].Get().(EncodingWriter)
+		w.encoder.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buffer)
+	} else {
+		_, err = w.ResponseWriter.Write(buffer)
+	}
+	return err
+}
+
+// Flush sends what was written so far, a streamed response being compressed whatever its
+// size.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.encoder != nil {
+		w.encoder.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets handlers take over the connection, nothing is compressed then
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buffer, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.decided = true
+	}
+	return conn, buffer, err
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// release completes the compressed stream and returns the encoder to its pool
+func (w *compressWriter) release() {
+	if w.encoder != nil {
+		w.encoder.Close()
+		w.encoder.Reset(io.Discard)
+		w.compressor.encoders[w.encoding].Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,25 @@
+// Package brotli provides the br encoder of the yagaw compression middleware, apart from
+// the core package so that only its users depend on a brotli implementation.
+package brotli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Algatux/yagaw"
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultLevel balances speed and size for responses compressed on the fly
+const DefaultLevel = 4
+
+// Encoder returns the br encoder at the given level, from brotli.BestSpeed to
+// brotli.BestCompression, for yagaw.CompressWith. It panics when the level is invalid.
+func Encoder(level int) yagaw.Encoder {
+	if level < brotli.BestSpeed || level > brotli.BestCompression {
+		panic(fmt.Errorf("invalid brotli level %d", level))
+	}
+	return yagaw.Encoder{Name: "br", NewWriter: func() yagaw.EncodingWriter {
+		return brotli.NewWriterLevel(io.Discard, level)
+	}}
+}
@@ -0,0 +1,46 @@
+package brotli
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Algatux/yagaw"
+	"github.com/andybalholm/brotli"
+)
+
+func TestEncoder(t *testing.T) {
+	body := strings.Repeat("brotli compressed body ", 100)
+	handler := yagaw.CompressWith(yagaw.CompressOptions{Encoders: []yagaw.Encoder{yagaw.GzipEncoder(1), Encoder(DefaultLevel)}})(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "text/plain")
+			io.WriteString(rw, body)
+		}))
+
+	// Serving twice reuses the pooled writer
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "br;q=1.0, gzip;q=0.5")
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+
+		if rw.Header().Get("Content-Encoding") != "br" {
+			t.Fatalf("expected br, got %v", rw.Header())
+		}
+		decoded, err := io.ReadAll(brotli.NewReader(rw.Body))
+		if err != nil || string(decoded) != body {
+			t.Errorf("expected the body to decode, got %d bytes %v", len(decoded), err)
+		}
+	}
+}
+
+func TestEncoderInvalidLevel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	Encoder(12)
+}
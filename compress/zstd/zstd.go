@@ -0,0 +1,28 @@
+// Package zstd provides the zstd encoder of the yagaw compression middleware, apart from
+// the core package so that only its users depend on a zstd implementation.
+package zstd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Algatux/yagaw"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultLevel is the default level of the zstd command line tool
+const DefaultLevel = 3
+
+// Encoder returns the zstd encoder at the given zstd level, from 1 to 22, for
+// yagaw.CompressWith. It panics when the level is invalid.
+func Encoder(level int) yagaw.Encoder {
+	if level < 1 || level > 22 {
+		panic(fmt.Errorf("invalid zstd level %d", level))
+	}
+	// Responses are compressed as they are written, one goroutine each is enough
+	options := []zstd.EOption{zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1)}
+	return yagaw.Encoder{Name: "zstd", NewWriter: func() yagaw.EncodingWriter {
+		writer, _ := zstd.NewWriter(io.Discard, options...)
+		return writer
+	}}
+}
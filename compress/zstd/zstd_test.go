@@ -0,0 +1,51 @@
+package zstd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Algatux/yagaw"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestEncoder(t *testing.T) {
+	body := strings.Repeat("zstd compressed body ", 100)
+	handler := yagaw.CompressWith(yagaw.CompressOptions{Encoders: []yagaw.Encoder{Encoder(DefaultLevel), yagaw.GzipEncoder(1)}})(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("Content-Type", "text/plain")
+			io.WriteString(rw, body)
+		}))
+
+	// Serving twice reuses the pooled writer, zstd being preferred by the server on ties
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip, zstd")
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, req)
+
+		if rw.Header().Get("Content-Encoding") != "zstd" {
+			t.Fatalf("expected zstd, got %v", rw.Header())
+		}
+		reader, err := zstd.NewReader(rw.Body)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		decoded, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil || string(decoded) != body {
+			t.Errorf("expected the body to decode, got %d bytes %v", len(decoded), err)
+		}
+	}
+}
+
+func TestEncoderInvalidLevel(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	Encoder(23)
+}
@@ -0,0 +1,237 @@
+package yagaw
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	largeJSON := `{"users":[` + strings.Repeat(`{"name":"gopher"},`, 100) + `{}]}`
+	server := NewServer("127.0.0.1", 0).Use(Compress(gzip.BestSpeed))
+	route := func(path string, contentType string, status int, body string) {
+		server.GetRouter().RegisterRoute(GET, path, func(req *http.Request, params Params) *HttpResponse {
+			response := NewHttpResponse(status).SetBody(body)
+			if contentType != "" {
+				response.SetHeader("Content-Type", contentType)
+			}
+			return response
+		})
+	}
+	route("/large", "application/json", http.StatusOK, largeJSON)
+	route("/tiny", "application/json", http.StatusOK, `{"name":"gopher"}`)
+	route("/sniffed", "", http.StatusOK, strings.Repeat("plain text ", 200))
+	route("/image", "image/png", http.StatusOK, strings.Repeat("x", 2000))
+	route("/empty", "", http.StatusNoContent, "")
+	server.GetRouter().RegisterRoute(GET, "/encoded", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetHeader("Content-Encoding", "br").SetBody(strings.Repeat("x", 2000))
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		acceptEncoding string
+		compressed     bool
+	}{
+		{"large JSON", "GET", "/large", "gzip, deflate", true},
+		{"sniffed content type", "GET", "/sniffed", "gzip", true},
+		{"tiny JSON", "GET", "/tiny", "gzip", false},
+		{"gzip not accepted", "GET", "/large", "deflate", false},
+		{"gzip refused", "GET", "/large", "gzip;q=0, deflate", false},
+		{"type not compressible", "GET", "/image", "gzip", false},
+		{"no content", "GET", "/empty", "gzip", false},
+		{"already encoded", "GET", "/encoded", "gzip", false},
+		{"HEAD request", "HEAD", "/large", "gzip", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rw := httptest.NewRecorder()
+			server.serveHTTP(rw, req)
+
+			if rw.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary: Accept-Encoding, got %v", rw.Header().Values("Vary"))
+			}
+			if compressed := rw.Header().Get("Content-Encoding") == "gzip"; compressed != tt.compressed {
+				t.Fatalf("expected compressed: %t, got %v", tt.compressed, rw.Header())
+			}
+			if !tt.compressed {
+				return
+			}
+			if rw.Header().Get("Content-Length") != "" {
+				t.Errorf("expected no Content-Length, got %q", rw.Header().Get("Content-Length"))
+			}
+			reader, err := gzip.NewReader(rw.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, err := io.ReadAll(reader)
+			if err != nil || (tt.path == "/large" && string(body) != largeJSON) {
+				t.Errorf("expected the body to decompress, got %d bytes %v", len(body), err)
+			}
+		})
+	}
+}
+
+func TestCompressStreaming(t *testing.T) {
+	release := make(chan struct{})
+	router := NewRouter().Mount("/events", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+		for _, event := range []string{`{"event":1}` + "\n", `{"event":2}` + "\n"} {
+			rw.Write([]byte(event))
+			http.NewResponseController(rw).Flush()
+			<-release
+		}
+	}))
+	ts := NewTestServerT(t, router)
+	ts.Server().Use(Compress(gzip.DefaultCompression))
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	if res.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected the stream to be compressed, got %v", res.Header)
+	}
+
+	reader, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := bufio.NewReader(reader)
+	// Each event is readable while the handler still blocks
+	for _, expected := range []string{`{"event":1}` + "\n", `{"event":2}` + "\n"} {
+		line, err := lines.ReadString('\n')
+		if err != nil || line != expected {
+			t.Fatalf("expected %q, got %q %v", expected, line, err)
+		}
+		release <- struct{}{}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	offered := []string{"zstd", "br", "gzip"}
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"br;q=1.0, gzip;q=0.5", "br"},
+		{"gzip;q=0.5, zstd;q=0.8, br;q=0.8", "zstd"},
+		{"gzip, br", "br"},
+		{"GZIP", "gzip"},
+		{"identity", ""},
+		{"", ""},
+		{"compress, x-unknown;q=1", ""},
+		{"*", "zstd"},
+		{"*;q=0.1, gzip;q=0.5", "gzip"},
+		{"zstd;q=0, br;q=0, *", "gzip"},
+		{"gzip;q=0", ""},
+		{"gzip;q=abc, br", "br"},
+	}
+	for _, tt := range tests {
+		if encoding := negotiateEncoding(tt.acceptEncoding, offered); encoding != tt.expected {
+			t.Errorf("expected %q for %q, got %q", tt.expected, tt.acceptEncoding, encoding)
+		}
+	}
+}
+
+// upperEncoder is a fake coding upper-casing the body
+type upperEncoder struct{ w io.Writer }
+
+func (e *upperEncoder) Write(data []byte) (int, error) {
+	return e.w.Write([]byte(strings.ToUpper(string(data))))
+}
+func (e *upperEncoder) Close() error      { return nil }
+func (e *upperEncoder) Flush() error      { return nil }
+func (e *upperEncoder) Reset(w io.Writer) { e.w = w }
+
+func TestCompressWith(t *testing.T) {
+	upper := Encoder{Name: "x-upper", NewWriter: func() EncodingWriter { return &upperEncoder{} }}
+	server := NewServer("127.0.0.1", 0).Use(CompressWith(CompressOptions{
+		Encoders: []Encoder{GzipEncoder(gzip.BestSpeed), upper},
+		MinSize:  4,
+	}))
+	server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetHeader("Content-Type", "text/plain").SetBody("hello")
+	})
+
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+		body           string
+	}{
+		{"x-upper;q=1.0, gzip;q=0.5", "x-upper", "HELLO"},
+		{"gzip, x-upper", "gzip", ""},
+		{"identity", "", "hello"},
+		{"br, zstd", "", "hello"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(string(GET), "/hello", nil)
+		req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		rw := httptest.NewRecorder()
+		server.serveHTTP(rw, req)
+
+		if rw.Header().Get("Content-Encoding") != tt.encoding {
+			t.Errorf("expected %q to be encoded with %q, got %q", tt.acceptEncoding, tt.encoding, rw.Header().Get("Content-Encoding"))
+		}
+		if tt.body != "" && rw.Body.String() != tt.body {
+			t.Errorf("expected %q for %q, got %q", tt.body, tt.acceptEncoding, rw.Body.String())
+		}
+	}
+}
+
+type closeCounter struct {
+	*gzip.Writer
+	closed *int
+}
+
+func (w closeCounter) Close() error {
+	*w.closed++
+	return w.Writer.Close()
+}
+
+func TestCompressPanic(t *testing.T) {
+	closed := 0
+	encoder := Encoder{Name: "gzip", NewWriter: func() EncodingWriter {
+		return closeCounter{Writer: gzip.NewWriter(io.Discard), closed: &closed}
+	}}
+	handler := CompressWith(CompressOptions{Encoders: []Encoder{encoder}})(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Write([]byte(strings.Repeat("partial ", 200)))
+		http.NewResponseController(rw).Flush()
+		panic("broken stream")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rw := httptest.NewRecorder()
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "broken stream" {
+				t.Errorf("expected the panic to be passed along, got %v", recovered)
+			}
+		}()
+		handler.ServeHTTP(rw, req)
+	}()
+
+	if closed != 1 {
+		t.Errorf("expected the encoder to be closed once, got %d", closed)
+	}
+	reader, err := gzip.NewReader(rw.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body, err := io.ReadAll(reader); err != nil || string(body) != strings.Repeat("partial ", 200) {
+		t.Errorf("expected the stream sent so far to be complete, got %d bytes %v", len(body), err)
+	}
+}
@@ -0,0 +1,116 @@
+package yagaw
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ----------- CONCURRENCY LIMIT -----------
+
+type concurrencyLimiter struct {
+	slots      chan struct{}
+	queue      int64
+	timeout    time.Duration
+	retryAfter string
+	inFlight   atomic.Int64
+	queued     atomic.Int64
+}
+
+// ConcurrencyStats is a snapshot of the counters of the limit set with LimitConcurrency
+type ConcurrencyStats struct {
+	InFlight int64 `json:"inFlight"`
+	Queued   int64 `json:"queued"`
+}
+
+// MaxConcurrent returns server middleware admitting up to n requests at once, queueing up
+// to queue more for at most timeout, as long as their clients wait without one, and
+// shedding the others with a 503 whose Retry-After is the timeout rounded up to the second.
+// Slots are released even when handlers panic. Server.LimitConcurrency adds it with its
+// counters reported by Stats. It panics unless n and timeout are positive and queue isn't
+// negative.
+func MaxConcurrent(n int, queue int, timeout time.Duration) func(http.Handler) http.Handler {
+	return newConcurrencyLimiter(n, queue, timeout).handler
+}
+
+func newConcurrencyLimiter(n int, queue int, timeout time.Duration) *concurrencyLimiter {
+	if n <= 0 || queue < 0 || timeout <= 0 {
+		panic(fmt.Errorf("invalid concurrency limit of %d requests queueing %d for %s", n, queue, timeout))
+	}
+	return &concurrencyLimiter{
+		slots:      make(chan struct{}, n),
+		queue:      int64(queue),
+		timeout:    timeout,
+		retryAfter: strconv.Itoa(int(math.Ceil(timeout.Seconds()))),
+	}
+}
+
+func (l *concurrencyLimiter) stats() ConcurrencyStats {
+	return ConcurrencyStats{InFlight: l.inFlight.Load(), Queued: l.queued.Load()}
+}
+
+func (l *concurrencyLimiter) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		l.serve(next, rw, req)
+	})
+}
+
+func (l *concurrencyLimiter) serve(next http.Handler, rw http.ResponseWriter, req *http.Request) {
+	if !l.acquire(req) {
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Header().Set("Retry-After", l.retryAfter)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(rw, "503 - Service unavailable")
+		return
+	}
+	l.inFlight.Add(1)
+	defer func() {
+		l.inFlight.Add(-1)
+		<-l.slots
+	}()
+	next.ServeHTTP(rw, req)
+}
+
+// acquire takes a slot, waiting in the queue when it has room
+func (l *concurrencyLimiter) acquire(req *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.queued.Add(1) > l.queue {
+		l.queued.Add(-1)
+		return false
+	}
+	defer l.queued.Add(-1)
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-req.Context().Done():
+		return false
+	}
+}
+
+// LimitConcurrency adds the server middleware of MaxConcurrent(n, queue, timeout) like Use
+// does, its counters being reported by Stats and the stats endpoint. The server has a single
+// limit: calling it again replaces the limit in place for the next requests, those admitted
+// before releasing their slots to the previous one.
+func (s *Server) LimitConcurrency(n int, queue int, timeout time.Duration) *Server {
+	if s.concurrency.Swap(newConcurrencyLimiter(n, queue, timeout)) != nil {
+		return s
+	}
+	return s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			s.concurrency.Load().serve(next, rw, req)
+		})
+	})
+}
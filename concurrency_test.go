@@ -0,0 +1,155 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrent(t *testing.T) {
+	started, release := make(chan string, 10), make(chan struct{})
+	server := NewServer("127.0.0.1", 0).LimitConcurrency(2, 1, time.Minute)
+	server.GetRouter().RegisterRoute(GET, "/work/{id}", func(req *http.Request, params Params) *HttpResponse {
+		started <- PathParam(req, "id")
+		<-release
+		return NewHttpResponse(http.StatusOK)
+	})
+	server.GetRouter().RegisterRoute(GET, "/panic", func(req *http.Request, params Params) *HttpResponse {
+		panic("broken handler")
+	})
+	serve := func(path string) chan *httptest.ResponseRecorder {
+		served := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			rw := httptest.NewRecorder()
+			server.serveHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+			served <- rw
+		}()
+		return served
+	}
+
+	// Two requests are admitted, the third waits in the queue
+	first, second := serve("/work/1"), serve("/work/2")
+	<-started
+	<-started
+	queued := serve("/work/3")
+	waitFor(t, "the request to be queued", func() bool { return server.Stats().Concurrency.Queued == 1 })
+	if stats := server.Stats().Concurrency; stats.InFlight != 2 {
+		t.Errorf("expected 2 requests in flight, got %+v", stats)
+	}
+
+	// The queue is full, the next request is shed right away
+	shed := <-serve("/work/4")
+	if shed.Code != http.StatusServiceUnavailable || shed.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected a 503 with Retry-After, got %d %v", shed.Code, shed.Header())
+	}
+
+	// Completing a request admits the queued one
+	release <- struct{}{}
+	if id := <-started; id != "3" {
+		t.Errorf("expected the queued request to be admitted, got %s", id)
+	}
+	close(release)
+	for _, served := range []chan *httptest.ResponseRecorder{first, second, queued} {
+		if rw := <-served; rw.Code != http.StatusOK {
+			t.Errorf("expected the request to be served, got %d", rw.Code)
+		}
+	}
+
+	// Slots of panicking handlers are released
+	captureLog(t, "error")
+	for range 3 {
+		<-serve("/panic")
+	}
+	if stats := server.Stats().Concurrency; stats.InFlight != 0 || stats.Queued != 0 {
+		t.Errorf("expected every slot to be released, got %+v", stats)
+	}
+}
+
+func TestMaxConcurrentQueueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	limiter := newConcurrencyLimiter(1, 1, 20*time.Millisecond)
+	handler := limiter.handler(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	defer close(release)
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(string(GET), "/", nil))
+	waitFor(t, "the request to be admitted", func() bool { return limiter.inFlight.Load() == 1 })
+
+	began := time.Now()
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(string(GET), "/", nil))
+	if rw.Code != http.StatusServiceUnavailable || rw.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected a 503 once the timeout expired, got %d %v", rw.Code, rw.Header())
+	}
+	if elapsed := time.Since(began); elapsed < 20*time.Millisecond {
+		t.Errorf("expected the request to wait in the queue, shed after %s", elapsed)
+	}
+	if queued := limiter.queued.Load(); queued != 0 {
+		t.Errorf("expected the queue to be empty, got %d", queued)
+	}
+}
+
+func TestMaxConcurrentInvalidArguments(t *testing.T) {
+	tests := []struct {
+		n       int
+		queue   int
+		timeout time.Duration
+	}{
+		{0, 1, time.Second},
+		{1, -1, time.Second},
+		{1, 1, 0},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected MaxConcurrent(%d, %d, %s) to panic", tt.n, tt.queue, tt.timeout)
+				}
+			}()
+			MaxConcurrent(tt.n, tt.queue, tt.timeout)
+		}()
+	}
+}
+
+func TestLimitConcurrencyTwice(t *testing.T) {
+	started, release := make(chan struct{}, 10), make(chan struct{})
+	server := NewServer("127.0.0.1", 0).LimitConcurrency(1, 0, time.Minute).LimitConcurrency(2, 0, time.Minute)
+	server.GetRouter().RegisterRoute(GET, "/work", func(req *http.Request, params Params) *HttpResponse {
+		started <- struct{}{}
+		<-release
+		return NewHttpResponse(http.StatusOK)
+	})
+	serve := func() chan *httptest.ResponseRecorder {
+		served := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			rw := httptest.NewRecorder()
+			server.serveHTTP(rw, httptest.NewRequest(string(GET), "/work", nil))
+			served <- rw
+		}()
+		return served
+	}
+
+	if len(server.middleware) != 1 {
+		t.Errorf("expected a single limiter middleware, got %d", len(server.middleware))
+	}
+
+	// The second limit replaces the first one instead of stacking on it
+	first, second := serve(), serve()
+	<-started
+	<-started
+	if stats := server.Stats().Concurrency; stats.InFlight != 2 {
+		t.Errorf("expected 2 requests in flight, got %+v", stats)
+	}
+	if shed := <-serve(); shed.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the third request to be shed, got %d", shed.Code)
+	}
+
+	close(release)
+	for _, served := range []chan *httptest.ResponseRecorder{first, second} {
+		if rw := <-served; rw.Code != http.StatusOK {
+			t.Errorf("expected the request to be served, got %d", rw.Code)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package yagaw
+
+import (
+	"fmt"
+	"maps"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type ConflictSeverity int
+
+const (
+	ConflictWarning ConflictSeverity = iota
+	ConflictError
+)
+
+func (s ConflictSeverity) String() string {
+	if s == ConflictError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Conflict reports two routes of the same method able to match the same path, Route being
+// the one registered first and Example a path matched by both. Duplicate is set when Other
+// replaced Route, both having the same pattern.
+type Conflict struct {
+	Severity  ConflictSeverity
+	Method    HttpMethod
+	Route     RouteInfo
+	Other     RouteInfo
+	Example   string
+	Duplicate bool
+}
+
+func (c Conflict) String() string {
+	if c.Duplicate {
+		return fmt.Sprintf("%s: `%s %s` replaces `%s %s`, e.g. `%s`", c.Severity, c.Method, c.Other.Path, c.Method, c.Route.Path, c.Example)
+	}
+	return fmt.Sprintf("%s: `%s %s` overlaps `%s %s`, e.g. `%s`", c.Severity, c.Method, c.Other.Path, c.Method, c.Route.Path, c.Example)
+}
+
+// Validate reports the pairs of routes that can match the same request path. Exact
+// duplicates, where a registration replaced a previous one, are errors. Overlaps resolved
+// by the matching precedence, like `/users/new` and `/users/{id}`, are warnings unless
+// StrictConflicts is enabled. Method specific routes overlapping an Any route are expected
+// and not reported, host routers are validated on their own.
+func (r *Router) Validate() []Conflict {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	overlapSeverity := ConflictWarning
+	if r.strictConflicts {
+		overlapSeverity = ConflictError
+	}
+
+	conflicts := []Conflict{}
+	seen := map[string]bool{}
+	report := func(conflict Conflict) {
+		// Any routes are stored for every method, their conflicts are reported once
+		key := fmt.Sprint(conflict.Severity, conflict.Route.Path, " ", conflict.Other.Path)
+		if !conflict.Route.AnyMethod {
+			key = fmt.Sprint(conflict.Method, " ", key)
+		}
+		if !seen[key] {
+			seen[key] = true
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	routes := map[HttpMethod][]conflictRoute{}
+	r.eachSlot(func(method HttpMethod, slot *RequestHandlerPackage) {
+		for _, candidate := range slot.candidates {
+			routes[method] = append(routes[method], newConflictRoute(slot, candidate))
+		}
+	})
+
+	methods := slices.SortedFunc(maps.Keys(routes), compareMethods)
+	for _, method := range methods {
+		methodRoutes := routes[method]
+		slices.SortFunc(methodRoutes, func(a, b conflictRoute) int { return a.handlerPackage.order - b.handlerPackage.order })
+
+		// The replaced fallbacks are only reported as duplicates
+		live := methodRoutes[:0]
+		for _, route := range methodRoutes {
+			fallback := route.slot.fallback()
+			if route.handlerPackage != fallback && len(route.handlerPackage.target().matchers) == 0 {
+				report(route.duplicateOf(method, fallback))
+				continue
+			}
+			live = append(live, route)
+		}
+		methodRoutes = live
+
+		for i, route := range methodRoutes {
+			for _, other := range methodRoutes[i+1:] {
+				if route.slot == other.slot || route.handlerPackage.anyMethod != other.handlerPackage.anyMethod || route.variantOf(other) {
+					continue
+				}
+				if example, overlaps := overlapExample(route.segments, other.segments); overlaps {
+					report(Conflict{
+						Severity: overlapSeverity,
+						Method:   method,
+						Route:    route.handlerPackage.info(method),
+						Other:    other.handlerPackage.info(method),
+						Example:  example,
+					})
+				}
+			}
+		}
+	}
+
+	slices.SortStableFunc(conflicts, func(a, b Conflict) int {
+		if a.Method != b.Method {
+			return compareMethods(a.Method, b.Method)
+		}
+		if a.Route.Path != b.Route.Path {
+			return strings.Compare(a.Route.Path, b.Route.Path)
+		}
+		return strings.Compare(a.Other.Path, b.Other.Path)
+	})
+	return conflicts
+}
+
+// StrictConflicts makes Validate report overlapping routes as errors instead of warnings
+func (r *Router) StrictConflicts(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strictConflicts = enable
+	return r
+}
+
+// ----------- CONFLICT DETECTION -----------
+
+type conflictRoute struct {
+	slot           *RequestHandlerPackage
+	handlerPackage *RequestHandlerPackage
+	segments       []routeSegment
+}
+
+func newConflictRoute(slot *RequestHandlerPackage, handlerPackage *RequestHandlerPackage) conflictRoute {
+	// The variant without the optional parameter is the second path of the expansion
+	paths, _ := expandOptionalParam(handlerPackage.Path)
+	path := paths[0]
+	if handlerPackage.expanded {
+		path = paths[len(paths)-1]
+	}
+	parsed, _ := parseRoutePath(path, handlerPackage.caseSensitive, handlerPackage.paramPattern)
+	return conflictRoute{slot: slot, handlerPackage: handlerPackage, segments: parsed.segments}
+}
+
+// variantOf reports whether both routes come from the same optional parameter route, or
+// one is an alias of the other
+func (c conflictRoute) variantOf(other conflictRoute) bool {
+	if c.handlerPackage.target() == other.handlerPackage.target() {
+		return true
+	}
+	return c.handlerPackage.expanded != other.handlerPackage.expanded && c.handlerPackage.Path == other.handlerPackage.Path
+}
+
+// eachSlot visits every stored route slot, the routes sharing method and pattern
+func (r *Router) eachSlot(fn func(method HttpMethod, slot *RequestHandlerPackage)) {
+	for _, routeMaps := range []map[HttpMethod]map[string]*RequestHandlerPackage{r.staticRoutes, r.exactRoutes} {
+		for method, routes := range routeMaps {
+			for _, slot := range routes {
+				fn(method, slot)
+			}
+		}
+	}
+	r.tree.walk(fn)
+}
+
+func (c conflictRoute) duplicateOf(method HttpMethod, fallback *RequestHandlerPackage) Conflict {
+	return Conflict{
+		Severity:  ConflictError,
+		Method:    method,
+		Route:     c.handlerPackage.info(method),
+		Other:     fallback.info(method),
+		Example:   pathSample(c.segments),
+		Duplicate: true,
+	}
+}
+
+// overlapExample returns a path matched by both segment lists, if any
+func overlapExample(a []routeSegment, b []routeSegment) (string, bool) {
+	parts := []string{}
+	for i := 0; ; i++ {
+		if i == len(a) || i == len(b) {
+			return strings.Join(parts, "/"), len(a) == len(b)
+		}
+
+		// A catch-all matches whatever the other route has left
+		if a[i].catchAll || b[i].catchAll {
+			rest := a[i:]
+			if a[i].catchAll {
+				rest = b[i:]
+			}
+			return strings.Join(append(parts, pathSample(rest)), "/"), true
+		}
+
+		part, overlaps := segmentOverlap(a[i], b[i])
+		if !overlaps {
+			return "", false
+		}
+		parts = append(parts, part)
+	}
+}
+
+// segmentOverlap returns a segment value matched by both segments. Parameter patterns are
+// compared through sample values, overlaps found by no sample are not reported.
+func segmentOverlap(a routeSegment, b routeSegment) (string, bool) {
+	switch {
+	case a.pattern == nil && b.pattern == nil:
+		if a.caseSensitive && b.caseSensitive {
+			return a.literal, a.literal == b.literal
+		}
+		if a.caseSensitive {
+			return a.literal, strings.EqualFold(a.literal, b.literal)
+		}
+		return b.literal, strings.EqualFold(a.literal, b.literal)
+	case a.pattern == nil:
+		return a.literal, b.pattern.MatchString(a.literal)
+	case b.pattern == nil:
+		return b.literal, a.pattern.MatchString(b.literal)
+	}
+
+	for _, sample := range patternSamples(a.pattern.String()) {
+		if b.pattern.MatchString(sample) {
+			return sample, true
+		}
+	}
+	for _, sample := range patternSamples(b.pattern.String()) {
+		if a.pattern.MatchString(sample) {
+			return sample, true
+		}
+	}
+	return "", false
+}
+
+// compareMethods orders methods following HttpMethods, custom ones last
+func compareMethods(a HttpMethod, b HttpMethod) int {
+	indexA, indexB := slices.Index(HttpMethods, a), slices.Index(HttpMethods, b)
+	switch {
+	case indexA < 0 && indexB < 0:
+		return strings.Compare(string(a), string(b))
+	case indexA < 0:
+		return 1
+	case indexB < 0:
+		return -1
+	}
+	return indexA - indexB
+}
+
+func pathSample(segments []routeSegment) string {
+	parts := make([]string, len(segments))
+	for i, segment := range segments {
+		parts[i] = segmentSample(segment)
+	}
+	return strings.Join(parts, "/")
+}
+
+func segmentSample(segment routeSegment) string {
+	if segment.catchAll {
+		return sampleRunes[:1]
+	}
+	if segment.pattern == nil {
+		return segment.literal
+	}
+	if samples := patternSamples(segment.pattern.String()); len(samples) > 0 {
+		return samples[0]
+	}
+	return ""
+}
+
+// ----------- PATTERN SAMPLES -----------
+
+// Characters classes are sampled with the first of these runes they contain
+const sampleRunes = "x1aA0-_.~"
+
+// patternSamples returns strings matched by the pattern, one per preferred sample rune
+func patternSamples(pattern string) []string {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	parsed = parsed.Simplify()
+
+	samples := []string{}
+	for _, preferred := range sampleRunes {
+		builder := strings.Builder{}
+		if writeSample(&builder, parsed, preferred) && !slices.Contains(samples, builder.String()) {
+			samples = append(samples, builder.String())
+		}
+	}
+	return samples
+}
+
+func writeSample(builder *strings.Builder, re *syntax.Regexp, preferred rune) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			builder.WriteString(strings.ToLower(string(re.Rune)))
+		} else {
+			builder.WriteString(string(re.Rune))
+		}
+	case syntax.OpCharClass:
+		sample, found := classSample(re.Rune, preferred)
+		if !found {
+			return false
+		}
+		builder.WriteRune(sample)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		builder.WriteRune(preferred)
+	case syntax.OpCapture, syntax.OpPlus:
+		return writeSample(builder, re.Sub[0], preferred)
+	case syntax.OpRepeat:
+		for range re.Min {
+			if !writeSample(builder, re.Sub[0], preferred) {
+				return false
+			}
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !writeSample(builder, sub, preferred) {
+				return false
+			}
+		}
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			alternative := strings.Builder{}
+			if writeSample(&alternative, sub, preferred) {
+				builder.WriteString(alternative.String())
+				return true
+			}
+		}
+		return false
+	case syntax.OpNoMatch:
+		return false
+	}
+	// Empty matches, optional and repeated parts and assertions contribute nothing
+	return true
+}
+
+// classSample picks the preferred rune when the class contains it, then the other sample
+// runes and the first printable rune of the class other than `/`.
+func classSample(ranges []rune, preferred rune) (rune, bool) {
+	for _, sample := range string(preferred) + sampleRunes {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= sample && sample <= ranges[i+1] {
+				return sample, true
+			}
+		}
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for sample := max(ranges[i], ' '); sample <= ranges[i+1] && sample <= unicode.MaxASCII; sample++ {
+			if sample != '/' && unicode.IsPrint(sample) {
+				return sample, true
+			}
+		}
+	}
+	return 0, false
+}
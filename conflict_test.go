@@ -0,0 +1,86 @@
+package yagaw
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestValidateReportsConflicts(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/users/{id}", handler)
+	router.RegisterRoute(GET, "/users/{name}", handler)
+	router.RegisterRoute(GET, "/files/{a}/{b}", handler)
+	router.RegisterRoute(GET, "/files/static/{b}", handler)
+	router.RegisterRoute(GET, "/orders/{id:int}", handler)
+	router.RegisterRoute(GET, "/orders/{code:alpha}", handler)
+	router.RegisterRoute(GET, "/hex/{id:[0-9a-f]+}", handler)
+	router.RegisterRoute(GET, "/hex/{id:[0-9]{3}}", handler)
+	router.RegisterRoute(GET, "/assets/{*path}", handler)
+	router.RegisterRoute(GET, "/assets/img/{name}.png", handler)
+	router.RegisterRoute(POST, "/users/{id}", handler)
+	router.RegisterRoute(GET, "/hooks", handler).MatchHeader("X-Event", "push")
+	router.RegisterRoute(GET, "/hooks", handler)
+	router.Any("/ping", handler)
+	router.RegisterRoute(GET, "/ping", handler)
+	router.RegisterRoute(GET, "/reports/{id}.{format?}", handler)
+
+	expected := []struct {
+		severity  ConflictSeverity
+		route     string
+		other     string
+		example   string
+		duplicate bool
+	}{
+		{ConflictWarning, "/assets/{*path}", "/assets/img/{name}.png", "/assets/img/x.png", false},
+		{ConflictWarning, "/files/{a}/{b}", "/files/static/{b}", "/files/static/x", false},
+		{ConflictWarning, "/hex/{id:[0-9a-f]+}", "/hex/{id:[0-9]{3}}", "/hex/111", false},
+		{ConflictError, "/users/{id}", "/users/{name}", "/users/x", true},
+	}
+
+	conflicts := router.Validate()
+	if len(conflicts) != len(expected) {
+		t.Fatalf("expected %d conflicts, got %v", len(expected), conflicts)
+	}
+	for i, conflict := range conflicts {
+		tt := expected[i]
+		if conflict.Method != GET || conflict.Severity != tt.severity || conflict.Route.Path != tt.route || conflict.Other.Path != tt.other || conflict.Example != tt.example || conflict.Duplicate != tt.duplicate {
+			t.Errorf("expected %v `%s` against `%s` on %s, got %s", tt.severity, tt.route, tt.other, tt.example, conflict)
+		}
+	}
+
+	router.StrictConflicts(true)
+	for _, conflict := range router.Validate() {
+		if conflict.Severity != ConflictError {
+			t.Errorf("expected every conflict to be an error in strict mode, got %s", conflict)
+		}
+	}
+}
+
+func TestValidateExamplesMatchBothRoutes(t *testing.T) {
+	router := NewRouter()
+	for i, path := range []string{"/v{version}/items", "/{prefix}/items", "/v1/{resource}", "/{lang?:alpha}", "/en"} {
+		router.RegisterRoute(GET, path, func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(path)
+		}).Priority(i)
+	}
+
+	conflicts := router.Validate()
+	if len(conflicts) == 0 {
+		t.Fatal("expected conflicts")
+	}
+	for _, conflict := range conflicts {
+		for _, path := range []string{conflict.Route.Path, conflict.Other.Path} {
+			probe := NewRouter()
+			probe.RegisterRoute(GET, path, func(req *http.Request, params Params) *HttpResponse {
+				return NewHttpResponse(http.StatusOK)
+			})
+			if match, _ := probe.match(&http.Request{Method: string(GET)}, GET, conflict.Example); match.handlerPackage == nil {
+				t.Errorf("example %q of %s does not match `%s`", conflict.Example, conflict, path)
+			}
+		}
+	}
+}
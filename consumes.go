@@ -0,0 +1,99 @@
+package yagaw
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Consumes restricts the request Content-Type accepted by the route, overriding the router
+// default. Parameters like charset are ignored and `application/*` accepts every subtype,
+// other types get a 415. No types disables the check for the route.
+func (rt *Route) Consumes(types ...string) *Route {
+	if rt.err != nil {
+		return rt
+	}
+	consumes := make([]string, 0, len(types))
+	for _, contentType := range types {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			rt.err = fmt.Errorf("invalid media type `%s`: %w", contentType, err)
+			return rt
+		}
+		consumes = append(consumes, mediaType)
+	}
+
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+	for _, handlerPackage := range rt.handlerPackages {
+		handlerPackage.consumes = consumes
+	}
+	return rt
+}
+
+// Consumes sets the request Content-Type accepted by the routes without their own
+// restriction, invalid media types are ignored.
+func (r *Router) Consumes(types ...string) *Router {
+	consumes := []string{}
+	for _, contentType := range types {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			consumes = append(consumes, mediaType)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consumes = consumes
+	return r
+}
+
+// SetUnsupportedMediaTypeHandler replaces the handler used when the request Content-Type
+// is not accepted by the route, a nil handler restores the default plain text 415. The
+// accepted types are listed in the Accept-Post header, Accept-Patch for PATCH requests.
+func (r *Router) SetUnsupportedMediaTypeHandler(handler HttpRequestHandler) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.unsupportedMediaType = handler
+	return r
+}
+
+func (r *Router) routeConsumes(handlerPackage *RequestHandlerPackage) []string {
+	if handlerPackage.consumes != nil {
+		return handlerPackage.consumes
+	}
+	return r.consumes
+}
+
+// checkContentType replaces the match with the 415 handler when the route doesn't accept
+// the request Content-Type. GET, HEAD and DELETE requests without a body are not checked.
+func (r *Router) checkContentType(req *http.Request, match routeMatch) routeMatch {
+	if len(match.consumes) == 0 || !hasBody(req) && (req.Method == string(GET) || req.Method == string(HEAD) || req.Method == string(DELETE)) {
+		return match
+	}
+
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err == nil && acceptsMediaType(match.consumes, mediaType) {
+		return match
+	}
+	return routeMatch{
+		handlerPackage: &RequestHandlerPackage{Handler: handlerOr(r.unsupportedMediaType, unsupportedMediaTypeHandler)},
+		accepted:       match.consumes,
+	}
+}
+
+func acceptsMediaType(consumes []string, mediaType string) bool {
+	for _, accepted := range consumes {
+		if accepted == mediaType {
+			return true
+		}
+		if prefix, isWildcard := strings.CutSuffix(accepted, "/*"); isWildcard && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasBody(req *http.Request) bool {
+	return req.ContentLength != 0 || len(req.TransferEncoding) > 0
+}
@@ -0,0 +1,109 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteConsumes(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("ok")
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(POST, "/users", handler).Consumes("application/json", "application/xml")
+	router.RegisterRoute(PATCH, "/users/{id}", handler).Consumes("application/merge-patch+json")
+	router.RegisterRoute(GET, "/users", handler).Consumes("application/json")
+	router.RegisterRoute(POST, "/uploads", handler).Consumes("image/*")
+	router.RegisterRoute(POST, "/anything", handler)
+
+	tests := []struct {
+		method      HttpMethod
+		path        string
+		contentType string
+		body        string
+		status      int
+		accept      string
+	}{
+		{POST, "/users", "application/json", "{}", http.StatusOK, ""},
+		{POST, "/users", "application/json; charset=utf-8", "{}", http.StatusOK, ""},
+		{POST, "/users", "Application/XML", "<user/>", http.StatusOK, ""},
+		{POST, "/users", "text/plain", "john", http.StatusUnsupportedMediaType, "application/json, application/xml"},
+		{POST, "/users", "", "john", http.StatusUnsupportedMediaType, "application/json, application/xml"},
+		{PATCH, "/users/7", "application/json", "{}", http.StatusUnsupportedMediaType, "application/merge-patch+json"},
+		{GET, "/users", "", "", http.StatusOK, ""},
+		{GET, "/users", "text/plain", "john", http.StatusUnsupportedMediaType, "application/json"},
+		{POST, "/uploads", "image/png", "png", http.StatusOK, ""},
+		{POST, "/uploads", "video/mp4", "mp4", http.StatusUnsupportedMediaType, "image/*"},
+		{POST, "/anything", "text/plain", "john", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method)+" "+tt.path+" "+tt.contentType, func(t *testing.T) {
+			req := httptest.NewRequest(string(tt.method), tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			acceptHeader := "Accept-Post"
+			if tt.method == PATCH {
+				acceptHeader = "Accept-Patch"
+			}
+			if accept := rw.Header().Get(acceptHeader); accept != tt.accept {
+				t.Errorf("expected %s %q, got %q", acceptHeader, tt.accept, accept)
+			}
+		})
+	}
+
+	if err := router.RegisterRoute(PUT, "/users/{id}", handler).Consumes("application/").Err(); err == nil {
+		t.Error("expected an error for an invalid media type")
+	}
+}
+
+func TestRouterConsumesDefault(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router := NewRouter().Consumes("application/json")
+	router.RegisterRoute(POST, "/users", handler)
+	router.RegisterRoute(POST, "/uploads", handler).Consumes("multipart/form-data")
+	router.RegisterRoute(POST, "/webhooks", handler).Consumes()
+	router.SetUnsupportedMediaTypeHandler(func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusUnsupportedMediaType).SetBody(`{"error":"unsupported media type"}`)
+	})
+
+	tests := []struct {
+		path        string
+		contentType string
+		status      int
+		body        string
+	}{
+		{"/users", "application/json", http.StatusOK, ""},
+		{"/users", "text/plain", http.StatusUnsupportedMediaType, `{"error":"unsupported media type"}`},
+		{"/uploads", "multipart/form-data; boundary=x", http.StatusOK, ""},
+		{"/uploads", "application/json", http.StatusUnsupportedMediaType, `{"error":"unsupported media type"}`},
+		{"/webhooks", "text/plain", http.StatusOK, ""},
+		{"/missing", "text/plain", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.contentType, func(t *testing.T) {
+			req := httptest.NewRequest(string(POST), tt.path, strings.NewReader("body"))
+			req.Header.Set("Content-Type", tt.contentType)
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+		})
+	}
+}
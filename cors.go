@@ -0,0 +1,219 @@
+package yagaw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------- CORS -----------
+
+var ErrCORSWildcardCredentials = errors.New("CORS credentials can't be allowed for any origin")
+
+// CORSOptions configures the middleware returned by CORS
+type CORSOptions struct {
+	// AllowedOrigins lists the origins allowed, exactly like `https://app.example.com`, as
+	// subdomains like `https://*.example.com` or all of them with `*`
+	AllowedOrigins []string
+	// AllowedMethods lists the methods preflights may ask for, GET, HEAD and POST unless set
+	AllowedMethods []HttpMethod
+	// AllowedHeaders lists the request headers preflights may ask for, `*` allowing any
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers scripts may read besides the safelisted ones
+	ExposedHeaders []string
+	// AllowCredentials lets cookies and authorization headers be sent, it can't be combined
+	// with the `*` origin
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the preflight responses, their default unless set
+	MaxAge time.Duration
+	// AllowOriginFunc decides per request whether the origin is allowed, e.g. for origins
+	// held in a database, in place of AllowedOrigins. A panic refuses the origin
+	AllowOriginFunc func(req *http.Request, origin string) bool
+}
+
+type originPattern struct {
+	prefix string
+	suffix string
+}
+
+type cors struct {
+	exact          []string
+	patterns       []originPattern
+	anyOrigin      bool
+	methods        []string
+	allowedMethods string
+	headers        []string
+	anyHeader      bool
+	exposedHeaders string
+	credentials    bool
+	maxAge         string
+	originFunc     func(req *http.Request, origin string) bool
+}
+
+// CORS returns server middleware answering the preflight OPTIONS requests of the allowed
+// origins with 204, without reaching the routes, and adding the CORS headers to their other
+// responses. Requests from other origins get no CORS headers, browsers refusing them then.
+// Options are checked upfront, allowing credentials for any origin is refused.
+func CORS(options CORSOptions) (func(http.Handler) http.Handler, error) {
+	settings := &cors{credentials: options.AllowCredentials, originFunc: options.AllowOriginFunc}
+	for _, origin := range options.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		prefix, suffix, isPattern := strings.Cut(origin, "*")
+		switch {
+		case origin == "*":
+			settings.anyOrigin = true
+		case !isPattern:
+			settings.exact = append(settings.exact, origin)
+		case strings.Contains(suffix, "*") || !strings.HasSuffix(prefix, "://") || !strings.HasPrefix(suffix, "."):
+			return nil, fmt.Errorf("invalid CORS origin `%s`: only subdomain wildcards like `https://*.example.com` are supported", origin)
+		default:
+			settings.patterns = append(settings.patterns, originPattern{prefix: prefix, suffix: suffix})
+		}
+	}
+	if settings.anyOrigin && settings.credentials {
+		return nil, ErrCORSWildcardCredentials
+	}
+
+	settings.methods = []string{string(GET), string(HEAD), string(POST)}
+	if len(options.AllowedMethods) > 0 {
+		settings.methods = settings.methods[:0]
+		for _, method := range options.AllowedMethods {
+			settings.methods = append(settings.methods, string(method))
+		}
+	}
+	settings.allowedMethods = strings.Join(settings.methods, ", ")
+	for _, header := range options.AllowedHeaders {
+		if header == "*" {
+			settings.anyHeader = true
+			continue
+		}
+		settings.headers = append(settings.headers, http.CanonicalHeaderKey(header))
+	}
+	settings.exposedHeaders = strings.Join(options.ExposedHeaders, ", ")
+	if options.MaxAge > 0 {
+		settings.maxAge = strconv.Itoa(int(options.MaxAge.Round(time.Second).Seconds()))
+	}
+	return settings.handler, nil
+}
+
+// MustCORS is like CORS but panics when the options are invalid
+func MustCORS(options CORSOptions) func(http.Handler) http.Handler {
+	middleware, err := CORS(options)
+	if err != nil {
+		panic(err)
+	}
+	return middleware
+}
+
+func (c *cors) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if req.Method == http.MethodOptions && origin != "" && req.Header.Get("Access-Control-Request-Method") != "" {
+			c.preflight(rw, req, origin)
+			return
+		}
+
+		// Responses differ by origin, caches must not hand one to another
+		rw.Header().Add("Vary", "Origin")
+		if origin != "" && c.allowedOrigin(req, origin) {
+			c.allowOrigin(rw.Header(), origin)
+			if c.exposedHeaders != "" {
+				rw.Header().Set("Access-Control-Expose-Headers", c.exposedHeaders)
+			}
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// preflight answers the preflight request, without CORS headers when anything it asks for
+// is not allowed.
+func (c *cors) preflight(rw http.ResponseWriter, req *http.Request, origin string) {
+	headers := rw.Header()
+	headers.Add("Vary", "Origin")
+	headers.Add("Vary", "Access-Control-Request-Method")
+	headers.Add("Vary", "Access-Control-Request-Headers")
+	defer rw.WriteHeader(http.StatusNoContent)
+
+	requestedMethod := req.Header.Get("Access-Control-Request-Method")
+	if !c.allowedOrigin(req, origin) || !slices.Contains(c.methods, requestedMethod) {
+		return
+	}
+	requestedHeaders := []string{}
+	for _, header := range strings.Split(req.Header.Get("Access-Control-Request-Headers"), ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			requestedHeaders = append(requestedHeaders, http.CanonicalHeaderKey(header))
+		}
+	}
+	if !c.anyHeader && slices.ContainsFunc(requestedHeaders, func(header string) bool { return !slices.Contains(c.headers, header) }) {
+		return
+	}
+
+	c.allowOrigin(headers, origin)
+	headers.Set("Access-Control-Allow-Methods", c.allowedMethods)
+	if len(requestedHeaders) > 0 {
+		headers.Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+	}
+	if c.maxAge != "" {
+		headers.Set("Access-Control-Max-Age", c.maxAge)
+	}
+}
+
+func (c *cors) allowOrigin(headers http.Header, origin string) {
+	if c.anyOrigin && !c.credentials && c.originFunc == nil {
+		headers.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	headers.Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		headers.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *cors) allowedOrigin(req *http.Request, origin string) bool {
+	if c.originFunc != nil {
+		return c.callOriginFunc(req, origin)
+	}
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(c.exact, origin) {
+		return true
+	}
+	return slices.ContainsFunc(c.patterns, func(pattern originPattern) bool {
+		subdomain, found := strings.CutPrefix(origin, pattern.prefix)
+		if !found {
+			return false
+		}
+		subdomain, found = strings.CutSuffix(subdomain, pattern.suffix)
+		return found && isSubdomain(subdomain)
+	})
+}
+
+// callOriginFunc asks AllowOriginFunc, refusing the origin when it panics
+func (c *cors) callOriginFunc(req *http.Request, origin string) (allowed bool) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			Log.Error(fmt.Sprintf("CORS origin check of `%s` panicked: %v", origin, recovered))
+			allowed = false
+		}
+	}()
+	return c.originFunc(req, origin)
+}
+
+// isSubdomain reports whether the labels matched by an origin wildcard are a host name part
+func isSubdomain(labels string) bool {
+	if labels == "" || strings.HasPrefix(labels, ".") || strings.HasSuffix(labels, ".") {
+		return false
+	}
+	for _, char := range labels {
+		if (char < 'a' || char > 'z') && (char < '0' || char > '9') && char != '-' && char != '.' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,206 @@
+package yagaw
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pho3b/tiny-logger/logs/log_level"
+)
+
+func TestCORSOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CORSOptions
+		valid   bool
+	}{
+		{"exact and subdomains", CORSOptions{AllowedOrigins: []string{"https://app.test", "https://*.example.test"}, AllowCredentials: true}, true},
+		{"any origin", CORSOptions{AllowedOrigins: []string{"*"}}, true},
+		{"any origin with credentials", CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}, false},
+		{"wildcard inside a label", CORSOptions{AllowedOrigins: []string{"https://app-*.example.test"}}, false},
+		{"two wildcards", CORSOptions{AllowedOrigins: []string{"https://*.*.example.test"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CORS(tt.options)
+			if tt.valid != (err == nil) {
+				t.Errorf("expected valid: %t, got %v", tt.valid, err)
+			}
+		})
+	}
+
+	if _, err := CORS(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true}); !errors.Is(err, ErrCORSWildcardCredentials) {
+		t.Errorf("expected ErrCORSWildcardCredentials, got %v", err)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	routeCalls := 0
+	newServer := func(options CORSOptions) *Server {
+		server := NewServer("127.0.0.1", 0).Use(MustCORS(options))
+		server.GetRouter().RegisterRoute(GET, "/users", func(req *http.Request, params Params) *HttpResponse {
+			routeCalls++
+			return NewHttpResponse(http.StatusOK).SetBody("users")
+		})
+		return server
+	}
+	serve := func(server *Server, method string, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/users", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rw := httptest.NewRecorder()
+		server.serveHTTP(rw, req)
+		return rw
+	}
+	server := newServer(CORSOptions{
+		AllowedOrigins:   []string{"https://app.test", "https://*.example.test"},
+		AllowedMethods:   []HttpMethod{GET, PUT},
+		AllowedHeaders:   []string{"Authorization", "content-type"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			origin  string
+			method  string
+			headers string
+			allowed bool
+		}{
+			{"allowed", "https://app.test", "PUT", "authorization, Content-Type", true},
+			{"subdomain", "https://eu.api.example.test", "GET", "", true},
+			{"origin not allowed", "https://evil.test", "PUT", "", false},
+			{"apex of a wildcard", "https://example.test", "PUT", "", false},
+			{"method not allowed", "https://app.test", "DELETE", "", false},
+			{"header not allowed", "https://app.test", "PUT", "X-Debug", false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				routeCalls = 0
+				rw := serve(server, http.MethodOptions, tt.origin, map[string]string{
+					"Access-Control-Request-Method":  tt.method,
+					"Access-Control-Request-Headers": tt.headers,
+				})
+				if rw.Code != http.StatusNoContent || routeCalls != 0 {
+					t.Errorf("expected a 204 without reaching the route, got %d and %d calls", rw.Code, routeCalls)
+				}
+				if rw.Header().Values("Vary")[0] != "Origin" {
+					t.Errorf("expected Vary: Origin, got %v", rw.Header().Values("Vary"))
+				}
+				allowOrigin := rw.Header().Get("Access-Control-Allow-Origin")
+				if !tt.allowed {
+					if allowOrigin != "" || rw.Header().Get("Access-Control-Allow-Methods") != "" {
+						t.Errorf("expected no CORS headers, got %v", rw.Header())
+					}
+					return
+				}
+				if allowOrigin != tt.origin || rw.Header().Get("Access-Control-Allow-Methods") != "GET, PUT" ||
+					rw.Header().Get("Access-Control-Max-Age") != "600" || rw.Header().Get("Access-Control-Allow-Credentials") != "true" {
+					t.Errorf("expected the preflight to be allowed, got %v", rw.Header())
+				}
+				if tt.headers != "" && rw.Header().Get("Access-Control-Allow-Headers") != "Authorization, Content-Type" {
+					t.Errorf("expected the requested headers to be allowed, got %q", rw.Header().Get("Access-Control-Allow-Headers"))
+				}
+			})
+		}
+	})
+
+	t.Run("credentialed request", func(t *testing.T) {
+		rw := serve(server, http.MethodGet, "https://eu.example.test", map[string]string{"Cookie": "session=1"})
+		if rw.Code != http.StatusOK || rw.Body.String() != "users" {
+			t.Errorf("expected the route to be served, got %d %q", rw.Code, rw.Body.String())
+		}
+		if rw.Header().Get("Access-Control-Allow-Origin") != "https://eu.example.test" ||
+			rw.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+			rw.Header().Get("Access-Control-Expose-Headers") != "X-Request-Id" ||
+			rw.Header().Get("Vary") != "Origin" {
+			t.Errorf("expected the CORS headers, got %v", rw.Header())
+		}
+	})
+
+	t.Run("origin not allowed", func(t *testing.T) {
+		rw := serve(server, http.MethodGet, "https://eu.example.test.evil.test", nil)
+		if rw.Code != http.StatusOK || rw.Header().Get("Access-Control-Allow-Origin") != "" || rw.Header().Get("Vary") != "Origin" {
+			t.Errorf("expected the route without CORS headers, got %d %v", rw.Code, rw.Header())
+		}
+	})
+
+	t.Run("same origin", func(t *testing.T) {
+		rw := serve(server, http.MethodGet, "", nil)
+		if rw.Code != http.StatusOK || rw.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("expected the route without CORS headers, got %d %v", rw.Code, rw.Header())
+		}
+	})
+
+	t.Run("simple request from any origin", func(t *testing.T) {
+		rw := serve(newServer(CORSOptions{AllowedOrigins: []string{"*"}}), http.MethodGet, "https://anywhere.test", nil)
+		if rw.Header().Get("Access-Control-Allow-Origin") != "*" || rw.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("expected any origin to be allowed, got %v", rw.Header())
+		}
+	})
+}
+
+func TestCORSAllowOriginFunc(t *testing.T) {
+	read := captureLog(t, log_level.ErrorLvlName)
+	server := NewServer("127.0.0.1", 0).Use(MustCORS(CORSOptions{
+		// The callback takes precedence over the static list
+		AllowedOrigins:   []string{"https://static.test"},
+		AllowedMethods:   []HttpMethod{GET, PUT},
+		AllowCredentials: true,
+		AllowOriginFunc: func(req *http.Request, origin string) bool {
+			if origin == "https://broken.test" {
+				panic("tenant store unreachable")
+			}
+			return origin == "https://tenant-a.test" && req.URL.Path == "/users"
+		},
+	}))
+	server.GetRouter().RegisterRoute(GET, "/users", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("users")
+	})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://tenant-a.test", true},
+		{"https://tenant-b.test", false},
+		{"https://static.test", false},
+		{"https://broken.test", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			for _, method := range []string{http.MethodOptions, http.MethodGet} {
+				req := httptest.NewRequest(method, "/users", nil)
+				req.Header.Set("Origin", tt.origin)
+				if method == http.MethodOptions {
+					req.Header.Set("Access-Control-Request-Method", "PUT")
+				}
+				rw := httptest.NewRecorder()
+				server.serveHTTP(rw, req)
+
+				if rw.Header().Values("Vary")[0] != "Origin" {
+					t.Errorf("expected Vary: Origin on the %s response, got %v", method, rw.Header().Values("Vary"))
+				}
+				expected := ""
+				if tt.allowed {
+					expected = tt.origin
+				}
+				if allowOrigin := rw.Header().Get("Access-Control-Allow-Origin"); allowOrigin != expected {
+					t.Errorf("expected the %s response to allow %q, got %q", method, expected, allowOrigin)
+				}
+			}
+		})
+	}
+	if !strings.Contains(read(), "tenant store unreachable") {
+		t.Errorf("expected the panic to be logged, got %q", read())
+	}
+}
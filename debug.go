@@ -0,0 +1,139 @@
+package yagaw
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ----------- DEBUG ROUTES -----------
+
+// DebugRoute is the JSON description of a route served by the debug endpoint
+type DebugRoute struct {
+	Method      HttpMethod        `json:"method"`
+	Path        string            `json:"path"`
+	Name        string            `json:"name,omitempty"`
+	Handler     string            `json:"handler"`
+	Params      []string          `json:"params"`
+	Constraints map[string]string `json:"constraints,omitempty"`
+	Middleware  []string          `json:"middleware"`
+	AnyMethod   bool              `json:"anyMethod,omitempty"`
+	Implicit    bool              `json:"implicit,omitempty"`
+	Priority    int               `json:"priority,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Deprecated  bool              `json:"deprecated,omitempty"`
+	Aliases     []string          `json:"aliases,omitempty"`
+	Consumes    []string          `json:"consumes,omitempty"`
+	Produces    []string          `json:"produces,omitempty"`
+}
+
+// EnableDebugRoutes registers a GET route at path answering with the route table as JSON,
+// wrapped by the given middleware. The debug endpoint is not registered unless enabled.
+func (r *Router) EnableDebugRoutes(path string, mw ...Middleware) *Route {
+	return r.RegisterRouteWith(GET, path, r.DebugRoutesHandler(), mw...)
+}
+
+// DebugRoutesHandler returns the handler of the debug endpoint, for registering it under a
+// group. Routes are sorted by path then method so that two deployments can be diffed.
+func (r *Router) DebugRoutesHandler() HttpRequestHandler {
+	return func(req *http.Request, _ Params) *HttpResponse {
+		body, err := json.MarshalIndent(r.debugRoutes(), "", "  ")
+		if err != nil {
+			return NewHttpResponse(http.StatusInternalServerError).SetBody("500 - Internal server error")
+		}
+		return NewHttpResponse(http.StatusOK).SetHeader("Content-Type", "application/json").SetBody(string(body) + "\n")
+	}
+}
+
+func (r *Router) debugRoutes() []DebugRoute {
+	orderedRoutes := r.orderedRoutes()
+	slices.SortStableFunc(orderedRoutes, func(a, b orderedRoute) int {
+		return cmp.Compare(a.handlerPackage.Path, b.handlerPackage.Path)
+	})
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make([]DebugRoute, len(orderedRoutes))
+	for i, route := range orderedRoutes {
+		handlerPackage := route.handlerPackage
+		routes[i] = DebugRoute{
+			Method:      route.method,
+			Path:        handlerPackage.Path,
+			Name:        handlerPackage.name,
+			Handler:     handlerName(handlerPackage.Handler),
+			Params:      append([]string{}, handlerPackage.paramNames...),
+			Constraints: handlerPackage.constraints(),
+			Middleware:  middlewareNames(handlerPackage.middlewareStack(r.middleware)),
+			AnyMethod:   handlerPackage.anyMethod,
+			Implicit:    handlerPackage.implicitHead,
+			Priority:    handlerPackage.priority,
+			Version:     handlerPackage.routeVersion(),
+			Deprecated:  handlerPackage.isDeprecated(),
+			Aliases:     handlerPackage.aliasPaths(),
+			Consumes:    slices.Clone(handlerPackage.consumes),
+			Produces:    slices.Clone(handlerPackage.produces),
+		}
+	}
+	return routes
+}
+
+// middlewareStack returns the middleware wrapping the route from the outermost one, in the
+// reverse order of chain.
+func (p *RequestHandlerPackage) middlewareStack(routerMiddleware []Middleware) []Middleware {
+	groups := [][]Middleware{p.middleware}
+	for group := p.group; group != nil; group = group.parent {
+		groups = append(groups, group.middleware)
+	}
+	groups = append(groups, p.mergedMiddleware, routerMiddleware)
+
+	stack := []Middleware{}
+	for _, mw := range slices.Backward(groups) {
+		stack = append(stack, mw...)
+	}
+	return stack
+}
+
+// constraints returns the constraint of every parameter as declared in the path, parameters
+// without one report the default pattern of the route if any, catch-all ones never do.
+func (p *RequestHandlerPackage) constraints() map[string]string {
+	constraints := map[string]string{}
+	path := p.Path
+	for cursor := 0; ; {
+		open := strings.IndexByte(path[cursor:], '{')
+		if open < 0 {
+			break
+		}
+		open += cursor
+		end := closingBrace(path, open)
+		if end < 0 {
+			break
+		}
+		cursor = end + 1
+
+		name, constraint, hasConstraint := strings.Cut(path[open+1:end], ":")
+		name, isCatchAll := strings.CutPrefix(name, "*")
+		name = strings.TrimSuffix(name, "?")
+		if !hasConstraint && !isCatchAll {
+			constraint = p.paramPattern
+		}
+		if constraint != "" {
+			constraints[name] = constraint
+		}
+	}
+	if len(constraints) == 0 {
+		return nil
+	}
+	return constraints
+}
+
+func middlewareNames(middleware []Middleware) []string {
+	names := make([]string, len(middleware))
+	for i, mw := range middleware {
+		names[i] = runtime.FuncForPC(reflect.ValueOf(mw).Pointer()).Name()
+	}
+	return names
+}
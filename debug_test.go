@@ -0,0 +1,116 @@
+package yagaw
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func requireToken(next HttpRequestHandler) HttpRequestHandler {
+	return func(req *http.Request, params Params) *HttpResponse {
+		if req.Header.Get("Authorization") != "secret" {
+			return NewHttpResponse(http.StatusUnauthorized)
+		}
+		return next(req, params)
+	}
+}
+
+func TestEnableDebugRoutes(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+	newRouter := func() *Router {
+		router := NewRouter()
+		router.RegisterRoute(POST, "/users", handler)
+		router.RegisterRoute(GET, "/users/{id:int}", handler).Name("user")
+		api := router.Group("/api").Use(requireToken)
+		api.RegisterRoute(GET, "/files/{*rest}", handler)
+		router.RegisterRoute(GET, "/users", handler)
+		return router
+	}
+
+	t.Run("off by default", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		newRouter().ServeHTTP(rw, httptest.NewRequest(string(GET), "/_yagaw/routes", nil))
+
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rw.Code)
+		}
+	})
+
+	t.Run("route table as sorted JSON", func(t *testing.T) {
+		router := newRouter()
+		router.EnableDebugRoutes("/_yagaw/routes")
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/_yagaw/routes", nil))
+
+		if rw.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rw.Code)
+		}
+		if contentType := rw.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("expected application/json, got %q", contentType)
+		}
+		routes := []DebugRoute{}
+		if err := json.Unmarshal(rw.Body.Bytes(), &routes); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		listed := []string{}
+		for _, route := range routes {
+			listed = append(listed, string(route.Method)+" "+route.Path)
+		}
+		expected := []string{"GET /_yagaw/routes", "GET /api/files/{*rest}", "GET /users", "POST /users", "GET /users/{id:int}"}
+		if !slices.Equal(listed, expected) {
+			t.Fatalf("expected %v, got %v", expected, listed)
+		}
+
+		user := routes[4]
+		if user.Name != "user" || !slices.Equal(user.Params, []string{"id"}) || user.Constraints["id"] != "int" {
+			t.Errorf("unexpected user route %+v", user)
+		}
+		files := routes[1]
+		if len(files.Middleware) != 1 || !strings.HasSuffix(files.Middleware[0], ".requireToken") {
+			t.Errorf("expected the group middleware, got %v", files.Middleware)
+		}
+		if files.Constraints != nil {
+			t.Errorf("expected no constraint, got %v", files.Constraints)
+		}
+	})
+
+	t.Run("stable output", func(t *testing.T) {
+		bodies := []string{}
+		for range 2 {
+			router := newRouter()
+			router.EnableDebugRoutes("/_yagaw/routes")
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/_yagaw/routes", nil))
+			bodies = append(bodies, rw.Body.String())
+		}
+		if bodies[0] != bodies[1] {
+			t.Errorf("expected identical route tables, got %q and %q", bodies[0], bodies[1])
+		}
+	})
+
+	t.Run("behind middleware", func(t *testing.T) {
+		router := newRouter()
+		router.EnableDebugRoutes("/_yagaw/routes", requireToken)
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/_yagaw/routes", nil))
+		if rw.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", rw.Code)
+		}
+
+		req := httptest.NewRequest(string(GET), "/_yagaw/routes", nil)
+		req.Header.Set("Authorization", "secret")
+		rw = httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		if rw.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d", rw.Code)
+		}
+	})
+}
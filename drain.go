@@ -0,0 +1,75 @@
+package yagaw
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ----------- DRAIN MODE -----------
+
+// DefaultDrainRetryAfter is the Retry-After sent with the 503 responses of a draining server
+const DefaultDrainRetryAfter = 10 * time.Second
+
+type drainSettings struct {
+	exempt     []string
+	retryAfter time.Duration
+}
+
+// Drain makes the server answer new requests with 503 and a Retry-After header while those
+// in flight complete, readiness failing too, e.g. during a deployment before Shutdown. The
+// health and stats endpoints and the exempt paths keep being served.
+func (s *Server) Drain() *Server {
+	s.draining.Store(true)
+	return s
+}
+
+// Undrain serves requests again after Drain, e.g. when a deployment is aborted
+func (s *Server) Undrain() *Server {
+	s.draining.Store(false)
+	return s
+}
+
+// Draining reports whether the server is draining
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// DrainExempt keeps serving the given paths while draining, e.g. an admin endpoint
+func (s *Server) DrainExempt(paths ...string) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drain.exempt = append(s.drain.exempt, paths...)
+	s.composeHandler()
+	return s
+}
+
+// DrainRetryAfter sets the Retry-After of the responses sent while draining,
+// DefaultDrainRetryAfter unless set.
+func (s *Server) DrainRetryAfter(retryAfter time.Duration) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drain.retryAfter = retryAfter
+	s.composeHandler()
+	return s
+}
+
+// drainHandler answers with 503 while draining, the flag is the only cost otherwise
+func (s *Server) drainHandler(next http.Handler) http.Handler {
+	exempt := slices.Clone(s.drain.exempt)
+	retryAfter := strconv.Itoa(int(s.drain.retryAfter.Round(time.Second).Seconds()))
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !s.draining.Load() || slices.Contains(exempt, req.URL.Path) {
+			next.ServeHTTP(rw, req)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.Header().Set("Retry-After", retryAfter)
+		rw.Header().Set("Connection", "close")
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(rw, "503 - Service unavailable")
+	})
+}
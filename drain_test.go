@@ -0,0 +1,63 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerDrain(t *testing.T) {
+	server := NewServer("127.0.0.1", 0).
+		EnableHealth("/healthz", "/readyz").
+		DrainExempt("/admin/deploy").
+		DrainRetryAfter(30 * time.Second)
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("served")
+	}
+	server.GetRouter().RegisterRoute(GET, "/users/{id}", handler)
+	server.GetRouter().RegisterRoute(POST, "/admin/deploy", handler)
+
+	serve := func(method HttpMethod, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		server.serveHTTP(rw, httptest.NewRequest(string(method), path, nil))
+		return rw
+	}
+	tests := []struct {
+		method   HttpMethod
+		path     string
+		drained  int
+		expected int
+	}{
+		{GET, "/users/42", http.StatusServiceUnavailable, http.StatusOK},
+		{GET, "/missing", http.StatusServiceUnavailable, http.StatusNotFound},
+		{POST, "/admin/deploy", http.StatusOK, http.StatusOK},
+		{GET, "/healthz", http.StatusOK, http.StatusOK},
+		{GET, "/readyz", http.StatusServiceUnavailable, http.StatusOK},
+	}
+
+	server.Drain()
+	if !server.Draining() {
+		t.Fatal("expected the server to be draining")
+	}
+	for _, tt := range tests {
+		rw := serve(tt.method, tt.path)
+		if rw.Code != tt.drained {
+			t.Errorf("expected %s %s to get %d while draining, got %d", tt.method, tt.path, tt.drained, rw.Code)
+		}
+	}
+	if rw := serve(GET, "/users/42"); rw.Header().Get("Retry-After") != "30" || rw.Body.String() != "503 - Service unavailable" {
+		t.Errorf("expected a 503 with Retry-After, got %v %q", rw.Header(), rw.Body.String())
+	}
+
+	server.Undrain()
+	for _, tt := range tests {
+		rw := serve(tt.method, tt.path)
+		if rw.Code != tt.expected {
+			t.Errorf("expected %s %s to get %d once undrained, got %d", tt.method, tt.path, tt.expected, rw.Code)
+		}
+		if rw.Header().Get("Retry-After") != "" {
+			t.Errorf("expected no Retry-After once undrained, got %q", rw.Header().Get("Retry-After"))
+		}
+	}
+}
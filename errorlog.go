@@ -0,0 +1,43 @@
+package yagaw
+
+import (
+	"log"
+	"strings"
+)
+
+// ----------- NET/HTTP ERROR LOG -----------
+
+// tlsHandshakeError starts the lines net/http logs for failed TLS handshakes
+const tlsHandshakeError = "http: TLS handshake error"
+
+// errorLogWriter writes the lines of the net/http error log through Log, TLS handshake
+// errors at the debug level as scanners and health checks cause plenty of them.
+type errorLogWriter struct {
+	suppressHandshakeErrors bool
+}
+
+func (w errorLogWriter) Write(line []byte) (int, error) {
+	message := strings.TrimSuffix(string(line), "\n")
+	switch {
+	case !strings.HasPrefix(message, tlsHandshakeError):
+		Log.Error(message)
+	case !w.suppressHandshakeErrors:
+		Log.Debug(message)
+	}
+	return len(line), nil
+}
+
+// newErrorLog returns the logger given to the http.Server of every run
+func newErrorLog(suppressHandshakeErrors bool) *log.Logger {
+	return log.New(errorLogWriter{suppressHandshakeErrors: suppressHandshakeErrors}, "", 0)
+}
+
+// SuppressHandshakeErrors leaves out of the log the `TLS handshake error from ...` lines
+// net/http reports for clients failing the handshake, logged at the debug level otherwise.
+// The other errors of net/http, like malformed responses, are logged at the error level.
+func (s *Server) SuppressHandshakeErrors(suppress bool) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.suppressHandshakeErrors = suppress
+	return s
+}
@@ -0,0 +1,78 @@
+package yagaw
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Pho3b/tiny-logger/logs/log_level"
+)
+
+func TestServerErrorLog(t *testing.T) {
+	cert, _, _ := selfSignedCert(t)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	defer client.CloseIdleConnections()
+
+	tests := []struct {
+		name      string
+		suppress  bool
+		handshake bool
+	}{
+		{"handshake errors at debug", false, true},
+		{"handshake errors suppressed", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read := captureLog(t, log_level.DebugLvlName)
+			Log.ShowLogLevel(true)
+
+			server := NewServer("127.0.0.1", 0).
+				WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}).
+				SuppressHandshakeErrors(tt.suppress)
+			server.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					rw.WriteHeader(http.StatusAccepted)
+					rw.WriteHeader(http.StatusOK)
+				})
+			})
+			ran := make(chan error, 1)
+			go func() { ran <- server.RunTLS("", "") }()
+			address := awaitReady(t, server, ran)
+			defer server.Shutdown(context.Background())
+
+			// Plain HTTP on the TLS port fails the handshake
+			conn, err := net.Dial("tcp", address)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+			conn.Read(make([]byte, 512))
+			conn.Close()
+			// The connection is tracked as closed once the error is logged
+			waitFor(t, "the connection to be closed", func() bool { return server.conns.open.Load() == 0 })
+
+			res, err := client.Get("https://" + address + "/")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			res.Body.Close()
+
+			waitFor(t, "the superfluous WriteHeader to be logged", func() bool {
+				return strings.Contains(read(), "http: superfluous response.WriteHeader call")
+			})
+			for _, line := range strings.Split(read(), "\n") {
+				if strings.Contains(line, "superfluous") && !strings.Contains(line, "ERROR") {
+					t.Errorf("expected an error line, got %q", line)
+
+				}
+			}
+			handshakeLogged := strings.Contains(read(), "DEBUG") && strings.Contains(read(), "http: TLS handshake error from")
+			if handshakeLogged != tt.handshake {
+				t.Errorf("expected the handshake error to be logged: %t, got %q", tt.handshake, read())
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package yagaw
+
+import (
+	"net/http"
+	"strings"
+)
+
+type Group struct {
+	router     *Router
+	parent     *Group
+	prefix     string
+	middleware []Middleware
+	version    string
+	deprecated bool
+}
+
+func (g *Group) RegisterRoute(method HttpMethod, path string, handler HttpRequestHandler) *Route {
+	fullPath, err := g.routePath(method, path)
+	if err != nil {
+		return &Route{router: g.router, err: err}
+	}
+	return g.router.newRoute(method, &RequestHandlerPackage{Handler: handler, Path: fullPath, group: g})
+}
+
+func (g *Group) RegisterRouteWith(method HttpMethod, path string, handler HttpRequestHandler, mw ...Middleware) *Route {
+	fullPath, err := g.routePath(method, path)
+	if err != nil {
+		return &Route{router: g.router, err: err}
+	}
+	return g.router.newRoute(method, &RequestHandlerPackage{Handler: handler, Path: fullPath, group: g, middleware: mw})
+}
+
+func (g *Group) Any(path string, handler HttpRequestHandler) *Route {
+	fullPath, err := g.routePath(HttpMethods[0], path)
+	if err != nil {
+		return &Route{router: g.router, err: err}
+	}
+	return g.router.registerAny(&RequestHandlerPackage{Handler: handler, Path: fullPath, group: g})
+}
+
+func (g *Group) Handle(method HttpMethod, path string, handler http.Handler) *Route {
+	if handler == nil {
+		return g.RegisterRoute(method, path, nil)
+	}
+	return g.RegisterRoute(method, path, adaptHandler(handler))
+}
+
+func (g *Group) HandleFunc(method HttpMethod, path string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	if handler == nil {
+		return g.RegisterRoute(method, path, nil)
+	}
+	return g.Handle(method, path, http.HandlerFunc(handler))
+}
+
+// routePath validates the path of a route before the prefix hides a missing leading slash,
+// an empty path registering the route at the group prefix itself
+func (g *Group) routePath(method HttpMethod, path string) (string, error) {
+	if path != "" {
+		if err := validatePath(method, path); err != nil {
+			return "", err
+		}
+	}
+	return joinPaths(g.prefix, path), nil
+}
+
+func (g *Group) Get(path string, handler HttpRequestHandler) *Group {
+	return g.chainRoute(GET, path, handler)
+}
+
+func (g *Group) Post(path string, handler HttpRequestHandler) *Group {
+	return g.chainRoute(POST, path, handler)
+}
+
+func (g *Group) Put(path string, handler HttpRequestHandler) *Group {
+	return g.chainRoute(PUT, path, handler)
+}
+
+func (g *Group) Patch(path string, handler HttpRequestHandler) *Group {
+	return g.chainRoute(PATCH, path, handler)
+}
+
+func (g *Group) Delete(path string, handler HttpRequestHandler) *Group {
+	return g.chainRoute(DELETE, path, handler)
+}
+
+func (g *Group) Head(path string, handler HttpRequestHandler) *Group {
+	return g.chainRoute(HEAD, path, handler)
+}
+
+func (g *Group) Options(path string, handler HttpRequestHandler) *Group {
+	return g.chainRoute(OPTIONS, path, handler)
+}
+
+func (g *Group) chainRoute(method HttpMethod, path string, handler HttpRequestHandler) *Group {
+	if err := g.RegisterRoute(method, path, handler).Err(); err != nil {
+		Log.Error(err)
+	}
+	return g
+}
+
+func (g *Group) Group(prefix string) *Group {
+	return &Group{router: g.router, parent: g, prefix: joinPaths(g.prefix, prefix)}
+}
+
+// Use appends middleware wrapping the routes of the group and of its nested groups, it runs
+// inside the router middleware and outside the route middleware.
+func (g *Group) Use(mw ...Middleware) *Group {
+	g.router.mu.Lock()
+	defer g.router.mu.Unlock()
+	g.middleware = append(g.middleware, mw...)
+	return g
+}
+
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+func (r *Router) Group(prefix string) *Group {
+	return &Group{router: r, prefix: joinPaths("", prefix)}
+}
+
+// ----------- HELPERS -----------
+func joinPaths(prefix string, path string) string {
+	if path == "" {
+		return prefix
+	}
+	return strings.TrimSuffix(prefix, "/") + path
+}
@@ -0,0 +1,156 @@
+package yagaw
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGroupRegisterRoute(t *testing.T) {
+	router := NewRouter()
+
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+
+	api := router.Group("/api/v1")
+	api.RegisterRoute(GET, "/status", handlerFor("status"))
+
+	users := api.Group("/users")
+	users.RegisterRoute(GET, "", handlerFor("list"))
+	users.RegisterRoute(GET, "/{id}", handlerFor("show"))
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"group route", "/api/v1/status", "status"},
+		{"nested group root", "/api/v1/users", "list"},
+		{"nested group parameter", "/api/v1/users/42", "show"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rw.Code)
+			}
+			if rw.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("unprefixed path is not registered", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/status", nil))
+
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rw.Code)
+		}
+	})
+}
+
+func TestGroupPrefixWithParameters(t *testing.T) {
+	router := NewRouter()
+
+	var captured map[string]string
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		captured = PathParams(req)
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.Group("/tenants/{tenantId}/").Group("/projects").RegisterRoute(GET, "/{projectId}", handler)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/tenants/acme/projects/7", nil))
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rw.Code)
+	}
+	if captured["tenantId"] != "acme" || captured["projectId"] != "7" {
+		t.Errorf("expected tenantId=acme and projectId=7, got %v", captured)
+	}
+}
+
+func TestGroupRegisteredRoutesShowExpandedPaths(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	group := router.Group("/api/v1").Group("/users")
+	group.RegisterRoute(GET, "/{id}", handler)
+	group.RegisterRoute(POST, "", handler)
+
+	routes := *router.RegisteredRoutes()
+	if _, exists := routes[GET]["/api/v1/users/{id}"]; !exists {
+		t.Error("expected GET /api/v1/users/{id} to be registered")
+	}
+	if _, exists := routes[POST]["/api/v1/users"]; !exists {
+		t.Error("expected POST /api/v1/users to be registered")
+	}
+	if group.Prefix() != "/api/v1/users" {
+		t.Errorf("expected prefix '/api/v1/users', got %q", group.Prefix())
+	}
+}
+
+func TestGroupInvalidRegistrations(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	api := router.Group("/api")
+
+	tests := []struct {
+		name    string
+		route   func() *Route
+		message string
+	}{
+		{"path without leading slash", func() *Route { return api.RegisterRoute(GET, "users", handler) }, "GET users`: path must start with `/`"},
+		{"path without leading slash with middleware", func() *Route { return api.RegisterRouteWith(POST, "users", handler) }, "POST users`: path must start with `/`"},
+		{"any path without leading slash", func() *Route { return api.Any("users", handler) }, "GET users`: path must start with `/`"},
+		{"empty path without prefix", func() *Route { return router.Group("").RegisterRoute(GET, "", handler) }, "`GET`: empty path"},
+		{"nil handler", func() *Route { return api.Handle(GET, "/users", nil) }, "nil handler"},
+		{"nil handler func", func() *Route { return api.HandleFunc(GET, "/users", nil) }, "nil handler"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.route().Err()
+			if !errors.Is(err, ErrInvalidRoute) {
+				t.Fatalf("expected ErrInvalidRoute, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error containing %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+
+	if routes := *router.RegisteredRoutes(); len(routes) != 0 {
+		t.Errorf("expected no registered routes, got %v", routes)
+	}
+
+	t.Run("empty path registers the group root", func(t *testing.T) {
+		if err := api.RegisterRoute(GET, "", handler).Err(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/api", nil))
+
+		if rw.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rw.Code)
+		}
+	})
+}
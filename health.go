@@ -0,0 +1,202 @@
+package yagaw
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// ----------- HEALTH -----------
+
+// DefaultReadinessCheckTimeout is how long a readiness check may run before it is failed
+const DefaultReadinessCheckTimeout = 2 * time.Second
+
+// HealthReport is the JSON body of the health endpoints, failing readiness checks are
+// listed by name with their error.
+type HealthReport struct {
+	Status  string            `json:"status"`
+	Failing map[string]string `json:"failing,omitempty"`
+}
+
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type healthSettings struct {
+	livePath   string
+	readyPath  string
+	checks     []readinessCheck
+	timeout    time.Duration
+	drainDelay time.Duration
+	closing    atomic.Bool
+}
+
+// EnableHealth serves liveness at livePath, always 200 while the server runs, and readiness
+// at readyPath, 503 when a readiness check fails or the server drains or shuts down. Both are
+// answered before the router, an empty path leaves that endpoint out.
+func (s *Server) EnableHealth(livePath string, readyPath string) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	health := s.healthSettings()
+	health.livePath, health.readyPath = livePath, readyPath
+	s.composeHandler()
+	return s
+}
+
+// AddReadinessCheck registers a check run by every readiness request, e.g. pinging the
+// database. Checks run concurrently, each with its own timeout.
+func (s *Server) AddReadinessCheck(name string, check func(ctx context.Context) error) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	health := s.healthSettings()
+	health.checks = append(health.checks, readinessCheck{name: name, check: check})
+	return s
+}
+
+// ReadinessCheckTimeout sets how long each readiness check may run,
+// DefaultReadinessCheckTimeout unless set.
+func (s *Server) ReadinessCheckTimeout(timeout time.Duration) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthSettings().timeout = timeout
+	return s
+}
+
+// ReadinessDrainDelay sets how long Shutdown keeps accepting connections once readiness
+// answers 503, for load balancers to notice before the listeners close. None unless set.
+func (s *Server) ReadinessDrainDelay(delay time.Duration) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthSettings().drainDelay = delay
+	return s
+}
+
+func (s *Server) healthSettings() *healthSettings {
+	if s.health == nil {
+		s.health = &healthSettings{timeout: DefaultReadinessCheckTimeout}
+	}
+	return s.health
+}
+
+// failReadiness makes readiness fail and waits for the drain delay or the context to be done
+func (s *Server) failReadiness(ctx context.Context) {
+	s.mu.Lock()
+	health := s.health
+	var delay time.Duration
+	if health != nil && health.readyPath != "" {
+		delay = health.drainDelay
+	}
+	s.mu.Unlock()
+	if health == nil {
+		return
+	}
+
+	health.closing.Store(true)
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// healthHandler answers the health endpoints and passes the other requests to next
+func (s *Server) healthHandler(next http.Handler, health *healthSettings) http.Handler {
+	livePath, readyPath := health.livePath, health.readyPath
+	if livePath == "" && readyPath == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			next.ServeHTTP(rw, req)
+			return
+		}
+		switch req.URL.Path {
+		case livePath:
+			writeHealthReport(rw, http.StatusOK, HealthReport{Status: "ok"})
+		case readyPath:
+			status, report := s.readiness(req.Context(), health)
+			writeHealthReport(rw, status, report)
+		default:
+			next.ServeHTTP(rw, req)
+		}
+	})
+}
+
+func (s *Server) readiness(ctx context.Context, health *healthSettings) (int, HealthReport) {
+	if health.closing.Load() {
+		return http.StatusServiceUnavailable, HealthReport{Status: "shutting down"}
+	}
+	if s.draining.Load() {
+		return http.StatusServiceUnavailable, HealthReport{Status: "draining"}
+	}
+	s.mu.Lock()
+	checks, timeout := slices.Clone(health.checks), health.timeout
+	s.mu.Unlock()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, len(checks))
+	for _, check := range checks {
+		go func() { results <- result{check.name, runReadinessCheck(ctx, check.check, timeout)} }()
+	}
+
+	failing := map[string]string{}
+	for range checks {
+		if result := <-results; result.err != nil {
+			failing[result.name] = result.err.Error()
+		}
+	}
+	if len(failing) > 0 {
+		return http.StatusServiceUnavailable, HealthReport{Status: "unavailable", Failing: failing}
+	}
+	return http.StatusOK, HealthReport{Status: "ok"}
+}
+
+// runReadinessCheck fails the check once the timeout has passed, even when it ignores its
+// context, so that a slow check can't hold the others' report. A panicking check fails too.
+func runReadinessCheck(ctx context.Context, check func(ctx context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				Log.Error(fmt.Sprintf("panic running a readiness check: %v\n%s", recovered, debug.Stack()))
+				done <- fmt.Errorf("panic: %v", recovered)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		return ctx.Err()
+	}
+}
+
+func writeHealthReport(rw http.ResponseWriter, status int, report HealthReport) {
+	body, _ := json.Marshal(report)
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(status)
+	rw.Write(append(body, '\n'))
+}
@@ -0,0 +1,128 @@
+package yagaw
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServerHealth(t *testing.T) {
+	get := func(t *testing.T, server *Server, path string) (int, HealthReport) {
+		rw := httptest.NewRecorder()
+		server.serveHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+		report := HealthReport{}
+		if rw.Header().Get("Content-Type") == "application/json" {
+			if err := json.Unmarshal(rw.Body.Bytes(), &report); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		return rw.Code, report
+	}
+
+	t.Run("off by default", func(t *testing.T) {
+		if status, _ := get(t, NewServer("127.0.0.1", 0), "/healthz"); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+	})
+
+	t.Run("checks passing", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0).
+			EnableHealth("/healthz", "/readyz").
+			AddReadinessCheck("database", func(ctx context.Context) error { return nil })
+		if status, report := get(t, server, "/healthz"); status != http.StatusOK || report.Status != "ok" {
+			t.Errorf("expected liveness 200, got %d %+v", status, report)
+		}
+		if status, report := get(t, server, "/readyz"); status != http.StatusOK || report.Failing != nil {
+			t.Errorf("expected readiness 200, got %d %+v", status, report)
+		}
+	})
+
+	t.Run("failing and slow checks", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+		server := NewServer("127.0.0.1", 0).
+			EnableHealth("/healthz", "/readyz").
+			ReadinessCheckTimeout(50*time.Millisecond).
+			AddReadinessCheck("database", func(ctx context.Context) error { return nil }).
+			AddReadinessCheck("cache", func(ctx context.Context) error { return errors.New("connection refused") }).
+			AddReadinessCheck("queue", func(ctx context.Context) error {
+				// Ignores its context, the timeout must still apply
+				<-release
+				return nil
+			})
+
+		started := time.Now()
+		status, report := get(t, server, "/readyz")
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Errorf("expected the slow check to be cut at its timeout, took %v", elapsed)
+		}
+		if status != http.StatusServiceUnavailable {
+			t.Errorf("expected 503, got %d", status)
+		}
+		expected := map[string]string{"cache": "connection refused", "queue": "timed out after 50ms"}
+		if len(report.Failing) != len(expected) || report.Failing["cache"] != expected["cache"] || report.Failing["queue"] != expected["queue"] {
+			t.Errorf("expected %v failing, got %v", expected, report.Failing)
+		}
+		if status, _ := get(t, server, "/healthz"); status != http.StatusOK {
+			t.Errorf("expected liveness to stay 200, got %d", status)
+		}
+	})
+
+	t.Run("panicking check", func(t *testing.T) {
+		captureLog(t, "error")
+		server := NewServer("127.0.0.1", 0).
+			EnableHealth("/healthz", "/readyz").
+			AddReadinessCheck("database", func(ctx context.Context) error { return nil }).
+			AddReadinessCheck("cache", func(ctx context.Context) error { panic("nil client") })
+		status, report := get(t, server, "/readyz")
+		if status != http.StatusServiceUnavailable || len(report.Failing) != 1 || report.Failing["cache"] != "panic: nil client" {
+			t.Errorf("expected the panicking check to fail, got %d %+v", status, report)
+		}
+	})
+
+	t.Run("routes still served", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0).EnableHealth("/healthz", "")
+		server.GetRouter().RegisterRoute(GET, "/readyz", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusTeapot)
+		})
+		if status, _ := get(t, server, "/readyz"); status != http.StatusTeapot {
+			t.Errorf("expected the route, got %d", status)
+		}
+	})
+
+	t.Run("readiness fails during shutdown", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0).
+			EnableHealth("/healthz", "/readyz").
+			ReadinessDrainDelay(200 * time.Millisecond)
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		address := awaitReady(t, server, ran)
+		readyz := func() int {
+			res, err := http.Get("http://" + address + "/readyz")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			return res.StatusCode
+		}
+		if status := readyz(); status != http.StatusOK {
+			t.Fatalf("expected 200 before the shutdown, got %d", status)
+		}
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- server.Shutdown(context.Background()) }()
+		waitFor(t, "readiness to fail", func() bool { return readyz() == http.StatusServiceUnavailable })
+		if err := <-shutdown; err != nil {
+			t.Errorf("unexpected shutdown error: %v", err)
+		}
+		if err := <-ran; err != nil {
+			t.Errorf("expected Run to return nil, got %v", err)
+		}
+	})
+}
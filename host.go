@@ -0,0 +1,181 @@
+package yagaw
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const defaultHostParamPattern = `[^.]+`
+
+type hostParamsKey struct{}
+
+// hostPattern is a host with parameters, e.g. `{tenant}.example.com`
+type hostPattern struct {
+	host     string
+	pattern  *regexp.Regexp
+	names    []string
+	captures []int
+	router   *Router
+}
+
+// Host returns the router serving the requests for the given host, created on first use.
+// Host routers are independent routers with their own routes, options and middleware,
+// requests whose host matches none of them are served by the parent router.
+//
+// Labels of the host may be parameters like `{tenant}.example.com`, captured values are
+// read like path parameters. A parameter matches a whole label unless constrained, hosts
+// without parameters are tried first, then the others in registration order.
+func (r *Router) Host(host string) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if strings.ContainsRune(host, '{') {
+		return r.hostPatternRouter(host)
+	}
+
+	host = normalizeHost(host)
+	if r.hosts == nil {
+		r.hosts = make(map[string]*Router)
+	}
+	if r.hosts[host] == nil {
+		r.hosts[host] = NewRouter()
+	}
+	return r.hosts[host]
+}
+
+func (r *Router) hostPatternRouter(host string) *Router {
+	host = strings.TrimSuffix(hostPatternName(host), ".")
+	for _, registered := range r.hostPatterns {
+		if strings.EqualFold(registered.host, host) {
+			return registered.router
+		}
+	}
+
+	compiled, err := compileHostPattern(host)
+	if err != nil {
+		// The router is returned anyway so that chained registrations don't panic
+		Log.Error(fmt.Sprintf("invalid host `%s`: %v", host, err))
+		return NewRouter()
+	}
+	compiled.router = NewRouter()
+	r.hostPatterns = append(r.hostPatterns, compiled)
+	return compiled.router
+}
+
+func (r *Router) hostRouter(host string) (*Router, map[string]string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.hosts) == 0 && len(r.hostPatterns) == 0 {
+		return nil, nil
+	}
+	host = normalizeHost(host)
+	if hostRouter := r.hosts[host]; hostRouter != nil {
+		return hostRouter, nil
+	}
+	for _, registered := range r.hostPatterns {
+		submatches := registered.pattern.FindStringSubmatch(host)
+		if submatches == nil {
+			continue
+		}
+		params := make(map[string]string, len(registered.names))
+		for i, name := range registered.names {
+			params[name] = submatches[registered.captures[i]]
+		}
+		return registered.router, params
+	}
+	return nil, nil
+}
+
+// compileHostPattern turns the parameters of the host in capture groups, `{name}` matches
+// a label and `{name:regex}` the constraint, named constraints like `int` included.
+func compileHostPattern(host string) (*hostPattern, error) {
+	if err := validateRoutePath(host); err != nil {
+		return nil, err
+	}
+
+	compiled := &hostPattern{host: host}
+	source := strings.Builder{}
+	source.WriteString("(?i)^")
+	groups := 0
+	cursor := 0
+	for {
+		open := strings.IndexByte(host[cursor:], '{')
+		if open < 0 {
+			break
+		}
+		open += cursor
+		end := closingBrace(host, open)
+
+		name, constraint, hasConstraint := strings.Cut(host[open+1:end], ":")
+		if !hasConstraint {
+			constraint = defaultHostParamPattern
+		} else if typePattern, known := ParamTypes[constraint]; known {
+			constraint = typePattern
+		}
+		constraintPattern, err := regexp.Compile(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid constraint for parameter `%s`: %w", name, err)
+		}
+
+		source.WriteString(regexp.QuoteMeta(host[cursor:open]))
+		source.WriteString("(" + constraint + ")")
+		compiled.names = append(compiled.names, name)
+		compiled.captures = append(compiled.captures, groups+1)
+		groups += 1 + constraintPattern.NumSubexp()
+		cursor = end + 1
+	}
+	source.WriteString(regexp.QuoteMeta(host[cursor:]) + "$")
+
+	pattern, err := regexp.Compile(source.String())
+	if err != nil {
+		return nil, err
+	}
+	compiled.pattern = pattern
+	return compiled, nil
+}
+
+// hostPatternName drops the port of a host with parameters, the colon of a constraint
+// is not taken for the port separator.
+func hostPatternName(host string) string {
+	lastBrace := strings.LastIndexByte(host, '}')
+	if colon := strings.LastIndexByte(host, ':'); colon > lastBrace {
+		return host[:colon]
+	}
+	return host
+}
+
+// normalizeHost drops the port and the trailing dot, hosts are compared case insensitively
+func normalizeHost(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// withHostParams stores the parameters captured from the host, along with the ones of the
+// parent host routers.
+func withHostParams(req *http.Request, params map[string]string) *http.Request {
+	if inherited := hostParams(req); len(inherited) > 0 {
+		params = mergeParams(inherited, params)
+	}
+	return req.WithContext(context.WithValue(req.Context(), hostParamsKey{}, params))
+}
+
+func hostParams(req *http.Request) map[string]string {
+	params, _ := req.Context().Value(hostParamsKey{}).(map[string]string)
+	return params
+}
+
+// mergeParams returns the params overridden by the others, equal names in the path win
+// over the host ones.
+func mergeParams(params map[string]string, others map[string]string) map[string]string {
+	merged := maps.Clone(params)
+	maps.Copy(merged, others)
+	return merged
+}
@@ -0,0 +1,129 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostRouting(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + PathParam(req, "id"))
+		}
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/", handler("default"))
+	router.RegisterRoute(GET, "/users/{id}", handler("default user "))
+	router.Host("api.example.com").RegisterRoute(GET, "/users/{id}", handler("api user "))
+	router.Host("Admin.Example.com").RegisterRoute(GET, "/", handler("admin"))
+
+	tests := []struct {
+		host   string
+		path   string
+		status int
+		body   string
+	}{
+		{"api.example.com", "/users/7", http.StatusOK, "api user 7"},
+		{"api.example.com:8080", "/users/7", http.StatusOK, "api user 7"},
+		{"API.Example.COM", "/users/7", http.StatusOK, "api user 7"},
+		{"api.example.com.", "/users/7", http.StatusOK, "api user 7"},
+		{"api.example.com.:443", "/users/7", http.StatusOK, "api user 7"},
+		{"admin.example.com", "/", http.StatusOK, "admin"},
+		{"api.example.com", "/", http.StatusNotFound, "404 - Page not found"},
+		{"www.example.com", "/users/7", http.StatusOK, "default user 7"},
+		{"www.example.com", "/", http.StatusOK, "default"},
+		{"127.0.0.1:8080", "/", http.StatusOK, "default"},
+		{"[::1]:8080", "/users/1", http.StatusOK, "default user 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(string(GET), tt.path, nil)
+			req.Host = tt.host
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if rw.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	if router.Host("api.example.com:8080") != router.Host("API.example.com.") {
+		t.Error("expected the same router for equivalent hosts")
+	}
+}
+
+func TestHostParameters(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + " " + PathParam(req, "tenant") + "," + PathParam(req, "region") + "," + PathParam(req, "id"))
+		}
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/users/{id}", handler("default"))
+	router.Host("www.example.com").RegisterRoute(GET, "/users/{id}", handler("www"))
+	router.Host("{tenant}.example.com").RegisterRoute(GET, "/users/{id}", handler("tenant"))
+	router.Host("{tenant}.{region:eu|us}.example.com:8080").RegisterRoute(GET, "/users/{id}", handler("regional"))
+	router.Host("shard{id:int}.example.org").RegisterRoute(GET, "/users/{id}", handler("shard"))
+
+	tests := []struct {
+		host string
+		body string
+	}{
+		{"acme.example.com:8080", "tenant acme,,7"},
+		{"ACME.Example.com", "tenant acme,,7"},
+		{"acme.example.com.", "tenant acme,,7"},
+		{"www.example.com", "www ,,7"},
+		{"acme.eu.example.com", "regional acme,eu,7"},
+		{"acme.asia.example.com", "default ,,7"},
+		{"example.com", "default ,,7"},
+		{"shard3.example.org", "shard ,,7"},
+		{"shardx.example.org", "default ,,7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			req := httptest.NewRequest(string(GET), "/users/7", nil)
+			req.Host = tt.host
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != http.StatusOK || rw.Body.String() != tt.body {
+				t.Errorf("expected %q, got %d %q", tt.body, rw.Code, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("params api", func(t *testing.T) {
+		var tenant struct {
+			Name string `path:"tenant"`
+			ID   int    `path:"id"`
+		}
+		var params Params
+		router.Host("{tenant}.example.net").RegisterRoute(GET, "/users/{id:int}", func(req *http.Request, p Params) *HttpResponse {
+			params = p
+			if err := BindPath(req, &tenant); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			return NewHttpResponse(http.StatusOK)
+		})
+
+		req := httptest.NewRequest(string(GET), "/users/7", nil)
+		req.Host = "acme.example.net:8080"
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		if tenant.Name != "acme" || tenant.ID != 7 || params["tenant"] != "acme" || params["id"] != "7" {
+			t.Errorf("expected the tenant along with the path params, got %+v %v", tenant, params)
+		}
+	})
+
+	if router.Host("{tenant}.example.com:443") != router.Host("{tenant}.Example.com.") {
+		t.Error("expected the same router for equivalent host patterns")
+	}
+}
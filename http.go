@@ -16,6 +16,9 @@ const (
 	CONNECT HttpMethod = `CONNECT`
 )
 
+var HttpMethods = []HttpMethod{GET, HEAD, OPTIONS, TRACE, PUT, DELETE, POST, PATCH, CONNECT}
+
+// Params holds the path parameters matched for the request, nil when the route has none
 type Params map[string]any
 type HttpRequestHandler func(req *http.Request, params Params) *HttpResponse
 
@@ -23,6 +26,7 @@ type HttpResponse struct {
 	headers map[string]string
 	status  int
 	body    string
+	writer  func(rw http.ResponseWriter)
 }
 
 func (r *HttpResponse) SetHeader(key string, value string) *HttpResponse {
@@ -41,3 +45,13 @@ func NewHttpResponse(status int) *HttpResponse {
 		headers: make(map[string]string),
 	}
 }
+
+// delegatedResponse leaves the writing of the response to a standard http.Handler,
+// headers set on the HttpResponse are applied before the handler runs.
+func delegatedResponse(handler http.Handler, req *http.Request) *HttpResponse {
+	response := NewHttpResponse(http.StatusOK)
+	response.writer = func(rw http.ResponseWriter) {
+		handler.ServeHTTP(rw, req)
+	}
+	return response
+}
@@ -0,0 +1,53 @@
+package yagaw
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ----------- HTTP TO HTTPS REDIRECT -----------
+
+// RedirectHTTP makes RunTLS start a companion listener on the port, answering every request
+// with a redirect to its HTTPS equivalent, host, path and query preserved: a 301 for GET and
+// HEAD, a 308 otherwise so that clients keep the method and body. The HTTPS port is
+// added to the location unless it is 443. With WithAutocert it answers the HTTP-01
+// challenges too, in place of the listener on the autocert challenge address.
+func (s *Server) RedirectHTTP(port int) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redirectPort = port
+	return s
+}
+
+// redirectAddr returns the address of the redirect listener, the one of the server on the
+// redirect port, every interface for servers on a Unix socket.
+func (s *Server) redirectAddr() string {
+	host := s.address
+	if s.unixSocket != "" {
+		host = ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(s.redirectPort))
+}
+
+// redirectToHTTPS answers with a permanent redirect to the HTTPS server, like the router
+// redirects paths
+func (s *Server) redirectToHTTPS(rw http.ResponseWriter, req *http.Request) {
+	host := req.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	if addr, isTCP := s.Addr().(*net.TCPAddr); isTCP && addr.Port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(addr.Port))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	status := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		status = http.StatusMovedPermanently
+	}
+	http.Redirect(rw, req, "https://"+host+req.URL.RequestURI(), status)
+}
@@ -0,0 +1,88 @@
+package yagaw
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestServerRedirectHTTP(t *testing.T) {
+	cert, _, _ := selfSignedCert(t)
+	noRedirects := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }}
+	send := func(t *testing.T, method string, port int, host string, path string) *http.Response {
+		req, _ := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+		req.Host = host
+		res, err := noRedirects.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("redirect to HTTPS", func(t *testing.T) {
+		redirectPort := freePort(t)
+		server := NewServer("127.0.0.1", 0).
+			WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}).
+			RedirectHTTP(redirectPort)
+		ran := make(chan error, 1)
+		go func() { ran <- server.RunTLS("", "") }()
+		address := awaitReady(t, server, ran)
+		_, tlsPort, _ := net.SplitHostPort(address)
+
+		tests := []struct {
+			method   string
+			host     string
+			path     string
+			status   int
+			expected string
+		}{
+			{http.MethodGet, "example.test", "/users/42?tab=posts&page=2", http.StatusMovedPermanently, "https://example.test:" + tlsPort + "/users/42?tab=posts&page=2"},
+			{http.MethodGet, fmt.Sprintf("example.test:%d", redirectPort), "/", http.StatusMovedPermanently, "https://example.test:" + tlsPort + "/"},
+			{http.MethodGet, "[::1]", "/search?q=a%20b", http.StatusMovedPermanently, "https://[::1]:" + tlsPort + "/search?q=a%20b"},
+			{http.MethodHead, "example.test", "/", http.StatusMovedPermanently, "https://example.test:" + tlsPort + "/"},
+			{http.MethodPost, "example.test", "/users", http.StatusPermanentRedirect, "https://example.test:" + tlsPort + "/users"},
+			{http.MethodDelete, "example.test", "/users/42", http.StatusPermanentRedirect, "https://example.test:" + tlsPort + "/users/42"},
+		}
+		for _, tt := range tests {
+			res := send(t, tt.method, redirectPort, tt.host, tt.path)
+			if res.StatusCode != tt.status || res.Header.Get("Location") != tt.expected {
+				t.Errorf("%s: expected a %d to %q, got %d %q", tt.method, tt.status, tt.expected, res.StatusCode, res.Header.Get("Location"))
+			}
+		}
+
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatalf("unexpected shutdown error: %v", err)
+		}
+		if err := <-ran; err != nil {
+			t.Errorf("expected RunTLS to return nil, got %v", err)
+		}
+		if _, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", redirectPort)); err == nil {
+			t.Error("expected the redirect listener to be closed")
+		}
+	})
+
+	t.Run("challenges passed through", func(t *testing.T) {
+		redirectPort := freePort(t)
+		server := NewServer("127.0.0.1", 0).
+			WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}).
+			WithAutocert("example.test").
+			AutocertCacheDir(t.TempDir()).
+			RedirectHTTP(redirectPort)
+		ran := make(chan error, 1)
+		go func() { ran <- server.RunTLS("", "") }()
+		awaitReady(t, server, ran)
+		defer server.Shutdown(context.Background())
+
+		// The token is unknown to the manager, which answers instead of redirecting
+		if res := send(t, http.MethodGet, redirectPort, "example.test", "/.well-known/acme-challenge/token"); res.StatusCode != http.StatusNotFound {
+			t.Errorf("expected the manager to answer the challenge, got %d %q", res.StatusCode, res.Header.Get("Location"))
+		}
+		if res := send(t, http.MethodGet, redirectPort, "example.test", "/users?page=2"); res.StatusCode != http.StatusMovedPermanently {
+			t.Errorf("expected other paths to be redirected, got %d", res.StatusCode)
+		}
+	})
+}
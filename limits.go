@@ -0,0 +1,44 @@
+package yagaw
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Default limits of the request path, oversized paths are answered with a 414
+const (
+	DefaultMaxPathLength   = 8192
+	DefaultMaxPathSegments = 256
+)
+
+// MaxPathLength sets the longest request path in bytes, escaped form included, that the
+// router attempts to match. Longer paths are answered with a 414 before any matching work,
+// a length of 0 disables the limit. The default is DefaultMaxPathLength.
+func (r *Router) MaxPathLength(length int) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxPathLength = length
+	return r
+}
+
+// MaxPathSegments sets the highest number of `/` separated segments of the request path the
+// router attempts to match, like MaxPathLength. The default is DefaultMaxPathSegments.
+func (r *Router) MaxPathSegments(segments int) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxPathSegments = segments
+	return r
+}
+
+// exceedsPathLimits reports whether the request path is beyond the router limits, the
+// segments are only counted once the length is known to be acceptable.
+func (r *Router) exceedsPathLimits(req *http.Request) bool {
+	r.mu.RLock()
+	maxLength, maxSegments := r.maxPathLength, r.maxPathSegments
+	r.mu.RUnlock()
+
+	if maxLength > 0 && max(len(req.URL.Path), len(req.URL.RawPath)) > maxLength {
+		return true
+	}
+	return maxSegments > 0 && strings.Count(req.URL.Path, "/") > maxSegments
+}
@@ -0,0 +1,93 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPathLimits(t *testing.T) {
+	newRouter := func(served *int) *Router {
+		router := NewRouter()
+		router.RegisterRoute(GET, "/{*rest}", func(req *http.Request, params Params) *HttpResponse {
+			*served++
+			return NewHttpResponse(http.StatusOK)
+		})
+		return router
+	}
+
+	tests := []struct {
+		name     string
+		limit    func(router *Router)
+		path     string
+		expected int
+	}{
+		{"default length", func(router *Router) {}, "/" + strings.Repeat("a", DefaultMaxPathLength), http.StatusRequestURITooLong},
+		{"default segments", func(router *Router) {}, strings.Repeat("/a", DefaultMaxPathSegments+1), http.StatusRequestURITooLong},
+		{"within defaults", func(router *Router) {}, strings.Repeat("/a", DefaultMaxPathSegments), http.StatusOK},
+		{"custom length", func(router *Router) { router.MaxPathLength(8) }, "/aaaaaaaa", http.StatusRequestURITooLong},
+		{"escaped length", func(router *Router) { router.MaxPathLength(8) }, "/%61%61%61", http.StatusRequestURITooLong},
+		{"custom segments", func(router *Router) { router.MaxPathSegments(2) }, "/a/b/c", http.StatusRequestURITooLong},
+		{"disabled length", func(router *Router) { router.MaxPathLength(0) }, "/" + strings.Repeat("a", 2*DefaultMaxPathLength), http.StatusOK},
+		{"disabled segments", func(router *Router) { router.MaxPathSegments(0) }, strings.Repeat("/a", 2*DefaultMaxPathSegments), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served := 0
+			router := newRouter(&served)
+			tt.limit(router)
+
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d", tt.expected, rw.Code)
+			}
+			if tt.expected == http.StatusRequestURITooLong && served != 0 {
+				t.Errorf("expected the route not to be served, got %d calls", served)
+			}
+		})
+	}
+
+	t.Run("refused before matching", func(t *testing.T) {
+		served := 0
+		router := newRouter(&served).MaxPathSegments(2).EnableRouteCache(8)
+		router.RegisterRoute(GET, "/a/{b}/{c}", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK)
+		})
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/a/b/c", nil))
+
+		if rw.Code != http.StatusRequestURITooLong {
+			t.Fatalf("expected 414, got %d", rw.Code)
+		}
+		if router.cache.len() != 0 {
+			t.Errorf("expected no lookup to be cached, got %d entries", router.cache.len())
+		}
+
+		router.MaxPathSegments(3)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(string(GET), "/a/b/c", nil))
+		if router.cache.len() != 1 {
+			t.Errorf("expected the lookup to be cached once allowed, got %d entries", router.cache.len())
+		}
+	})
+
+	t.Run("router middleware sees refused requests", func(t *testing.T) {
+		served := 0
+		router := newRouter(&served).MaxPathLength(4).Use(func(next HttpRequestHandler) HttpRequestHandler {
+			return func(req *http.Request, params Params) *HttpResponse {
+				return next(req, params).SetHeader("X-Seen", "true")
+			}
+		})
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/aaaaa", nil))
+
+		if rw.Code != http.StatusRequestURITooLong || rw.Header().Get("X-Seen") != "true" {
+			t.Errorf("expected a 414 through the middleware, got %d %v", rw.Code, rw.Header())
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package yagaw
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ----------- ADDITIONAL LISTENERS -----------
+
+type listenerTagKey struct{}
+
+type listenerSpec struct {
+	tag  string
+	addr string
+}
+
+type taggedListener struct {
+	net.Listener
+	tag string
+}
+
+// accepted tells whether the connection was accepted by the listener from its local address,
+// the accepted connections being left as they are for handlers asserting *net.TCPConn.
+func (l *taggedListener) accepted(conn net.Conn) bool {
+	local, isTCP := conn.LocalAddr().(*net.TCPAddr)
+	listening, listensTCP := l.Addr().(*net.TCPAddr)
+	if !isTCP || !listensTCP || local.Port != listening.Port {
+		return false
+	}
+	return listening.IP.IsUnspecified() || listening.IP.Equal(local.IP)
+}
+
+// AddListener makes the server listen on another TCP address as well, e.g. an internal
+// interface for admin traffic next to the public one. Every listener serves the same
+// handler, is shut down along with the others and has the run return once all of them are
+// closed. Requests tell the listeners apart with ListenerTag, the one given to NewServer
+// having the empty tag.
+func (s *Server) AddListener(tag string, addr string, port int) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.extraListeners = append(s.extraListeners, listenerSpec{tag: tag, addr: fmt.Sprintf("%s:%d", addr, port)})
+	return s
+}
+
+// ListenerTag returns the tag of the listener the request came through, empty for the
+// listener given to NewServer.
+func ListenerTag(req *http.Request) string {
+	tag, _ := req.Context().Value(listenerTagKey{}).(string)
+	return tag
+}
+
+// ListenerAddr returns the address the listener with the tag listens on, nil until it is
+// established or for unknown tags. The empty tag returns Addr.
+func (s *Server) ListenerAddr(tag string) net.Addr {
+	if tag == "" {
+		return s.Addr()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, listener := range s.tagged {
+		if listener.tag == tag {
+			return listener.Addr()
+		}
+	}
+	return nil
+}
+
+// listenExtra opens the additional listeners of a run, all of them or none
+func (s *Server) listenExtra() ([]*taggedListener, error) {
+	s.mu.Lock()
+	specs := s.extraListeners
+	s.mu.Unlock()
+
+	listeners := make([]*taggedListener, 0, len(specs))
+	for _, spec := range specs {
+		listener, err := net.Listen("tcp", spec.addr)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, &taggedListener{Listener: listener, tag: spec.tag})
+	}
+	return listeners, nil
+}
+
+// tagConnContext stores the tag of the listener that accepted the connection in its context
+// before calling the function set by WithConnContext.
+func (s *Server) tagConnContext(connContext func(ctx context.Context, conn net.Conn) context.Context) func(ctx context.Context, conn net.Conn) context.Context {
+	return func(ctx context.Context, conn net.Conn) context.Context {
+		s.mu.Lock()
+		tagged := s.tagged
+		s.mu.Unlock()
+		for _, listener := range tagged {
+			if listener.accepted(conn) {
+				ctx = context.WithValue(ctx, listenerTagKey{}, listener.tag)
+				break
+			}
+		}
+		if connContext != nil {
+			ctx = connContext(ctx, conn)
+		}
+		return ctx
+	}
+}
@@ -0,0 +1,110 @@
+package yagaw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+func TestServerAddListener(t *testing.T) {
+	// Connections are handed over as accepted, whatever their listener
+	wrapped := atomic.Int64{}
+	keepTCP := WithConnContext(func(ctx context.Context, conn net.Conn) context.Context {
+		if _, isTCP := conn.(*net.TCPConn); !isTCP {
+			wrapped.Add(1)
+		}
+		return ctx
+	})
+	server := NewServer("127.0.0.1", 0, keepTCP).AddListener("admin", "127.0.0.1", 0)
+	server.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/admin" && ListenerTag(req) != "admin" {
+				http.Error(rw, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(rw, req)
+		})
+	})
+	server.GetRouter().RegisterRoute(GET, "/admin", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("admin")
+	})
+	server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("hello from `" + ListenerTag(req) + "`")
+	})
+
+	ran := make(chan error, 1)
+	go func() { ran <- server.Run() }()
+	public := awaitReady(t, server, ran)
+	admin := server.ListenerAddr("admin").String()
+	if server.ListenerAddr("") != server.Addr() || server.ListenerAddr("missing") != nil {
+		t.Errorf("expected the addresses of the known listeners only")
+	}
+
+	get := func(address string, path string) (int, string) {
+		res, err := http.Get("http://" + address + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(body)
+	}
+	tests := []struct {
+		address  string
+		path     string
+		status   int
+		expected string
+	}{
+		{public, "/hello", http.StatusOK, "hello from ``"},
+		{admin, "/hello", http.StatusOK, "hello from `admin`"},
+		{public, "/admin", http.StatusForbidden, "forbidden\n"},
+		{admin, "/admin", http.StatusOK, "admin"},
+	}
+	for _, tt := range tests {
+		if status, body := get(tt.address, tt.path); status != tt.status || body != tt.expected {
+			t.Errorf("expected %s%s to get %d %q, got %d %q", tt.address, tt.path, tt.status, tt.expected, status, body)
+		}
+	}
+
+	if wrapped.Load() != 0 {
+		t.Errorf("expected every connection to be a *net.TCPConn, got %d wrapped", wrapped.Load())
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+	if err := <-ran; err != nil {
+		t.Errorf("expected Run to return nil, got %v", err)
+	}
+	for _, stopped := range []string{public, admin} {
+		if _, err := net.Dial("tcp", stopped); err == nil {
+			t.Errorf("expected %s to be closed", stopped)
+		}
+	}
+}
+
+func TestServerAddListenerBindError(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer taken.Close()
+
+	port := freePort(t)
+	server := NewServer("127.0.0.1", port).AddListener("admin", "127.0.0.1", taken.Addr().(*net.TCPAddr).Port)
+	if err := server.Run(); !errors.Is(err, syscall.EADDRINUSE) {
+		t.Fatalf("expected address already in use, got %v", err)
+	}
+	// The listener opened first is closed again
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatalf("expected the primary listener to be closed, got %v", err)
+	}
+	listener.Close()
+}
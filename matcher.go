@@ -0,0 +1,157 @@
+package yagaw
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+type requestMatcher func(mc *matchContext) bool
+
+// matchContext carries the request being resolved, the query string is parsed on first use
+// so that routes without query matchers never pay for it.
+type matchContext struct {
+	req   *http.Request
+	query url.Values
+}
+
+func (mc *matchContext) queryValues() url.Values {
+	if mc.query == nil {
+		mc.query = mc.req.URL.Query()
+	}
+	return mc.query
+}
+
+// MatchHeader restricts the route to requests carrying the header with exactly the given
+// value. Routes sharing method and path are tried in registration order, the one without
+// matchers being the fallback.
+func (rt *Route) MatchHeader(name string, value string) *Route {
+	return rt.match(func(mc *matchContext) bool {
+		return mc.req.Header.Get(name) == value
+	})
+}
+
+// MatchHeaderRegexp restricts the route to requests carrying the header with a value
+// matching the pattern.
+func (rt *Route) MatchHeaderRegexp(name string, pattern string) *Route {
+	valuePattern, err := rt.compileMatcherPattern("header", name, pattern)
+	if err != nil {
+		return rt
+	}
+	return rt.match(func(mc *matchContext) bool {
+		return valuePattern.MatchString(mc.req.Header.Get(name))
+	})
+}
+
+// MatchQuery restricts the route to requests whose query parameter has exactly the given
+// value, any of the values when the parameter is repeated.
+func (rt *Route) MatchQuery(name string, value string) *Route {
+	return rt.match(func(mc *matchContext) bool {
+		for _, queryValue := range mc.queryValues()[name] {
+			if queryValue == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchQueryPresent restricts the route to requests carrying the query parameter, whatever
+// its value.
+func (rt *Route) MatchQueryPresent(name string) *Route {
+	return rt.match(func(mc *matchContext) bool {
+		return mc.queryValues().Has(name)
+	})
+}
+
+// MatchQueryRegexp restricts the route to requests with a value of the query parameter
+// matching the pattern.
+func (rt *Route) MatchQueryRegexp(name string, pattern string) *Route {
+	valuePattern, err := rt.compileMatcherPattern("query parameter", name, pattern)
+	if err != nil {
+		return rt
+	}
+	return rt.match(func(mc *matchContext) bool {
+		for _, queryValue := range mc.queryValues()[name] {
+			if valuePattern.MatchString(queryValue) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (rt *Route) compileMatcherPattern(kind string, name string, pattern string) (*regexp.Regexp, error) {
+	valuePattern, err := regexp.Compile(pattern)
+	if err != nil {
+		// The route must not turn into a fallback because of the invalid pattern
+		rt.match(func(*matchContext) bool { return false })
+		rt.err = fmt.Errorf("invalid pattern for %s `%s`: %w", kind, name, err)
+	}
+	return valuePattern, err
+}
+
+func (rt *Route) match(matcher requestMatcher) *Route {
+	if rt.err != nil {
+		return rt
+	}
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+
+	for _, handlerPackage := range rt.handlerPackages {
+		handlerPackage.matchers = append(handlerPackage.matchers, matcher)
+	}
+	return rt
+}
+
+// resolve picks among the routes sharing method and pattern the first one whose matchers
+// all pass, in registration order, the fallback otherwise. It returns nil when none applies.
+func (p *RequestHandlerPackage) resolve(req *http.Request) *RequestHandlerPackage {
+	if p == nil {
+		return nil
+	}
+	if len(p.candidates) <= 1 && len(p.target().matchers) == 0 {
+		return p
+	}
+
+	mc := matchContext{req: req}
+	for _, candidate := range p.candidates {
+		if len(candidate.target().matchers) > 0 && candidate.target().matches(&mc) {
+			return candidate
+		}
+	}
+	return p.fallback()
+}
+
+func (p *RequestHandlerPackage) matches(mc *matchContext) bool {
+	for _, matcher := range p.matchers {
+		if !matcher(mc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *RequestHandlerPackage) fallback() *RequestHandlerPackage {
+	for i := len(p.candidates) - 1; i >= 0; i-- {
+		if len(p.candidates[i].target().matchers) == 0 {
+			return p.candidates[i]
+		}
+	}
+	return nil
+}
+
+// eachCandidate visits the routes with matchers and the fallback, the fallbacks replaced
+// by a later registration, the variants of optional parameters and the aliases are skipped.
+func (p *RequestHandlerPackage) eachCandidate(method HttpMethod, fn func(method HttpMethod, handlerPackage *RequestHandlerPackage)) {
+	fallback := p.fallback()
+	for _, candidate := range p.candidates {
+		if candidate.expanded || candidate.aliasOf != nil {
+			continue
+		}
+		if len(candidate.matchers) > 0 || candidate == fallback {
+			fn(method, candidate)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeaderMatchers(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(POST, "/hooks", handler("fallback"))
+	router.RegisterRoute(POST, "/hooks", handler("push")).MatchHeader("X-GitHub-Event", "push")
+	router.RegisterRoute(POST, "/hooks", handler("issues")).MatchHeader("X-GitHub-Event", "issues")
+	router.RegisterRoute(POST, "/hooks", handler("pull request")).MatchHeaderRegexp("X-GitHub-Event", "^pull_request(_review)?$")
+	router.RegisterRoute(POST, "/hooks", handler("issues v2")).MatchHeader("X-GitHub-Event", "issues")
+
+	router.RegisterRoute(POST, "/repos/{id}/hooks", handler("push")).MatchHeader("X-GitHub-Event", "push")
+	router.RegisterRoute(POST, "/repos/{repoId}/hooks", handler("signed push")).
+		MatchHeader("X-GitHub-Event", "push").
+		MatchHeaderRegexp("X-Hub-Signature-256", "^sha256=")
+	router.RegisterRoute(GET, "/repos/{id}/hooks", handler("list"))
+
+	tests := []struct {
+		path    string
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{"/hooks", map[string]string{"X-GitHub-Event": "push"}, http.StatusOK, "push"},
+		{"/hooks", map[string]string{"X-GitHub-Event": "issues"}, http.StatusOK, "issues"},
+		{"/hooks", map[string]string{"X-GitHub-Event": "pull_request_review"}, http.StatusOK, "pull request"},
+		{"/hooks", map[string]string{"X-GitHub-Event": "release"}, http.StatusOK, "fallback"},
+		{"/hooks", nil, http.StatusOK, "fallback"},
+		{"/repos/1/hooks", map[string]string{"X-GitHub-Event": "push"}, http.StatusOK, "push"},
+		{"/repos/1/hooks", map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=abc"}, http.StatusOK, "push"},
+		{"/repos/1/hooks", map[string]string{"X-GitHub-Event": "issues"}, http.StatusNotFound, "404 - Page not found"},
+		{"/repos/1/hooks", nil, http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.headers["X-GitHub-Event"], func(t *testing.T) {
+			req := httptest.NewRequest(string(POST), tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if rw.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	if routes := router.Routes(); len(routes) != 8 {
+		t.Errorf("expected every header matched route to be listed, got %d", len(routes))
+	}
+}
+
+func TestHeaderMatchersFallbackRules(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+	serve := func(router *Router, event string) string {
+		req := httptest.NewRequest(string(POST), "/hooks", nil)
+		req.Header.Set("X-GitHub-Event", event)
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		return rw.Body.String()
+	}
+
+	t.Run("fallback registered last", func(t *testing.T) {
+		router := NewRouter().OnDuplicate(DuplicateError)
+		router.RegisterRoute(POST, "/hooks", handler("push")).MatchHeader("X-GitHub-Event", "push")
+		if err := router.RegisterRoute(POST, "/hooks", handler("fallback")).Err(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if body := serve(router, "push"); body != "push" {
+			t.Errorf("expected the push handler, got %q", body)
+		}
+		if body := serve(router, "issues"); body != "fallback" {
+			t.Errorf("expected the fallback handler, got %q", body)
+		}
+	})
+
+	t.Run("fallback overwritten", func(t *testing.T) {
+		router := NewRouter()
+		router.RegisterRoute(POST, "/hooks", handler("first"))
+		router.RegisterRoute(POST, "/hooks", handler("second"))
+		if body := serve(router, "push"); body != "second" {
+			t.Errorf("expected the last fallback to win, got %q", body)
+		}
+		if routes := router.Routes(); len(routes) != 1 {
+			t.Errorf("expected the overwritten fallback not to be listed, got %v", routes)
+		}
+	})
+
+	t.Run("invalid header pattern", func(t *testing.T) {
+		router := NewRouter()
+		if err := router.RegisterRoute(POST, "/hooks", handler("")).MatchHeaderRegexp("X-GitHub-Event", "(push"); err.Err() == nil {
+			t.Error("expected an error for an invalid pattern")
+		}
+	})
+}
+
+func TestQueryMatchers(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/search", handler("user")).MatchQuery("type", "user")
+	router.RegisterRoute(GET, "/search", handler("repo")).MatchQuery("type", "repo")
+	router.RegisterRoute(GET, "/search", handler("numeric page")).MatchQueryRegexp("page", "^[0-9]+$")
+	router.RegisterRoute(GET, "/search", handler("cursor")).MatchQueryPresent("cursor")
+	router.RegisterRoute(GET, "/search", handler("everything"))
+	router.RegisterRoute(GET, "/items/{id}", handler("item with header and query")).
+		MatchHeader("Accept", "application/json").
+		MatchQuery("expand", "true")
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/search?type=user", http.StatusOK, "user"},
+		{"/search?type=repo", http.StatusOK, "repo"},
+		{"/search?type=repo&type=user", http.StatusOK, "user"},
+		{"/search?type=org", http.StatusOK, "everything"},
+		{"/search?page=2", http.StatusOK, "numeric page"},
+		{"/search?page=two", http.StatusOK, "everything"},
+		{"/search?cursor=", http.StatusOK, "cursor"},
+		{"/search?cursor", http.StatusOK, "cursor"},
+		{"/search?type=repo&cursor=abc", http.StatusOK, "repo"},
+		{"/search", http.StatusOK, "everything"},
+		{"/items/1?expand=true", http.StatusOK, "item with header and query"},
+		{"/items/1?expand=false", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(string(GET), tt.target, nil)
+			req.Header.Set("Accept", "application/json")
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if rw.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	if err := router.RegisterRoute(GET, "/invalid", handler("")).MatchQueryRegexp("page", "[0-9"); err.Err() == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestQueryIsParsedOnlyForQueryMatchers(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+	router.RegisterRoute(GET, "/hooks", handler).MatchHeader("X-Event", "push")
+	router.RegisterRoute(GET, "/hooks", handler)
+
+	mc := matchContext{req: httptest.NewRequest(string(GET), "/hooks?type=user", nil)}
+	slot := router.staticRoutes[GET]["/hooks"]
+	for _, candidate := range slot.candidates {
+		candidate.matches(&mc)
+	}
+	if mc.query != nil {
+		t.Error("expected the query not to be parsed by header matchers")
+	}
+}
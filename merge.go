@@ -0,0 +1,144 @@
+package yagaw
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Merge copies every route of the other router into this one, see MergeAt
+func (r *Router) Merge(other *Router) error {
+	return r.MergeAt("", other)
+}
+
+// MergeAt copies every route of the other router under the prefix, with its parameters,
+// matchers, name and options. The middleware of the other router and of its groups keeps
+// wrapping the copied routes, as it was when merging. Nothing is merged when a route or a
+// name is already registered, the conflicts are returned instead. The other router is
+// left untouched, its host routers and mounts are not merged.
+func (r *Router) MergeAt(prefix string, other *Router) error {
+	other.mu.RLock()
+	sourceMiddleware := slices.Clone(other.middleware)
+	other.mu.RUnlock()
+
+	merged := []orderedRoute{}
+	for _, route := range other.orderedRoutes() {
+		handlerPackage := route.handlerPackage.merged(sourceMiddleware)
+		handlerPackage.Path = joinPaths(prefix, handlerPackage.Path)
+		for _, alias := range handlerPackage.aliases {
+			alias.Path = joinPaths(prefix, alias.Path)
+		}
+		merged = append(merged, orderedRoute{route.method, handlerPackage})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conflicts := []error{}
+	for _, route := range merged {
+		if err := r.mergeConflict(route.method, route.handlerPackage); err != nil {
+			conflicts = append(conflicts, err)
+		}
+		for _, alias := range route.handlerPackage.aliases {
+			if err := r.mergeConflict(route.method, alias); err != nil {
+				conflicts = append(conflicts, err)
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return errors.Join(conflicts...)
+	}
+
+	for _, route := range merged {
+		aliases := route.handlerPackage.aliases
+		route.handlerPackage.aliases = nil
+		registered, err := r.addRoute(route.method, route.handlerPackage, DuplicateOverwrite)
+		if err != nil {
+			return err
+		}
+		if name := route.handlerPackage.name; name != "" && r.namedRoutes[name] == nil {
+			r.namedRoutes[name] = registered[0]
+		}
+		for _, alias := range aliases {
+			if err := r.addAlias(registered[0], alias.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merged returns a copy of the route to register in another router, the middleware of the
+// groups is flattened so that the copy doesn't depend on the source router. The aliases are
+// copied as paths, registered again along with the copy.
+func (p *RequestHandlerPackage) merged(sourceMiddleware []Middleware) *RequestHandlerPackage {
+	copied := p.clone()
+	copied.candidates = nil
+	copied.matchers = slices.Clone(p.matchers)
+	copied.aliases = nil
+	for _, alias := range p.aliases {
+		copied.aliases = append(copied.aliases, &RequestHandlerPackage{Path: alias.Path, anyMethod: alias.anyMethod, caseSensitive: alias.caseSensitive, matchers: copied.matchers})
+	}
+	copied.mergedMiddleware = append(slices.Clone(sourceMiddleware), copied.mergedMiddleware...)
+
+	groups := []*Group{}
+	for group := p.group; group != nil; group = group.parent {
+		groups = append(groups, group)
+	}
+	for _, group := range slices.Backward(groups) {
+		copied.mergedMiddleware = append(copied.mergedMiddleware, group.middleware...)
+	}
+	copied.version = p.routeVersion()
+	copied.deprecated = p.isDeprecated()
+	copied.group = nil
+	return &copied
+}
+
+// mergeConflict reports whether merging the route would replace a registered one or reuse
+// a registered name.
+func (r *Router) mergeConflict(method HttpMethod, handlerPackage *RequestHandlerPackage) error {
+	if named, exists := r.namedRoutes[handlerPackage.name]; handlerPackage.name != "" && exists {
+		return fmt.Errorf("%w: `%s` is already used by `%s`", ErrDuplicateRouteName, handlerPackage.name, named.Path)
+	}
+	if len(handlerPackage.matchers) > 0 {
+		return nil
+	}
+
+	paths, err := expandOptionalParam(r.normalizeSlash(handlerPackage.Path))
+	if err != nil {
+		return fmt.Errorf("invalid route `%s %s`: %w", method, handlerPackage.Path, err)
+	}
+	for _, path := range paths {
+		registered := r.findSlot(method, path, handlerPackage.caseSensitive, handlerPackage.paramPattern)
+		if registered == nil || registered.anyMethod != handlerPackage.anyMethod {
+			continue
+		}
+		if fallback := registered.fallback(); fallback != nil {
+			return fmt.Errorf("%w: `%s %s` conflicts with `%s %s`", ErrDuplicateRoute, method, handlerPackage.Path, method, fallback.Path)
+		}
+	}
+	return nil
+}
+
+// findSlot returns the route registered for the method and the pattern of the path
+func (r *Router) findSlot(method HttpMethod, path string, caseSensitive bool, paramPattern string) *RequestHandlerPackage {
+	parsed, err := parseRoutePath(path, caseSensitive, paramPattern)
+	if err != nil {
+		return nil
+	}
+	if len(parsed.paramNames) == 0 && caseSensitive {
+		return r.exactRoutes[method][path]
+	}
+	if len(parsed.paramNames) == 0 {
+		return r.staticRoutes[method][strings.ToLower(path)]
+	}
+
+	node := r.tree
+	for _, segment := range parsed.segments {
+		if node = node.existingChild(segment); node == nil {
+			return nil
+		}
+	}
+	return node.handlers[method]
+}
@@ -0,0 +1,114 @@
+package yagaw
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMergeAt(t *testing.T) {
+	tagging := func(tag string) Middleware {
+		return func(next HttpRequestHandler) HttpRequestHandler {
+			return func(req *http.Request, params Params) *HttpResponse {
+				response := next(req, params)
+				return response.SetBody(tag + "(" + response.body + ")")
+			}
+		}
+	}
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + PathParam(req, "id"))
+		}
+	}
+
+	billing := NewRouter().Use(tagging("billing"))
+	billing.RegisterRoute(GET, "/invoices/{id:int}", handler("invoice ")).Name("invoice")
+	billing.RegisterRoute(GET, "/invoices/{id:int}", handler("pdf ")).MatchHeader("Accept", "application/pdf")
+	invoices := billing.Group("/admin").Use(tagging("group"))
+	invoices.RegisterRouteWith(DELETE, "/invoices/{id}", handler("deleted "), tagging("route"))
+
+	app := NewRouter().Use(tagging("app"))
+	app.RegisterRoute(GET, "/", handler("home"))
+	if err := app.MergeAt("/billing", billing); err != nil {
+		t.Fatalf("unexpected merge error: %v", err)
+	}
+
+	tests := []struct {
+		method HttpMethod
+		path   string
+		accept string
+		status int
+		body   string
+	}{
+		{GET, "/", "", http.StatusOK, "app(home)"},
+		{GET, "/billing/invoices/7", "", http.StatusOK, "app(billing(invoice 7))"},
+		{GET, "/billing/invoices/7", "application/pdf", http.StatusOK, "app(billing(pdf 7))"},
+		{GET, "/billing/invoices/abc", "", http.StatusNotFound, "app(404 - Page not found)"},
+		{DELETE, "/billing/admin/invoices/7", "", http.StatusOK, "app(billing(group(route(deleted 7))))"},
+		{GET, "/invoices/7", "", http.StatusNotFound, "app(404 - Page not found)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method)+" "+tt.path+" "+tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(string(tt.method), tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			rw := httptest.NewRecorder()
+			app.ServeHTTP(rw, req)
+
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+		})
+	}
+
+	if url, err := app.URL("invoice", map[string]string{"id": "7"}); err != nil || url != "/billing/invoices/7" {
+		t.Errorf("expected the merged named route, got %q (%v)", url, err)
+	}
+	if url, err := billing.URL("invoice", map[string]string{"id": "7"}); err != nil || url != "/invoices/7" {
+		t.Errorf("expected the source named route to be untouched, got %q (%v)", url, err)
+	}
+
+	rw := httptest.NewRecorder()
+	billing.ServeHTTP(rw, httptest.NewRequest(string(GET), "/invoices/7", nil))
+	if rw.Body.String() != "billing(invoice 7)" {
+		t.Errorf("expected the source router to keep serving, got %q", rw.Body.String())
+	}
+
+	for _, route := range app.Routes() {
+		if route.Path == "/billing/invoices/{id:int}" && route.ParamTypes["id"] != "int" {
+			t.Errorf("expected the parameter types to be kept, got %v", route.ParamTypes)
+		}
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	source := NewRouter()
+	source.RegisterRoute(GET, "/users/{name}", handler)
+	source.RegisterRoute(GET, "/status", handler).Name("status")
+	source.RegisterRoute(POST, "/users", handler)
+
+	target := NewRouter()
+	target.RegisterRoute(GET, "/users/{id}", handler)
+	target.RegisterRoute(GET, "/health", handler).Name("status")
+
+	err := target.Merge(source)
+	if !errors.Is(err, ErrDuplicateRoute) || !errors.Is(err, ErrDuplicateRouteName) {
+		t.Fatalf("expected route and name conflicts, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "`GET /users/{name}` conflicts with `GET /users/{id}`") {
+		t.Errorf("expected the conflict to name both routes, got %q", err.Error())
+	}
+	if len(target.Routes()) != 2 {
+		t.Errorf("expected nothing to be merged, got %v", target.Routes())
+	}
+
+	if err := target.MergeAt("/v2", source); errors.Is(err, ErrDuplicateRoute) || !errors.Is(err, ErrDuplicateRouteName) {
+		t.Errorf("expected only the name to conflict under another prefix, got %v", err)
+	}
+}
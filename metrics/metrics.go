@@ -0,0 +1,121 @@
+// Package metrics provides a Prometheus middleware for yagaw servers, measuring requests by
+// method, matched route pattern and status code.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Algatux/yagaw"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// UnmatchedRoute is the route label of the requests no route matched, e.g. the 404s, so
+// that unknown paths don't create series.
+const UnmatchedRoute = "unmatched"
+
+// Metrics holds the collectors updated by the middleware
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+	gatherer prometheus.Gatherer
+}
+
+type settings struct {
+	registerer prometheus.Registerer
+	buckets    []float64
+}
+
+// Option configures the collectors created by New
+type Option func(s *settings)
+
+// WithRegisterer registers the collectors on the given registerer instead of the default
+// one, e.g. a prometheus.NewRegistry() in tests.
+func WithRegisterer(registerer prometheus.Registerer) Option {
+	return func(s *settings) {
+		s.registerer = registerer
+	}
+}
+
+// WithBuckets sets the buckets of the duration histogram, prometheus.DefBuckets unless set
+func WithBuckets(buckets ...float64) Option {
+	return func(s *settings) {
+		s.buckets = buckets
+	}
+}
+
+// New creates the collectors and registers them, it panics when they are already
+// registered like prometheus.MustRegister.
+func New(opts ...Option) *Metrics {
+	settings := &settings{registerer: prometheus.DefaultRegisterer, buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(settings)
+	}
+
+	labels := []string{"method", "route", "status"}
+	metrics := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Requests served, by method, matched route pattern and status code.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time taken to serve requests, by method, matched route pattern and status code.",
+			Buckets: settings.buckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Requests being served.",
+		}),
+		gatherer: prometheus.DefaultGatherer,
+	}
+	settings.registerer.MustRegister(metrics.requests, metrics.duration, metrics.inFlight)
+
+	// Registries gather what they registered, like the default one
+	if gatherer, isGatherer := settings.registerer.(prometheus.Gatherer); isGatherer {
+		metrics.gatherer = gatherer
+	}
+	return metrics
+}
+
+// Middleware measures the requests served by next, to be given to Server.Use. The route
+// label is the pattern the router matched, read from the request once served, so the
+// middleware must get the request the router serves.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+		started := time.Now()
+		recorder := yagaw.NewStatusRecorder(rw)
+
+		next.ServeHTTP(recorder, req)
+
+		route := req.Pattern
+		if route == "" {
+			route = UnmatchedRoute
+		}
+		labels := prometheus.Labels{"method": methodLabel(req.Method), "route": route, "status": strconv.Itoa(recorder.Status())}
+		m.requests.With(labels).Inc()
+		m.duration.With(labels).Observe(time.Since(started).Seconds())
+	})
+}
+
+// MetricsHandler serves the metrics in the Prometheus exposition format, mount it at
+// `/metrics`. It gathers the registerer given to New when it is a registry, the default
+// gatherer otherwise.
+func (m *Metrics) MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{})
+}
+
+// methodLabel bounds the method label to the known methods, clients choose the others
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
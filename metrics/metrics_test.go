@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Algatux/yagaw"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMiddleware(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := New(WithRegisterer(registry), WithBuckets(0.1, 1))
+
+	router := yagaw.NewRouter()
+	router.RegisterRoute(yagaw.GET, "/users/{id:int}", func(req *http.Request, params yagaw.Params) *yagaw.HttpResponse {
+		return yagaw.NewHttpResponse(http.StatusOK)
+	})
+	router.RegisterRoute(yagaw.POST, "/users", func(req *http.Request, params yagaw.Params) *yagaw.HttpResponse {
+		return yagaw.NewHttpResponse(http.StatusCreated)
+	})
+	router.Handle(yagaw.GET, "/metrics", metrics.MetricsHandler())
+	handler := metrics.Middleware(router)
+
+	for _, request := range []struct{ method, path string }{
+		{"GET", "/users/1"}, {"GET", "/users/2"}, {"POST", "/users"}, {"GET", "/missing/1"}, {"GETT", "/users/3"},
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(request.method, request.path, nil))
+	}
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest("GET", "/metrics", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rw.Code)
+	}
+	scraped := rw.Body.String()
+
+	expected := []string{
+		`http_requests_total{method="GET",route="/users/{id:int}",status="200"} 2`,
+		`http_requests_total{method="POST",route="/users",status="201"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="OTHER",route="unmatched",status="405"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/users/{id:int}",status="200",le="0.1"} 2`,
+		`http_request_duration_seconds_count{method="POST",route="/users",status="201"} 1`,
+		// The scrape itself is in flight
+		`http_requests_in_flight 1`,
+	}
+	for _, series := range expected {
+		if !strings.Contains(scraped, series) {
+			t.Errorf("expected the series %s, got\n%s", series, scraped)
+		}
+	}
+	if strings.Contains(scraped, "/missing/1") {
+		t.Error("expected the raw path of unmatched requests not to be a label")
+	}
+}
+
+func TestNewRegistersOnce(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	New(WithRegisterer(registry))
+
+	defer func() {
+		if recovered := recover(); recovered == nil {
+			t.Error("expected registering the collectors twice to panic")
+		}
+	}()
+	New(WithRegisterer(registry))
+}
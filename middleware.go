@@ -0,0 +1,39 @@
+package yagaw
+
+type Middleware func(next HttpRequestHandler) HttpRequestHandler
+
+// Use appends middleware wrapping every handler resolved by the router, not found and
+// method not allowed handlers included. The first middleware added runs outermost.
+func (r *Router) Use(mw ...Middleware) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.middleware = append(r.middleware, mw...)
+	return r
+}
+
+func chainMiddleware(handler HttpRequestHandler, middleware []Middleware) HttpRequestHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// chain wraps the route handler with its own middleware first, then with the middleware
+// of the enclosing groups from the innermost to the outermost, then with the middleware
+// the route carried over when merged from another router.
+func (p *RequestHandlerPackage) chain() HttpRequestHandler {
+	handler := chainMiddleware(p.Handler, p.middleware)
+	for group := p.group; group != nil; group = group.parent {
+		handler = chainMiddleware(handler, group.middleware)
+	}
+	return chainMiddleware(handler, p.mergedMiddleware)
+}
+
+func (p *RequestHandlerPackage) middlewareCount() int {
+	count := len(p.middleware)
+	for group := p.group; group != nil; group = group.parent {
+		count += len(group.middleware)
+	}
+	count += len(p.mergedMiddleware)
+	return count
+}
@@ -0,0 +1,166 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			*calls = append(*calls, name+":before")
+			response := next(req, params)
+			*calls = append(*calls, name+":after")
+			return response
+		}
+	}
+}
+
+func TestUseExecutionOrder(t *testing.T) {
+	router := NewRouter()
+
+	calls := []string{}
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		calls = append(calls, "handler")
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/users", handler)
+	router.Use(recordingMiddleware("first", &calls), recordingMiddleware("second", &calls))
+	router.Use(recordingMiddleware("third", &calls))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(string(GET), "/users", nil))
+
+	expected := []string{
+		"first:before", "second:before", "third:before",
+		"handler",
+		"third:after", "second:after", "first:after",
+	}
+	if !slices.Equal(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+}
+
+func TestUseWrapsNotFoundAndMethodNotAllowed(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+	statuses := []int{}
+	router.Use(func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			response := next(req, params)
+			statuses = append(statuses, response.status)
+			return response.SetHeader("X-Middleware", "true")
+		}
+	})
+
+	router.RegisterRoute(GET, "/users", handler)
+
+	tests := []struct {
+		method HttpMethod
+		path   string
+		status int
+	}{
+		{GET, "/users", http.StatusOK},
+		{GET, "/unknown", http.StatusNotFound},
+		{POST, "/users", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+		if rw.Code != tt.status {
+			t.Errorf("expected status %d, got %d", tt.status, rw.Code)
+		}
+		if rw.Header().Get("X-Middleware") != "true" {
+			t.Errorf("expected middleware to run for %s %s", tt.method, tt.path)
+		}
+	}
+
+	if !slices.Equal(statuses, []int{http.StatusOK, http.StatusNotFound, http.StatusMethodNotAllowed}) {
+		t.Errorf("unexpected statuses seen by the middleware: %v", statuses)
+	}
+}
+
+func TestUseCanShortCircuit(t *testing.T) {
+	router := NewRouter()
+
+	called := false
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		called = true
+		return NewHttpResponse(http.StatusOK)
+	}
+	router.Use(func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			if req.Header.Get("Authorization") == "" {
+				return NewHttpResponse(http.StatusUnauthorized)
+			}
+			return next(req, params)
+		}
+	})
+
+	router.RegisterRoute(GET, "/private/{id}", handler)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/private/1", nil))
+
+	if rw.Code != http.StatusUnauthorized || called {
+		t.Errorf("expected the middleware to reject the request, got %d (handler called: %v)", rw.Code, called)
+	}
+}
+
+func TestRouteMiddlewareOrdering(t *testing.T) {
+	router := NewRouter()
+
+	calls := []string{}
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		calls = append(calls, "handler")
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.Use(recordingMiddleware("router", &calls))
+	api := router.Group("/api").Use(recordingMiddleware("api", &calls))
+	users := api.Group("/users")
+	users.RegisterRouteWith(GET, "/{id}", handler, recordingMiddleware("route1", &calls), recordingMiddleware("route2", &calls))
+	users.Use(recordingMiddleware("users", &calls))
+	users.RegisterRoute(GET, "", handler)
+	router.RegisterRouteWith(GET, "/private", handler, recordingMiddleware("private", &calls))
+	router.RegisterRoute(GET, "/public", handler)
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []string
+	}{
+		{"router, groups then route", "/api/users/42", []string{
+			"router:before", "api:before", "users:before", "route1:before", "route2:before",
+			"handler",
+			"route2:after", "route1:after", "users:after", "api:after", "router:after",
+		}},
+		{"group route without route middleware", "/api/users", []string{
+			"router:before", "api:before", "users:before", "handler", "users:after", "api:after", "router:after",
+		}},
+		{"route middleware outside groups", "/private", []string{
+			"router:before", "private:before", "handler", "private:after", "router:after",
+		}},
+		{"route middleware does not leak", "/public", []string{
+			"router:before", "handler", "router:after",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = []string{}
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(string(GET), tt.path, nil))
+
+			if !slices.Equal(calls, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+type mount struct {
+	prefix        string
+	handler       http.Handler
+	stripPrefix   bool
+	caseSensitive bool
+}
+
+type MountOption func(m *mount)
+
+// StripPrefix removes the mount prefix from the request path before it reaches the handler.
+func StripPrefix() MountOption {
+	return func(m *mount) {
+		m.stripPrefix = true
+	}
+}
+
+// Mount delegates every request whose path is under prefix, whatever the method, to the
+// given handler. Routes registered on the router are always preferred over mounts. The
+// prefix is matched case sensitively when the router is CaseSensitive.
+func (r *Router) Mount(prefix string, handler http.Handler, opts ...MountOption) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mounted := &mount{prefix: strings.TrimSuffix(prefix, "/"), handler: handler, caseSensitive: r.caseSensitive}
+	for _, opt := range opts {
+		opt(mounted)
+	}
+
+	// Longer prefixes first so that the most specific mount wins
+	r.mounts = slices.DeleteFunc(r.mounts, func(m *mount) bool {
+		return m.prefix == mounted.prefix || !m.caseSensitive && !mounted.caseSensitive && strings.EqualFold(m.prefix, mounted.prefix)
+	})
+	r.mounts = append(r.mounts, mounted)
+	slices.SortStableFunc(r.mounts, func(a, b *mount) int {
+		return len(b.prefix) - len(a.prefix)
+	})
+
+	return r
+}
+
+func (r *Router) findMount(path string) *mount {
+	for _, mounted := range r.mounts {
+		if mounted.matches(path, r.useRawPath) {
+			return mounted
+		}
+	}
+	return nil
+}
+
+// matches tells whether the path is the prefix or under it, an escaped path being decoded
+// as far as the prefix goes, without its encoded slashes separating segments.
+func (m *mount) matches(path string, escaped bool) bool {
+	end := len(m.prefix)
+	if escaped {
+		end = escapedLen(path, len(m.prefix))
+	}
+	if end < 0 || end > len(path) {
+		return false
+	}
+	head := path[:end]
+	if escaped {
+		decoded, err := url.PathUnescape(head)
+		if err != nil || strings.Count(head, "/") != strings.Count(m.prefix, "/") {
+			return false
+		}
+		head = decoded
+	}
+	if head != m.prefix && (m.caseSensitive || !strings.EqualFold(head, m.prefix)) {
+		return false
+	}
+	return end == len(path) || path[end] == '/'
+}
+
+func (m *mount) handle(req *http.Request, _ Params) *HttpResponse {
+	if !m.stripPrefix {
+		return delegatedResponse(m.handler, req)
+	}
+
+	strippedReq := new(http.Request)
+	*strippedReq = *req
+	strippedReq.URL = new(url.URL)
+	*strippedReq.URL = *req.URL
+	strippedReq.URL.Path = req.URL.Path[len(m.prefix):]
+	strippedReq.URL.RawPath = ""
+	if strippedReq.URL.Path == "" {
+		strippedReq.URL.Path = "/"
+	}
+	// The escaped path keeps its encoding so that the mounted handler splits it the same way,
+	// as long as its prefix is the one stripped from the path
+	if end := escapedLen(req.URL.RawPath, len(m.prefix)); end >= 0 {
+		if prefix, err := url.PathUnescape(req.URL.RawPath[:end]); err == nil && prefix == req.URL.Path[:len(m.prefix)] {
+			strippedReq.URL.RawPath = req.URL.RawPath[end:]
+		}
+	}
+
+	return delegatedResponse(m.handler, strippedReq)
+}
+
+// escapedLen returns the length of the start of the escaped path decoding to n bytes, -1
+// when it is shorter.
+func escapedLen(rawPath string, n int) int {
+	end := 0
+	for ; n > 0 && end < len(rawPath); n-- {
+		if rawPath[end] == '%' {
+			end += 3
+		} else {
+			end++
+		}
+	}
+	if n > 0 || end > len(rawPath) {
+		return -1
+	}
+	return end
+}
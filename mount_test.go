@@ -0,0 +1,130 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	router := NewRouter()
+
+	echoPath := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Mounted", "true")
+		rw.WriteHeader(http.StatusTeapot)
+		rw.Write([]byte(req.Method + " " + req.URL.Path))
+	})
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("route")
+	}
+
+	router.Mount("/static", echoPath)
+	router.Mount("/assets/", echoPath, StripPrefix())
+	router.RegisterRoute(GET, "/static/special", handler)
+
+	tests := []struct {
+		name     string
+		method   HttpMethod
+		path     string
+		status   int
+		expected string
+	}{
+		{"prefix preserved", GET, "/static/css/site.css", http.StatusTeapot, "GET /static/css/site.css"},
+		{"any method", DELETE, "/static/file", http.StatusTeapot, "DELETE /static/file"},
+		{"prefix itself", GET, "/static", http.StatusTeapot, "GET /static"},
+		{"prefix stripped", POST, "/assets/js/app.js", http.StatusTeapot, "POST /js/app.js"},
+		{"stripped prefix itself", GET, "/assets", http.StatusTeapot, "GET /"},
+		{"registered route wins", GET, "/static/special", http.StatusOK, "route"},
+		{"prefix must end on a segment", GET, "/staticfiles", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if rw.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestMountMostSpecificPrefixWins(t *testing.T) {
+	router := NewRouter()
+
+	handlerFor := func(body string) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte(body))
+		})
+	}
+
+	router.Mount("/api", handlerFor("api"))
+	router.Mount("/api/admin", handlerFor("admin"))
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/api/admin/users", nil))
+
+	if rw.Body.String() != "admin" {
+		t.Errorf("expected the most specific mount to win, got %q", rw.Body.String())
+	}
+}
+
+func TestMountedHandlerNotFoundIsPreserved(t *testing.T) {
+	router := NewRouter()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/exists", func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("exists"))
+	})
+	router.Mount("/files", mux)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/files/missing", nil))
+
+	if rw.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rw.Code)
+	}
+	if rw.Body.String() != "404 page not found\n" {
+		t.Errorf("expected the mounted handler 404 body, got %q", rw.Body.String())
+	}
+}
+
+func TestMountCaseSensitive(t *testing.T) {
+	router := NewRouter().CaseSensitive(true)
+	router.Mount("/Static", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte("mounted"))
+	}))
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/Static/site.css", http.StatusOK},
+		{"/static/site.css", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+		if rw.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, rw.Code)
+		}
+	}
+}
+
+func TestMountStripPrefixEscapedPath(t *testing.T) {
+	router := NewRouter()
+	router.Mount("/my files", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Write([]byte(req.URL.Path + " " + req.URL.EscapedPath()))
+	}), StripPrefix())
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/my%20files/a%2Fb", nil))
+
+	if expected := "/a/b /a%2Fb"; rw.Body.String() != expected {
+		t.Errorf("expected %q, got %q", expected, rw.Body.String())
+	}
+}
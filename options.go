@@ -0,0 +1,105 @@
+package yagaw
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ----------- SERVER OPTIONS -----------
+
+// ServerOption configures a server when it is created by NewServer
+type ServerOption func(s *Server)
+
+// httpSettings holds the timeouts, limits and hooks given to the http.Server of every run,
+// zero values keep the net/http defaults.
+type httpSettings struct {
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+	baseContext       func(net.Listener) context.Context
+	connContext       func(ctx context.Context, conn net.Conn) context.Context
+	connState         func(conn net.Conn, state http.ConnState)
+	disableKeepAlives bool
+}
+
+// WithReadTimeout limits how long reading a whole request, body included, may take
+func WithReadTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.httpSettings.readTimeout = timeout
+	}
+}
+
+// WithReadHeaderTimeout limits how long reading the request headers may take, the first
+// defence against clients sending them slowly (Slowloris). net/http leaves it unlimited,
+// internet-facing servers should set it.
+func WithReadHeaderTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.httpSettings.readHeaderTimeout = timeout
+	}
+}
+
+// WithWriteTimeout limits how long writing the response may take, from the end of the
+// request headers.
+func WithWriteTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.httpSettings.writeTimeout = timeout
+	}
+}
+
+// WithIdleTimeout limits how long a keep-alive connection waits for the next request
+func WithIdleTimeout(timeout time.Duration) ServerOption {
+	return func(s *Server) {
+		s.httpSettings.idleTimeout = timeout
+	}
+}
+
+// WithMaxHeaderBytes limits the size of the request headers, request line included
+func WithMaxHeaderBytes(size int) ServerOption {
+	return func(s *Server) {
+		s.httpSettings.maxHeaderBytes = size
+	}
+}
+
+// WithBaseContext sets the function returning the context every request context derives
+// from, e.g. to hold application wide values like a database pool read through
+// req.Context() without a middleware.
+func WithBaseContext(baseContext func(listener net.Listener) context.Context) ServerOption {
+	return func(s *Server) {
+		s.httpSettings.baseContext = baseContext
+	}
+}
+
+// WithConnContext sets the function deriving the context of a new connection from the
+// base one, the requests of the connection derive from it.
+func WithConnContext(connContext func(ctx context.Context, conn net.Conn) context.Context) ServerOption {
+	return func(s *Server) {
+		s.httpSettings.connContext = connContext
+	}
+}
+
+// WithConnState sets a function called on every connection state change, e.g. to count
+// the open connections, like http.Server.ConnState.
+func WithConnState(connState func(conn net.Conn, state http.ConnState)) ServerOption {
+	return func(s *Server) {
+		s.httpSettings.connState = connState
+	}
+}
+
+// WithDisableKeepAlives closes every connection once its request is answered, responses
+// carrying `Connection: close`. SetKeepAlivesEnabled changes it at runtime.
+func WithDisableKeepAlives() ServerOption {
+	return func(s *Server) {
+		s.httpSettings.disableKeepAlives = true
+	}
+}
+
+// WithRouter makes the server serve a router built beforehand instead of a new one
+func WithRouter(router *Router) ServerOption {
+	return func(s *Server) {
+		s.SetRouter(router)
+	}
+}
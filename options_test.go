@@ -0,0 +1,227 @@
+package yagaw
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerOptions(t *testing.T) {
+	t.Run("http.Server fields", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0,
+			WithReadTimeout(5*time.Second),
+			WithReadHeaderTimeout(2*time.Second),
+			WithWriteTimeout(10*time.Second),
+			WithIdleTimeout(time.Minute),
+			WithMaxHeaderBytes(64<<10),
+		)
+		httpServer, _ := server.prepare()
+
+		if httpServer.ReadTimeout != 5*time.Second {
+			t.Errorf("expected ReadTimeout 5s, got %v", httpServer.ReadTimeout)
+		}
+		if httpServer.ReadHeaderTimeout != 2*time.Second {
+			t.Errorf("expected ReadHeaderTimeout 2s, got %v", httpServer.ReadHeaderTimeout)
+		}
+		if httpServer.WriteTimeout != 10*time.Second {
+			t.Errorf("expected WriteTimeout 10s, got %v", httpServer.WriteTimeout)
+		}
+		if httpServer.IdleTimeout != time.Minute {
+			t.Errorf("expected IdleTimeout 1m, got %v", httpServer.IdleTimeout)
+		}
+		if httpServer.MaxHeaderBytes != 64<<10 {
+			t.Errorf("expected MaxHeaderBytes 65536, got %d", httpServer.MaxHeaderBytes)
+		}
+	})
+
+	t.Run("net/http defaults", func(t *testing.T) {
+		httpServer, _ := NewServer("127.0.0.1", 0).prepare()
+		if httpServer.ReadTimeout != 0 || httpServer.ReadHeaderTimeout != 0 || httpServer.WriteTimeout != 0 || httpServer.IdleTimeout != 0 || httpServer.MaxHeaderBytes != 0 {
+			t.Errorf("expected no limit, got %+v", httpServer)
+		}
+	})
+
+	t.Run("pre-built router", func(t *testing.T) {
+		router := NewRouter()
+		router.RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody("hello")
+		})
+		server := NewServer("127.0.0.1", 0, WithRouter(router))
+		if server.GetRouter() != router {
+			t.Fatal("expected the given router")
+		}
+
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		defer func() {
+			server.Shutdown(context.Background())
+			<-ran
+		}()
+		res, err := http.Get("http://" + awaitReady(t, server, ran) + "/hello")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+		if body, _ := io.ReadAll(res.Body); string(body) != "hello" {
+			t.Errorf("expected the router to be served, got %q", body)
+		}
+	})
+
+	t.Run("base and connection contexts", func(t *testing.T) {
+		type contextKey string
+		server := NewServer("127.0.0.1", 0,
+			WithBaseContext(func(net.Listener) context.Context {
+				return context.WithValue(context.Background(), contextKey("pool"), "main pool")
+			}),
+			WithConnContext(func(ctx context.Context, conn net.Conn) context.Context {
+				return context.WithValue(ctx, contextKey("remote"), conn.RemoteAddr().String())
+			}),
+		)
+		server.GetRouter().RegisterRoute(GET, "/users/{id}", func(req *http.Request, params Params) *HttpResponse {
+			pool, _ := req.Context().Value(contextKey("pool")).(string)
+			remote, _ := req.Context().Value(contextKey("remote")).(string)
+			return NewHttpResponse(http.StatusOK).SetBody(pool + " " + remote + " " + PathParam(req, "id"))
+		})
+
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		defer func() {
+			server.Shutdown(context.Background())
+			<-ran
+		}()
+		conn, err := net.Dial("tcp", awaitReady(t, server, ran))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("GET /users/42 HTTP/1.1\r\nHost: yagaw.test\r\nConnection: close\r\n\r\n"))
+		res, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+		expected := "main pool " + conn.LocalAddr().String() + " 42"
+		if body, _ := io.ReadAll(res.Body); string(body) != expected {
+			t.Errorf("expected %q, got %q", expected, body)
+		}
+	})
+
+	t.Run("slow headers", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0, WithReadHeaderTimeout(50*time.Millisecond))
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		defer func() {
+			server.Shutdown(context.Background())
+			<-ran
+		}()
+
+		conn, err := net.Dial("tcp", awaitReady(t, server, ran))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("GET / HTTP/1.1\r\n"))
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := io.ReadAll(conn); err != nil {
+			t.Errorf("expected the connection to be closed by the server, got %v", err)
+		}
+	})
+}
+
+func TestServerKeepAlives(t *testing.T) {
+	start := func(t *testing.T, opts ...ServerOption) (*Server, string) {
+		server := NewServer("127.0.0.1", 0, opts...)
+		server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody("hello")
+		})
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		t.Cleanup(func() {
+			server.Shutdown(context.Background())
+			<-ran
+		})
+		return server, awaitReady(t, server, ran)
+	}
+	// The raw response is read, net/http clients drop the Connection header
+	closes := func(t *testing.T, address string) bool {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("GET /hello HTTP/1.1\r\nHost: yagaw.test\r\n\r\n"))
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if line == "\r\n" {
+				return false
+			}
+			if strings.EqualFold(line, "Connection: close\r\n") {
+				return true
+			}
+		}
+	}
+
+	t.Run("enabled by default", func(t *testing.T) {
+		_, address := start(t)
+		if closes(t, address) {
+			t.Error("expected the connection to be kept alive")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		_, address := start(t, WithDisableKeepAlives())
+		if !closes(t, address) {
+			t.Error("expected Connection: close")
+		}
+	})
+
+	t.Run("toggled at runtime", func(t *testing.T) {
+		server, address := start(t)
+		server.SetKeepAlivesEnabled(false)
+		if !closes(t, address) {
+			t.Error("expected Connection: close once disabled")
+		}
+		server.SetKeepAlivesEnabled(true)
+		if closes(t, address) {
+			t.Error("expected the connection to be kept alive once enabled again")
+		}
+	})
+
+	t.Run("connection states", func(t *testing.T) {
+		states := make(chan http.ConnState, 8)
+		server, address := start(t, WithConnState(func(conn net.Conn, state http.ConnState) { states <- state }))
+
+		client := &http.Client{Transport: &http.Transport{}}
+		res, err := client.Get("http://" + address + "/hello")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		for _, expected := range []http.ConnState{http.StateNew, http.StateActive, http.StateIdle} {
+			if state := <-states; state != expected {
+				t.Fatalf("expected %v, got %v", expected, state)
+			}
+		}
+		if stats := server.Stats(); stats.Connections != 1 || stats.IdleConnections != 1 {
+			t.Errorf("expected one idle connection, got %d open and %d idle", stats.Connections, stats.IdleConnections)
+		}
+
+		client.CloseIdleConnections()
+		if state := <-states; state != http.StateClosed {
+			t.Fatalf("expected the connection to be closed, got %v", state)
+		}
+		if stats := server.Stats(); stats.Connections != 0 || stats.IdleConnections != 0 {
+			t.Errorf("expected no connection, got %d open and %d idle", stats.Connections, stats.IdleConnections)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package yagaw
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const methodOverrideHeader = "X-HTTP-Method-Override"
+const methodOverrideField = "_method"
+
+type originalMethodKey struct{}
+
+// AllowMethodOverride lets POST requests be routed as one of the given methods when they
+// carry an `X-HTTP-Method-Override` header or a `_method` form field, for HTML forms and
+// proxies limited to GET and POST. GET and HEAD are never valid targets so that a POST
+// can't be turned into a cacheable request, calling it without methods disables it.
+func (r *Router) AllowMethodOverride(methods ...HttpMethod) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methodOverrides = slices.DeleteFunc(slices.Clone(methods), func(method HttpMethod) bool {
+		return method == GET || method == HEAD
+	})
+	return r
+}
+
+// OriginalMethod returns the method the request was sent with, which differs from the
+// routed one when a method override was applied.
+func OriginalMethod(req *http.Request) HttpMethod {
+	if method, found := req.Context().Value(originalMethodKey{}).(HttpMethod); found {
+		return method
+	}
+	return HttpMethod(req.Method)
+}
+
+// overrideMethod returns the request to route, with the overridden method when one of the
+// allowed methods is requested. The header wins over the form field, which is read only
+// from form encoded bodies.
+func (r *Router) overrideMethod(req *http.Request) *http.Request {
+	r.mu.RLock()
+	allowed := r.methodOverrides
+	r.mu.RUnlock()
+	if len(allowed) == 0 || req.Method != string(POST) {
+		return req
+	}
+
+	override := req.Header.Get(methodOverrideHeader)
+	if override == "" && isFormEncoded(req) && req.ParseForm() == nil {
+		override = req.PostForm.Get(methodOverrideField)
+	}
+	method := HttpMethod(strings.ToUpper(override))
+	if !slices.Contains(allowed, method) {
+		return req
+	}
+
+	overridden := req.WithContext(context.WithValue(req.Context(), originalMethodKey{}, HttpMethod(req.Method)))
+	overridden.Method = string(method)
+	return overridden
+}
+
+// isFormEncoded reports whether the body is url encoded, so that looking for the form field
+// never reads a multipart body, uploads included, before the handler does.
+func isFormEncoded(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
@@ -0,0 +1,108 @@
+package yagaw
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(req.Method + " from " + string(OriginalMethod(req)))
+	}
+
+	router := NewRouter()
+	for _, method := range []HttpMethod{GET, POST, PUT, PATCH, DELETE} {
+		router.RegisterRoute(method, "/users/{id}", handler)
+	}
+
+	tests := []struct {
+		name     string
+		method   HttpMethod
+		header   string
+		form     string
+		expected string
+	}{
+		{"header", POST, "PUT", "", "PUT from POST"},
+		{"lowercase header", POST, "delete", "", "DELETE from POST"},
+		{"form field", POST, "", "_method=DELETE&name=john", "DELETE from POST"},
+		{"header wins over form", POST, "PUT", "_method=PATCH", "PUT from POST"},
+		{"not whitelisted", POST, "PATCH", "", "POST from POST"},
+		{"get refused", POST, "GET", "", "POST from POST"},
+		{"head refused", POST, "", "_method=HEAD", "POST from POST"},
+		{"only post is overridden", PUT, "DELETE", "", "PUT from PUT"},
+		{"no override", POST, "", "name=john", "POST from POST"},
+	}
+
+	router.AllowMethodOverride(PUT, DELETE, GET, HEAD)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(string(tt.method), "/users/7", strings.NewReader(tt.form))
+			if tt.form != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.header != "" {
+				req.Header.Set("X-HTTP-Method-Override", tt.header)
+			}
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != http.StatusOK || rw.Body.String() != tt.expected {
+				t.Errorf("expected 200 %q, got %d %q", tt.expected, rw.Code, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestMethodOverrideDisabledByDefault(t *testing.T) {
+	router := NewRouter()
+	router.RegisterRoute(DELETE, "/users/{id}", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusNoContent)
+	})
+
+	for _, allowed := range [][]HttpMethod{nil, {DELETE}, {}} {
+		router.AllowMethodOverride(allowed...)
+
+		req := httptest.NewRequest(string(POST), "/users/7", strings.NewReader("_method=DELETE"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+
+		expected := http.StatusMethodNotAllowed
+		if len(allowed) > 0 {
+			expected = http.StatusNoContent
+		}
+		if rw.Code != expected {
+			t.Errorf("expected status %d with overrides %v, got %d", expected, allowed, rw.Code)
+		}
+	}
+}
+
+func TestMethodOverrideLeavesMultipartBodyUnread(t *testing.T) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("_method", "DELETE")
+	writer.Close()
+	body := buf.String()
+
+	router := NewRouter().AllowMethodOverride(DELETE)
+	router.RegisterRoute(POST, "/uploads", func(req *http.Request, params Params) *HttpResponse {
+		read, _ := io.ReadAll(req.Body)
+		return NewHttpResponse(http.StatusOK).SetBody(req.Method + " " + strconv.Itoa(len(read)))
+	})
+
+	req := httptest.NewRequest(string(POST), "/uploads", strings.NewReader(body))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	expected := "POST " + strconv.Itoa(len(body))
+	if rw.Code != http.StatusOK || rw.Body.String() != expected {
+		t.Errorf("expected 200 %q, got %d %q", expected, rw.Code, rw.Body.String())
+	}
+}
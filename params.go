@@ -0,0 +1,47 @@
+package yagaw
+
+import (
+	"context"
+	"maps"
+	"net/http"
+)
+
+type pathParamsKey struct{}
+type allowedMethodsKey struct{}
+
+// PathParam returns the value captured for the named route parameter, or an
+// empty string when the matched route does not declare it.
+func PathParam(req *http.Request, name string) string {
+	value, _ := LookupPathParam(req, name)
+	return value
+}
+
+// LookupPathParam is like PathParam but also reports whether the parameter was captured.
+func LookupPathParam(req *http.Request, name string) (string, bool) {
+	params, _ := req.Context().Value(pathParamsKey{}).(map[string]string)
+	value, found := params[name]
+	return value, found
+}
+
+// PathParams returns a copy of all the parameters captured for the matched route.
+func PathParams(req *http.Request) map[string]string {
+	params, _ := req.Context().Value(pathParamsKey{}).(map[string]string)
+	paramsCopy := make(map[string]string, len(params))
+	maps.Copy(paramsCopy, params)
+	return paramsCopy
+}
+
+func withPathParams(req *http.Request, params map[string]string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), pathParamsKey{}, params))
+}
+
+// AllowedMethods returns the methods registered for the requested path when the router
+// answers with 405 Method Not Allowed or an automatic OPTIONS response.
+func AllowedMethods(req *http.Request) []HttpMethod {
+	allowed, _ := req.Context().Value(allowedMethodsKey{}).([]HttpMethod)
+	return allowed
+}
+
+func withAllowedMethods(req *http.Request, allowed []HttpMethod) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), allowedMethodsKey{}, allowed))
+}
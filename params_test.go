@@ -0,0 +1,319 @@
+package yagaw
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPathParamsMultipleParameters(t *testing.T) {
+	router := NewRouter()
+
+	var userId, postId string
+	var handlerParams Params
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		userId = PathParam(req, "userId")
+		postId = PathParam(req, "postId")
+		handlerParams = params
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/users/{userId}/posts/{postId}", handler)
+
+	req := httptest.NewRequest(string(GET), "/users/42/posts/7", nil)
+	rw := httptest.NewRecorder()
+
+	router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rw.Code)
+	}
+	if userId != "42" || postId != "7" {
+		t.Errorf("expected userId=42 and postId=7, got userId=%q postId=%q", userId, postId)
+	}
+	if handlerParams["userId"] != "42" || handlerParams["postId"] != "7" {
+		t.Errorf("expected handler params to carry the same values, got %v", handlerParams)
+	}
+}
+
+func TestPathParamsNestedRoute(t *testing.T) {
+	router := NewRouter()
+
+	var captured map[string]string
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		captured = PathParams(req)
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/api/v1/tenants/{tenantId}/users/{id}/profile", handler)
+
+	tests := []struct {
+		name     string
+		path     string
+		tenantId string
+		id       string
+	}{
+		{"numeric values", "/api/v1/tenants/1/users/123/profile", "1", "123"},
+		{"slug values", "/api/v1/tenants/acme-corp/users/john_doe/profile", "acme-corp", "john_doe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captured = nil
+			req := httptest.NewRequest(string(GET), tt.path, nil)
+			rw := httptest.NewRecorder()
+
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rw.Code)
+			}
+			if len(captured) != 2 {
+				t.Fatalf("expected 2 params, got %v", captured)
+			}
+			if captured["tenantId"] != tt.tenantId || captured["id"] != tt.id {
+				t.Errorf("expected tenantId=%q id=%q, got %v", tt.tenantId, tt.id, captured)
+			}
+		})
+	}
+}
+
+func TestPathParamMissing(t *testing.T) {
+	router := NewRouter()
+
+	var value string
+	var found bool
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		value, found = LookupPathParam(req, "unknown")
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/users/{id}", handler)
+	router.RegisterRoute(GET, "/users", handler)
+
+	for _, path := range []string{"/users/123", "/users"} {
+		t.Run(path, func(t *testing.T) {
+			value, found = "unset", true
+			req := httptest.NewRequest(string(GET), path, nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if found || value != "" {
+				t.Errorf("expected missing param to be reported as absent, got %q, %v", value, found)
+			}
+			if PathParam(req, "unknown") != "" {
+				t.Error("expected PathParam to return an empty string for a missing param")
+			}
+		})
+	}
+}
+
+func TestPathParamsAreNotSharedBetweenRequests(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "id"))
+	}
+
+	router.RegisterRoute(GET, "/users/{id}", handler)
+
+	for _, id := range []string{"1", "2", "3"} {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/users/"+id, nil))
+
+		if rw.Body.String() != id {
+			t.Errorf("expected body %q, got %q", id, rw.Body.String())
+		}
+	}
+}
+
+func TestPathParamsAreDecoded(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "name") + "|" + fmt.Sprint(params["name"]))
+	}
+	router.RegisterRoute(GET, "/users/{name}", handler)
+	router.RegisterRoute(GET, "/users/{name}/avatar.{ext:alpha}", handler)
+
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"/users/john", "john"},
+		{"/users/john%20doe", "john doe"},
+		{"/users/a%2Bb", "a+b"},
+		{"/users/a+b", "a+b"},
+		{"/users/%C3%A9milie", "émilie"},
+		{"/users/%E6%97%A5%E6%9C%AC", "日本"},
+		{"/users/%3F%23%26%3D%25", "?#&=%"},
+		{"/users/john%20doe/avatar.png", "john doe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.target, nil))
+
+			if rw.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rw.Code)
+			}
+			if expected := tt.expected + "|" + tt.expected; rw.Body.String() != expected {
+				t.Errorf("expected %q, got %q", expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestInvalidPathEncodingIsBadRequest(t *testing.T) {
+	router := NewRouter()
+	router.RegisterRoute(GET, "/users/{name}", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(string(GET), "/users/x", nil)
+	req.URL.Path = "/users/%zz"
+	req.URL.RawPath = "/users/%zz"
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rw.Code)
+	}
+
+	// Requests reaching the server with an invalid encoding never get to a 404 either
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /users/%zz HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400 from the server, got %d", response.StatusCode)
+	}
+}
+
+func TestUseRawPath(t *testing.T) {
+	newRouter := func(useRawPath bool) *Router {
+		router := NewRouter().UseRawPath(useRawPath)
+		handler := func(body string) HttpRequestHandler {
+			return func(req *http.Request, params Params) *HttpResponse {
+				return NewHttpResponse(http.StatusOK).SetBody(body + joinParams(req, "name", "owner", "rest"))
+			}
+		}
+		router.RegisterRoute(GET, "/repos/{name:.+}", handler("repo "))
+		router.RegisterRoute(GET, "/repos/{owner}/{name}", handler("owned "))
+		router.RegisterRoute(GET, "/files/{*rest}", handler("file "))
+		router.RegisterRoute(GET, "/about us", handler("about"))
+		return router
+	}
+
+	tests := []struct {
+		target  string
+		decoded string
+		raw     string
+	}{
+		{"/repos/org%2Fproject", "owned project,org,", "repo org/project,,"},
+		{"/repos/org/project", "owned project,org,", "owned project,org,"},
+		{"/repos/john%20doe", "repo john doe,,", "repo john doe,,"},
+		{"/files/a%2Fb/c", "file ,,a/b/c", "file ,,a/b/c"},
+		{"/about%20us", "about,,", "about,,"},
+	}
+
+	decoded, raw := newRouter(false), newRouter(true)
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			for router, expected := range map[*Router]string{decoded: tt.decoded, raw: tt.raw} {
+				rw := httptest.NewRecorder()
+				router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.target, nil))
+
+				if rw.Code != http.StatusOK {
+					t.Fatalf("expected status 200 with raw path %v, got %d", router.useRawPath, rw.Code)
+				}
+				if rw.Body.String() != expected {
+					t.Errorf("expected %q with raw path %v, got %q", expected, router.useRawPath, rw.Body.String())
+				}
+			}
+		})
+	}
+
+	t.Run("trailing slash redirect keeps the encoding", func(t *testing.T) {
+		raw.RedirectTrailingSlash(true)
+
+		rw := httptest.NewRecorder()
+		raw.ServeHTTP(rw, httptest.NewRequest(string(GET), "/repos/org%2Fproject/", nil))
+		if rw.Code != http.StatusMovedPermanently || rw.Header().Get("Location") != "/repos/org%2Fproject" {
+			t.Errorf("expected a redirect to /repos/org%%2Fproject, got %d %q", rw.Code, rw.Header().Get("Location"))
+		}
+	})
+}
+
+func TestEscapedPathMatching(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + joinParams(req, "name", "id", "rest"))
+		}
+	}
+	router := NewRouter()
+	router.RegisterRoute(GET, "/files/{name}", handler("file "))
+	router.RegisterRoute(GET, "/files/secret", handler("files secret"))
+	router.RegisterRoute(GET, "/secret", handler("secret"))
+	router.RegisterRoute(GET, "/repos/{name:.+}", handler("repo "))
+	router.RegisterRoute(GET, "/ids/{id:int}", handler("id "))
+	router.RegisterRoute(GET, "/static/{*rest}", handler("static "))
+
+	sub := NewRouter()
+	sub.RegisterRoute(GET, "/{name:.+}", handler("sub "))
+	router.Mount("/sub", sub, StripPrefix())
+
+	tests := []struct {
+		name     string
+		target   string
+		status   int
+		body     string
+		location string
+	}{
+		{"encoded slash fails the default class", "/files/a%2F..%2Fsecret", http.StatusNotFound, "404 - Page not found", ""},
+		{"encoded slash does not separate static segments", "/files%2Fsecret", http.StatusNotFound, "404 - Page not found", ""},
+		{"encoded slash allowed by the constraint", "/repos/org%2Fproject", http.StatusOK, "repo org/project,,", ""},
+		{"encoded slash in a catch-all", "/static/a%2Fb/c", http.StatusOK, "static ,,a/b/c", ""},
+		{"encoded slash kept by mounts", "/sub/a%2Fb", http.StatusOK, "sub a/b,,", ""},
+		{"mounted segment", "/sub/a%20b", http.StatusOK, "sub a b,,", ""},
+		{"encoded dot segments are cleaned", "/files/%2E%2E/secret", http.StatusMovedPermanently, "", "/secret"},
+		{"lowercase encoded dot segments are cleaned", "/files/%2e%2e", http.StatusMovedPermanently, "", "/"},
+		{"encoded dots inside a segment", "/files/%2E%2Ehidden", http.StatusOK, "file ..hidden,,", ""},
+		{"double encoded slash is decoded once", "/files/%252F", http.StatusOK, "file %2F,,", ""},
+		{"double encoded dots are not a dot segment", "/files/%252E%252E", http.StatusOK, "file %2E%2E,,", ""},
+		{"encoded literal matches the static route", "/files/%73ecret", http.StatusOK, "files secret,,", ""},
+		{"constraints run on the decoded segment", "/ids/%31%32", http.StatusOK, "id ,12,", ""},
+		{"encoded slash fails a constraint", "/ids/1%2F2", http.StatusNotFound, "404 - Page not found", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.target, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d %q", tt.status, rw.Code, rw.Body.String())
+			}
+			if tt.location != "" && rw.Header().Get("Location") != tt.location {
+				t.Errorf("expected a redirect to %q, got %q", tt.location, rw.Header().Get("Location"))
+			}
+			if tt.location == "" && rw.Body.String() != tt.body {
+				t.Errorf("expected %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package yagaw
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const defaultParamPattern = "([^/]+)"
+const catchAllPattern = "(.*)"
+
+// Named constraints usable in place of a raw regex, e.g. `{id:int}`
+var ParamTypes = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"alpha": `[a-zA-Z]+`,
+}
+
+var constraintNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+var ErrMalformedPattern = errors.New("malformed route pattern")
+
+// ----------- ROUTE PATH PARSING -----------
+type parsedRoute struct {
+	segments      []routeSegment
+	pattern       *regexp.Regexp
+	paramList     map[int]string
+	paramNames    []string
+	paramTypes    map[string]string
+	paramPatterns map[string]*regexp.Regexp
+	paramPattern  string
+}
+
+// parseRoutePath splits the registered path in tree segments, literal parts are quoted so
+// that only the parameter segments are treated as patterns. Parameters without constraint
+// use the given pattern, the default one when empty.
+func parseRoutePath(path string, caseSensitive bool, paramPattern string) (*parsedRoute, error) {
+	if err := validateRoutePath(path); err != nil {
+		return nil, err
+	}
+
+	flags := "(?i)"
+	if caseSensitive {
+		flags = ""
+	}
+
+	parsed := &parsedRoute{paramList: map[int]string{}, paramTypes: map[string]string{}, paramPatterns: map[string]*regexp.Regexp{}, paramPattern: paramPattern}
+	literals := strings.Split(path, "/")
+	patternParts := make([]string, 0, len(literals))
+
+	for i, literal := range literals {
+		segment, source, names, err := parseSegment(literal, i == len(literals)-1, flags, parsed)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			parsed.paramList[i-1] = name
+			parsed.paramNames = append(parsed.paramNames, name)
+		}
+		segment.caseSensitive = caseSensitive
+		parsed.segments = append(parsed.segments, segment)
+		patternParts = append(patternParts, source)
+	}
+
+	pattern, err := regexp.Compile(flags + "^" + strings.Join(patternParts, "/") + "$")
+	if err != nil {
+		return nil, err
+	}
+	parsed.pattern = pattern
+
+	return parsed, nil
+}
+
+// expandOptionalParam returns the paths to register for the given one, a path ending with
+// an optional parameter like `/articles/{year}/{month?}` is registered with and without it.
+// The optional parameter may also be a `.` suffix of the final segment, as the format in
+// `/reports/{id}.{format?}`.
+func expandOptionalParam(path string) ([]string, error) {
+	if err := validateRoutePath(path); err != nil {
+		return nil, err
+	}
+
+	for cursor := 0; ; {
+		open := strings.IndexByte(path[cursor:], '{')
+		if open < 0 {
+			return []string{path}, nil
+		}
+		open += cursor
+		end := closingBrace(path, open)
+		cursor = end + 1
+
+		name, constraint, hasConstraint := strings.Cut(path[open+1:end], ":")
+		name, isOptional := strings.CutSuffix(name, "?")
+		if !isOptional {
+			continue
+		}
+		isSegment := open > 0 && path[open-1] == '/'
+		isSuffix := open > 1 && path[open-1] == '.' && path[open-2] != '/'
+		if !isSegment && !isSuffix || end != len(path)-1 {
+			return nil, fmt.Errorf("optional parameter `%s` must be the whole final segment or its `.` suffix", name)
+		}
+
+		param := name
+		if hasConstraint {
+			param += ":" + constraint
+		}
+		withParam := path[:open] + "{" + param + "}"
+		withoutParam := path[:open-1]
+		if withoutParam == "" {
+			withoutParam = "/"
+		}
+		return []string{withParam, withoutParam}, nil
+	}
+}
+
+// validateRoutePath checks the braces of the path are balanced, every parameter has a name
+// and stays inside its own segment, errors point at the byte offset of the problem.
+func validateRoutePath(path string) error {
+	depth := 0
+	open := 0
+	inName := false
+
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '{':
+			if depth == 0 {
+				open = i
+				inName = true
+			} else if inName {
+				return fmt.Errorf("%w: nested `{` at offset %d", ErrMalformedPattern, i)
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				return fmt.Errorf("%w: unexpected `}` at offset %d", ErrMalformedPattern, i)
+			}
+			if inName && (i == open+1 || path[open+1:i] == "*") {
+				return fmt.Errorf("%w: empty parameter name at offset %d", ErrMalformedPattern, open)
+			}
+			depth--
+			if depth == 0 {
+				inName = false
+			}
+		case ':':
+			if inName && i == open+1 {
+				return fmt.Errorf("%w: empty parameter name at offset %d", ErrMalformedPattern, open)
+			}
+			inName = false
+		case '/':
+			if depth > 0 {
+				return fmt.Errorf("%w: parameter opened at offset %d spans a `/`", ErrMalformedPattern, open)
+			}
+		}
+	}
+
+	if depth > 0 {
+		return fmt.Errorf("%w: unclosed `{` at offset %d", ErrMalformedPattern, open)
+	}
+	return nil
+}
+
+func parseSegment(literal string, last bool, flags string, parsed *parsedRoute) (routeSegment, string, []string, error) {
+	sourceBuilder := strings.Builder{}
+	names := []string{}
+	captures := []int{}
+	groups := 0
+	cursor := 0
+	constrained := false
+	literalLen := 0
+
+	for {
+		open := strings.IndexByte(literal[cursor:], '{')
+		if open < 0 {
+			break
+		}
+		open += cursor
+		end := closingBrace(literal, open)
+		if end < 0 {
+			break
+		}
+		name := literal[open+1 : end]
+
+		// Catch-all parameters consume the rest of the path, slashes included
+		if catchAllName, isCatchAll := strings.CutPrefix(name, "*"); isCatchAll {
+			if open != 0 || end != len(literal)-1 || !last {
+				return routeSegment{}, "", nil, fmt.Errorf("catch-all parameter `%s` must be the whole final segment", name)
+			}
+			return routeSegment{catchAll: true}, catchAllPattern, []string{catchAllName}, nil
+		}
+
+		// An optional `:regex` suffix replaces the default parameter pattern
+		paramPattern := defaultParamPattern
+		innerGroups := 0
+		paramName, constraint, hasConstraint := strings.Cut(name, ":")
+		if !hasConstraint && parsed.paramPattern != "" {
+			defaultPattern, err := regexp.Compile(parsed.paramPattern)
+			if err != nil {
+				return routeSegment{}, "", nil, fmt.Errorf("invalid default parameter pattern: %w", err)
+			}
+			paramPattern = "(" + parsed.paramPattern + ")"
+			innerGroups = defaultPattern.NumSubexp()
+		}
+		if hasConstraint {
+			// Constraints looking like a name must be a known parameter type
+			if constraintNameRegex.MatchString(constraint) {
+				typePattern, known := ParamTypes[constraint]
+				if !known {
+					return routeSegment{}, "", nil, fmt.Errorf("unknown constraint `%s` for parameter `%s`", constraint, paramName)
+				}
+				parsed.paramTypes[paramName] = constraint
+				constraint = typePattern
+			}
+
+			constraintPattern, err := regexp.Compile(constraint)
+			if err != nil {
+				return routeSegment{}, "", nil, fmt.Errorf("invalid constraint for parameter `%s`: %w", paramName, err)
+			}
+			name = paramName
+			paramPattern = "(" + constraint + ")"
+			innerGroups = constraintPattern.NumSubexp()
+			constrained = true
+		}
+
+		// Values given to Router.URL are checked against the parameter pattern alone
+		valuePattern, err := regexp.Compile(flags + "^" + paramPattern + "$")
+		if err != nil {
+			return routeSegment{}, "", nil, err
+		}
+		parsed.paramPatterns[name] = valuePattern
+
+		sourceBuilder.WriteString(regexp.QuoteMeta(literal[cursor:open]))
+		sourceBuilder.WriteString(paramPattern)
+		literalLen += open - cursor
+		captures = append(captures, groups+1)
+		groups += 1 + innerGroups
+		names = append(names, name)
+		cursor = end + 1
+	}
+
+	if len(names) == 0 {
+		return routeSegment{literal: literal}, regexp.QuoteMeta(literal), nil, nil
+	}
+
+	sourceBuilder.WriteString(regexp.QuoteMeta(literal[cursor:]))
+	literalLen += len(literal) - cursor
+	source := sourceBuilder.String()
+	pattern, err := regexp.Compile(flags + "^" + source + "$")
+	if err != nil {
+		return routeSegment{}, "", nil, err
+	}
+
+	return routeSegment{pattern: pattern, captures: captures, literalLen: literalLen, constrained: constrained}, source, names, nil
+}
+
+// closingBrace returns the index of the brace closing the one at open, braces nested
+// inside a constraint like `{id:[0-9]{3}}` are skipped.
+func closingBrace(literal string, open int) int {
+	depth := 0
+	for i := open; i < len(literal); i++ {
+		switch literal[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
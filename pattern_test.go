@@ -0,0 +1,346 @@
+package yagaw
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMalformedRoutePatterns(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	tests := []struct {
+		path    string
+		message string
+	}{
+		{"/users/{id", "unclosed `{` at offset 7"},
+		{"/users/{id}/posts/{postId", "unclosed `{` at offset 18"},
+		{"/users/{}", "empty parameter name at offset 7"},
+		{"/users/{:int}", "empty parameter name at offset 7"},
+		{"/static/{*}", "empty parameter name at offset 8"},
+		{"/users/}{", "unexpected `}` at offset 7"},
+		{"/users/id}", "unexpected `}` at offset 9"},
+		{"/users/{a{b}}", "nested `{` at offset 9"},
+		{"/users/{id/posts}", "parameter opened at offset 7 spans a `/`"},
+		{"/files/{path:[a-z/]+}", "parameter opened at offset 7 spans a `/`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			err := router.RegisterRoute(GET, tt.path, handler).Err()
+			if !errors.Is(err, ErrMalformedPattern) {
+				t.Fatalf("expected ErrMalformedPattern, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error to contain %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+
+	if len(*router.RegisteredRoutes()) != 0 {
+		t.Error("malformed routes should not be registered")
+	}
+}
+
+func TestValidRoutePatternsStillRegister(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(joinParams(req, "a", "b"))
+	}
+
+	tests := []struct {
+		path     string
+		request  string
+		expected string
+	}{
+		{"/plain", "/plain", ","},
+		{"/one/{a}", "/one/x", "x,"},
+		{"/two/{a}/{b}", "/two/x/y", "x,y"},
+		{"/inside/{a}-{b}.json", "/inside/x-y.json", "x,y"},
+		{"/regex/{a:[0-9]{3}}", "/regex/123", "123,"},
+		{"/typed/{a:int}", "/typed/42", "42,"},
+		{"/rest/{*a}", "/rest/x/y/z", "x/y/z,"},
+	}
+
+	for _, tt := range tests {
+		if err := router.RegisterRoute(GET, tt.path, handler).Err(); err != nil {
+			t.Fatalf("unexpected error registering %q: %v", tt.path, err)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.request, nil))
+
+			if rw.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rw.Code)
+			}
+			if rw.Body.String() != tt.expected {
+				t.Errorf("expected params %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestMustRegisterRoute(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router := NewRouter()
+	router.MustRegisterRoute(GET, "/users/{id}", handler)
+	if len(*router.RegisteredRoutes()) != 1 {
+		t.Error("expected the valid route to be registered")
+	}
+
+	defer func() {
+		err, isError := recover().(error)
+		if !isError || !errors.Is(err, ErrMalformedPattern) {
+			t.Errorf("expected a panic with ErrMalformedPattern, got %v", err)
+		}
+	}()
+	router.MustRegisterRoute(GET, "/users/{id", handler)
+}
+
+func TestOptionalPathParameters(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		month, found := LookupPathParam(req, "month")
+		if !found {
+			month = "absent"
+		}
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "year") + "," + month + "," + PathParam(req, "lang"))
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/articles/{year}/{month?}", handler).Name("articles")
+	router.RegisterRoute(GET, "/{lang?:alpha}", handler).Name("home")
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/articles/2024/05", http.StatusOK, "2024,05,"},
+		{"/articles/2024", http.StatusOK, "2024,absent,"},
+		{"/articles/2024/", http.StatusNotFound, "404 - Page not found"},
+		{"/articles/2024/05/01", http.StatusNotFound, "404 - Page not found"},
+		{"/en", http.StatusOK, ",absent,en"},
+		{"/", http.StatusOK, ",absent,"},
+		{"/42", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if rw.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("trailing slash redirect", func(t *testing.T) {
+		router.RedirectTrailingSlash(true)
+		defer router.RedirectTrailingSlash(false)
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/articles/2024/", nil))
+		if rw.Code != http.StatusMovedPermanently || rw.Header().Get("Location") != "/articles/2024" {
+			t.Errorf("expected a redirect to /articles/2024, got %d %q", rw.Code, rw.Header().Get("Location"))
+		}
+	})
+
+	t.Run("listed once", func(t *testing.T) {
+		routes := router.Routes()
+		if len(routes) != 2 || routes[0].Path != "/articles/{year}/{month?}" {
+			t.Errorf("expected the optional routes to be listed once, got %v", routes)
+		}
+	})
+
+	t.Run("url", func(t *testing.T) {
+		for _, tt := range []struct {
+			name     string
+			params   map[string]string
+			expected string
+		}{
+			{"articles", map[string]string{"year": "2024", "month": "05"}, "/articles/2024/05"},
+			{"articles", map[string]string{"year": "2024"}, "/articles/2024"},
+			{"home", nil, "/"},
+			{"home", map[string]string{"lang": "en"}, "/en"},
+		} {
+			if url, err := router.URL(tt.name, tt.params); err != nil || url != tt.expected {
+				t.Errorf("expected %q, got %q (%v)", tt.expected, url, err)
+			}
+		}
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		if !router.UnregisterRoute(GET, "/articles/{y}/{m?}") {
+			t.Fatal("expected the optional route to be removed")
+		}
+		for _, path := range []string{"/articles/2024/05", "/articles/2024"} {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+			if rw.Code != http.StatusNotFound {
+				t.Errorf("expected 404 for %s, got %d", path, rw.Code)
+			}
+		}
+	})
+}
+
+func TestOptionalPathParametersMustBeFinalSegment(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router := NewRouter()
+	for _, path := range []string{"/articles/{year?}/{month}", "/articles/v{version?}", "/files/{name?}.json", "{lang?}"} {
+		t.Run(path, func(t *testing.T) {
+			if err := router.RegisterRoute(GET, path, handler).Err(); err == nil {
+				t.Errorf("expected an error registering %q", path)
+			}
+		})
+	}
+	if len(router.Routes()) != 0 {
+		t.Error("invalid routes should not be registered")
+	}
+}
+
+func TestOptionalFormatSuffix(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		format, found := LookupPathParam(req, "format")
+		if !found {
+			format = "absent"
+		}
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "id") + "," + format)
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/reports/{id}.{format?}", handler).Name("report")
+	router.RegisterRoute(GET, "/exports/{id:int}.{format?:json|csv}", handler)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/reports/42.json", http.StatusOK, "42,json"},
+		{"/reports/42.csv", http.StatusOK, "42,csv"},
+		{"/reports/42", http.StatusOK, "42,absent"},
+		{"/reports/2024.05.csv", http.StatusOK, "2024.05,csv"},
+		{"/exports/7.csv", http.StatusOK, "7,csv"},
+		{"/exports/7", http.StatusOK, "7,absent"},
+		{"/exports/7.xml", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		params   map[string]string
+		expected string
+	}{
+		{map[string]string{"id": "42", "format": "json"}, "/reports/42.json"},
+		{map[string]string{"id": "42"}, "/reports/42"},
+	} {
+		if url, err := router.URL("report", tt.params); err != nil || url != tt.expected {
+			t.Errorf("expected %q, got %q (%v)", tt.expected, url, err)
+		}
+	}
+
+	for _, path := range []string{"/reports/.{format?}", "/reports/{id}.{format?}.gz"} {
+		if err := router.RegisterRoute(GET, path, handler).Err(); err == nil {
+			t.Errorf("expected an error registering %q", path)
+		}
+	}
+}
+
+func TestDefaultParamPattern(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "name") + "@" + PathParam(req, "version") + PathParam(req, "slug"))
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/pkg/{name}/{version}", handler)
+	router.RegisterRoute(GET, "/users/{name}", handler)
+
+	router.DefaultParamPattern(`[a-z0-9-]+`)
+	router.RegisterRoute(GET, "/slugs/{slug}", handler)
+	router.RegisterRoute(GET, "/tags/{name}/{version:[0-9.]+}", handler)
+
+	router.DefaultParamPattern(`[\p{L}0-9-]+`)
+	router.RegisterRoute(GET, "/articles/{slug}", handler)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/pkg/yagaw/1.2.3", http.StatusOK, "yagaw@1.2.3"},
+		{"/pkg/go~tools/v0.1.0-rc.1", http.StatusOK, "go~tools@v0.1.0-rc.1"},
+		{"/users/jane.doe@example.com", http.StatusOK, "jane.doe@example.com@"},
+		{"/slugs/hello-world", http.StatusOK, "@hello-world"},
+		{"/slugs/hello.world", http.StatusNotFound, "404 - Page not found"},
+		{"/tags/yagaw/1.2.3", http.StatusOK, "yagaw@1.2.3"},
+		{"/tags/ya.gaw/1.2.3", http.StatusNotFound, "404 - Page not found"},
+		{"/articles/caf%C3%A9-cr%C3%A8me", http.StatusOK, "@café-crème"},
+		{"/articles/a.b", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("url", func(t *testing.T) {
+		router.RegisterRoute(GET, "/langs/{slug}", handler).Name("lang")
+		if _, err := router.URL("lang", map[string]string{"slug": "a.b"}); err == nil {
+			t.Error("expected the value to be checked against the default pattern")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		router.DefaultParamPattern(`[a-z`)
+		defer router.DefaultParamPattern("")
+		if err := router.RegisterRoute(GET, "/broken/{id}", handler).Err(); err == nil || !strings.Contains(err.Error(), "invalid default parameter pattern") {
+			t.Errorf("expected an invalid default pattern error, got %v", err)
+		}
+		if err := router.RegisterRoute(GET, "/fine/{id:int}", handler).Err(); err != nil {
+			t.Errorf("expected constrained parameters to ignore the default pattern, got %v", err)
+		}
+	})
+
+	t.Run("unregister", func(t *testing.T) {
+		for _, path := range []string{"/pkg/{a}/{b}", "/slugs/{s}", "/articles/{s}"} {
+			if !router.UnregisterRoute(GET, path) {
+				t.Errorf("expected %s to be removed", path)
+			}
+		}
+	})
+}
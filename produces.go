@@ -0,0 +1,158 @@
+package yagaw
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type negotiatedTypeKey struct{}
+
+// Produces declares the response types of the route, in order of preference, overriding
+// the router default. The type is negotiated with the Accept header, q-values and wildcards
+// included, requests accepting none of them get a 406. No types disables the negotiation.
+func (rt *Route) Produces(types ...string) *Route {
+	if rt.err != nil {
+		return rt
+	}
+	produces := make([]string, 0, len(types))
+	for _, contentType := range types {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || strings.Contains(mediaType, "*") {
+			rt.err = fmt.Errorf("invalid media type `%s`", contentType)
+			return rt
+		}
+		produces = append(produces, mediaType)
+	}
+
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+	for _, handlerPackage := range rt.handlerPackages {
+		handlerPackage.produces = produces
+	}
+	return rt
+}
+
+// Produces sets the response types of the routes without their own, invalid media types
+// are ignored.
+func (r *Router) Produces(types ...string) *Router {
+	produces := []string{}
+	for _, contentType := range types {
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && !strings.Contains(mediaType, "*") {
+			produces = append(produces, mediaType)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.produces = produces
+	return r
+}
+
+// SetNotAcceptableHandler replaces the handler used when the Accept header matches none of
+// the route types, a nil handler restores the default plain text 406.
+func (r *Router) SetNotAcceptableHandler(handler HttpRequestHandler) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notAcceptable = handler
+	return r
+}
+
+// NegotiatedType returns the response type negotiated for a route declaring Produces, or
+// an empty string.
+func NegotiatedType(req *http.Request) string {
+	negotiated, _ := req.Context().Value(negotiatedTypeKey{}).(string)
+	return negotiated
+}
+
+func withNegotiatedType(req *http.Request, negotiated string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), negotiatedTypeKey{}, negotiated))
+}
+
+func (r *Router) routeProduces(handlerPackage *RequestHandlerPackage) []string {
+	if handlerPackage.produces != nil {
+		return handlerPackage.produces
+	}
+	return r.produces
+}
+
+// negotiate picks the response type of the match, it is replaced with the 406 handler when
+// the request accepts none of the route types.
+func (r *Router) negotiate(req *http.Request, match routeMatch) routeMatch {
+	if len(match.produces) == 0 {
+		return match
+	}
+	if match.negotiated = negotiateType(req.Header.Values("Accept"), match.produces); match.negotiated != "" {
+		return match
+	}
+	return routeMatch{handlerPackage: &RequestHandlerPackage{Handler: handlerOr(r.notAcceptable, notAcceptableHandler)}}
+}
+
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// negotiateType returns the produced type with the highest quality, the quality of a type
+// being the one of the most specific range matching it. Ties go to the first produced type,
+// without an Accept header everything is accepted.
+func negotiateType(accept []string, produces []string) string {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return produces[0]
+	}
+
+	best, bestQuality := "", 0.0
+	for _, produced := range produces {
+		quality, specificity := 0.0, -1
+		for _, accepted := range ranges {
+			if rangeSpecificity := matchMediaRange(accepted.mediaType, produced); rangeSpecificity > specificity {
+				quality, specificity = accepted.quality, rangeSpecificity
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = produced, quality
+		}
+	}
+	return best
+}
+
+// matchMediaRange returns how specific the range matching the type is, -1 when it doesn't
+func matchMediaRange(mediaRange string, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
+
+func parseAccept(accept []string) []mediaRange {
+	ranges := []mediaRange{}
+	for _, header := range accept {
+		for _, part := range strings.Split(header, ",") {
+			part = strings.TrimSpace(part)
+			if part == "*" {
+				part = "*/*"
+			}
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			quality := 1.0
+			if q, found := params["q"]; found {
+				if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+					quality = parsed
+				}
+			}
+			ranges = append(ranges, mediaRange{mediaType, quality})
+		}
+	}
+	return ranges
+}
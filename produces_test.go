@@ -0,0 +1,94 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteProduces(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(NegotiatedType(req))
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/users", handler).Produces("application/json", "application/xml", "text/csv")
+	router.RegisterRoute(GET, "/report", handler).Produces("text/html")
+	router.RegisterRoute(GET, "/plain", handler)
+
+	tests := []struct {
+		path     string
+		accept   string
+		status   int
+		expected string
+	}{
+		{"/users", "", http.StatusOK, "application/json"},
+		{"/users", "application/xml", http.StatusOK, "application/xml"},
+		{"/users", "*/*", http.StatusOK, "application/json"},
+		{"/users", "*", http.StatusOK, "application/json"},
+		{"/users", "text/*", http.StatusOK, "text/csv"},
+		{"/users", "application/json;q=0.5, application/xml;q=0.9", http.StatusOK, "application/xml"},
+		{"/users", "application/json;q=0.5, text/csv", http.StatusOK, "text/csv"},
+		{"/users", "application/*;q=0.8, application/xml;q=0.2", http.StatusOK, "application/json"},
+		{"/users", "*/*;q=0.1, application/json;q=0", http.StatusOK, "application/xml"},
+		{"/users", "application/json;q=0, application/xml;q=0, text/csv;q=0", http.StatusNotAcceptable, "406 - Not acceptable"},
+		{"/users", "image/png", http.StatusNotAcceptable, "406 - Not acceptable"},
+		{"/report", "text/html; charset=utf-8", http.StatusOK, "text/html"},
+		{"/report", "application/json, text/*;q=0.3", http.StatusOK, "text/html"},
+		{"/plain", "image/png", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(string(GET), tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if rw.Code != tt.status || rw.Body.String() != tt.expected {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.expected, rw.Code, rw.Body.String())
+			}
+		})
+	}
+
+	if err := router.RegisterRoute(GET, "/any", handler).Produces("application/*").Err(); err == nil {
+		t.Error("expected an error for a wildcard produced type")
+	}
+}
+
+func TestRouterProducesDefault(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(NegotiatedType(req))
+	}
+
+	router := NewRouter().Produces("application/json")
+	router.RegisterRoute(GET, "/users", handler)
+	router.RegisterRoute(GET, "/export", handler).Produces("text/csv")
+	router.RegisterRoute(GET, "/files", handler).Produces()
+	router.SetNotAcceptableHandler(func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusNotAcceptable).SetBody("custom 406")
+	})
+
+	tests := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{"/users", http.StatusNotAcceptable, "custom 406"},
+		{"/export", http.StatusOK, "text/csv"},
+		{"/files", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(string(GET), tt.path, nil)
+		req.Header.Set("Accept", "text/csv")
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+
+		if rw.Code != tt.status || rw.Body.String() != tt.expected {
+			t.Errorf("expected %d %q for %s, got %d %q", tt.status, tt.expected, tt.path, rw.Code, rw.Body.String())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package yagaw
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// ----------- TRUSTED PROXIES -----------
+
+type clientIPKey struct{}
+
+type proxySettings struct {
+	trusted []netip.Prefix
+	rewrite bool
+}
+
+// SetTrustedProxies sets the proxies, as CIDRs like `10.0.0.0/8` or single addresses, whose
+// X-Forwarded-For and X-Real-IP headers are believed when resolving the client address.
+// Headers sent by other peers are ignored, and no proxy is trusted unless set.
+func (s *Server) SetTrustedProxies(cidrs ...string) error {
+	trusted := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := parseProxy(cidr)
+		if err != nil {
+			return err
+		}
+		trusted = append(trusted, prefix)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.proxies.trusted = trusted
+	s.composeHandler()
+	return nil
+}
+
+// RewriteRemoteAddr makes the server replace the RemoteAddr of the requests coming through
+// a trusted proxy with the resolved client address, for middleware reading it directly.
+func (s *Server) RewriteRemoteAddr(enable bool) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.proxies.rewrite = enable
+	s.composeHandler()
+	return s
+}
+
+// ClientIP returns the address of the client, resolved through the trusted proxies when the
+// request was served by a server having some, the host of RemoteAddr otherwise.
+func ClientIP(req *http.Request) string {
+	if clientIP, found := req.Context().Value(clientIPKey{}).(string); found {
+		return clientIP
+	}
+	return remoteHost(req.RemoteAddr)
+}
+
+func parseProxy(cidr string) (netip.Prefix, error) {
+	if strings.Contains(cidr, "/") {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid trusted proxy `%s`: %w", cidr, err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(cidr)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy `%s`: %w", cidr, err)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// proxyHandler stores the resolved client address in the request context, the requests of
+// servers without trusted proxies are passed along untouched.
+func (settings proxySettings) proxyHandler(next http.Handler) http.Handler {
+	if len(settings.trusted) == 0 {
+		return next
+	}
+	trusted, rewrite := slices.Clone(settings.trusted), settings.rewrite
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		clientIP, proxied := resolveClientIP(req, trusted)
+		req = req.WithContext(context.WithValue(req.Context(), clientIPKey{}, clientIP))
+		if rewrite && proxied {
+			req.RemoteAddr = net.JoinHostPort(clientIP, "0")
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// resolveClientIP walks X-Forwarded-For from the peer, the nearest hop, to the client: the
+// first hop not trusted is the client. Without the header X-Real-IP is used, and the peer
+// address is returned when it isn't a trusted proxy.
+func resolveClientIP(req *http.Request, trusted []netip.Prefix) (string, bool) {
+	peer, valid := parseHop(req.RemoteAddr)
+	if !valid || !isTrusted(peer, trusted) {
+		return remoteHost(req.RemoteAddr), false
+	}
+
+	hops := []string{}
+	for _, header := range req.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		if realIP, valid := parseHop(req.Header.Get("X-Real-IP")); valid {
+			return realIP.String(), true
+		}
+		return peer.String(), false
+	}
+
+	client := peer
+	for _, hop := range slices.Backward(hops) {
+		addr, valid := parseHop(hop)
+		// A malformed hop was not added by a trusted proxy, the one after it is the client
+		if !valid {
+			break
+		}
+		client = addr
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+	return client.String(), client != peer
+}
+
+// parseHop parses an address as found in RemoteAddr and the forwarding headers, with or
+// without a port and IPv6 brackets.
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.TrimSpace(hop)
+	if addrPort, err := netip.ParseAddrPort(hop); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	return slices.ContainsFunc(trusted, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+}
+
+func remoteHost(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
@@ -0,0 +1,88 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{"no proxy", nil, "203.0.113.7:5555", nil, "203.0.113.7"},
+		{"spoofed by an untrusted peer", []string{"10.0.0.0/8"}, "203.0.113.7:5555", map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"}, "203.0.113.7"},
+		{"one trusted hop", []string{"10.0.0.0/8"}, "10.0.0.2:5555", map[string]string{"X-Forwarded-For": "203.0.113.7"}, "203.0.113.7"},
+		{"spoofed behind a trusted hop", []string{"10.0.0.0/8"}, "10.0.0.2:5555", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"chained proxies", []string{"10.0.0.0/8", "192.168.1.1"}, "10.0.0.2:5555", map[string]string{"X-Forwarded-For": "203.0.113.7, 192.168.1.1,10.1.2.3"}, "203.0.113.7"},
+		{"every hop trusted", []string{"10.0.0.0/8"}, "10.0.0.2:5555", map[string]string{"X-Forwarded-For": "10.0.0.9, 10.0.0.8"}, "10.0.0.9"},
+		{"malformed hop", []string{"10.0.0.0/8"}, "10.0.0.2:5555", map[string]string{"X-Forwarded-For": "203.0.113.7, garbage, 10.0.0.3"}, "10.0.0.3"},
+		{"real IP", []string{"10.0.0.0/8"}, "10.0.0.2:5555", map[string]string{"X-Real-IP": "203.0.113.7"}, "203.0.113.7"},
+		{"trusted peer without headers", []string{"10.0.0.0/8"}, "10.0.0.2:5555", nil, "10.0.0.2"},
+		{"IPv6 peer and client", []string{"2001:db8::/32"}, "[2001:db8::1]:5555", map[string]string{"X-Forwarded-For": "2001:db8:ffff::2, 2001:db8::3"}, "2001:db8:ffff::2"},
+		{"IPv6 client with port", []string{"10.0.0.0/8"}, "10.0.0.2:5555", map[string]string{"X-Forwarded-For": "[2001:db8::7]:443"}, "2001:db8::7"},
+		{"IPv4 mapped peer", []string{"10.0.0.0/8"}, "[::ffff:10.0.0.2]:5555", map[string]string{"X-Forwarded-For": "203.0.113.7"}, "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer("127.0.0.1", 0)
+			if err := server.SetTrustedProxies(tt.trusted...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			server.GetRouter().RegisterRoute(GET, "/whoami", func(req *http.Request, params Params) *HttpResponse {
+				return NewHttpResponse(http.StatusOK).SetBody(ClientIP(req))
+			})
+			req := httptest.NewRequest(string(GET), "/whoami", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			rw := httptest.NewRecorder()
+			server.serveHTTP(rw, req)
+
+			if rw.Body.String() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetTrustedProxies(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		err := NewServer("127.0.0.1", 0).SetTrustedProxies("10.0.0.0/8", "10.0.0.256/8")
+		if err == nil || !strings.Contains(err.Error(), "`10.0.0.256/8`") {
+			t.Errorf("expected the invalid proxy to be reported, got %v", err)
+		}
+	})
+
+	t.Run("remote address rewritten", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0).RewriteRemoteAddr(true)
+		if err := server.SetTrustedProxies("10.0.0.0/8"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		server.GetRouter().RegisterRoute(GET, "/whoami", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(req.RemoteAddr)
+		})
+		serve := func(remoteAddr string) string {
+			req := httptest.NewRequest(string(GET), "/whoami", nil)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-For", "2001:db8::7")
+			rw := httptest.NewRecorder()
+			server.serveHTTP(rw, req)
+			return rw.Body.String()
+		}
+
+		if remoteAddr := serve("10.0.0.2:5555"); remoteAddr != "[2001:db8::7]:0" {
+			t.Errorf("expected the client address, got %s", remoteAddr)
+		}
+		if remoteAddr := serve("203.0.113.7:5555"); remoteAddr != "203.0.113.7:5555" {
+			t.Errorf("expected untrusted peers to be kept, got %s", remoteAddr)
+		}
+	})
+}
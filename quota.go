@@ -0,0 +1,211 @@
+package yagaw
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ----------- ROUTE QUOTAS -----------
+
+// QuotaMetaKey is the route metadata key holding the quota of a route, like `100/min`
+const QuotaMetaKey = "rate"
+
+// DefaultQuotaKeyHeader is the header holding the API key of the clients
+const DefaultQuotaKeyHeader = "X-Api-Key"
+
+// QuotaStore counts the requests of every key in fixed windows, an implementation backed
+// by Redis shares the quotas between instances.
+type QuotaStore interface {
+	// Increment counts a request of the key in its current window of the given length and
+	// returns the requests counted so far in the window, this one included, and the time
+	// left until it ends, by the clock of the store.
+	Increment(ctx context.Context, key string, window time.Duration) (count int64, resetIn time.Duration, err error)
+}
+
+// QuotaOptions configures the middleware returned by Quota
+type QuotaOptions struct {
+	// Store counts the requests, a MemoryQuotaStore unless set
+	Store QuotaStore
+	// Key tells apart the clients, the DefaultQuotaKeyHeader header then ClientIP unless set
+	Key func(req *http.Request) string
+	// DefaultRate is the quota of the routes without one, like `1000/hour`, none unless set
+	DefaultRate string
+}
+
+type quotaRate struct {
+	limit  int64
+	window time.Duration
+}
+
+// Quota returns router middleware enforcing per route quotas per client, the quota of a
+// route being set with `Meta(QuotaMetaKey, "100/min")`: a count, a slash and a window
+// like `s`, `min`, `hour`, `day` or a duration like `10m`. Responses carry the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers, the latter in
+// seconds, and requests over the quota get a 429 with Retry-After. Counting is done in
+// fixed windows by the store, requests are let through when it fails or a quota is invalid.
+func Quota(options QuotaOptions) Middleware {
+	store, key := options.Store, options.Key
+	if store == nil {
+		store = NewMemoryQuotaStore()
+	}
+	if key == nil {
+		key = apiKeyOrClientIP
+	}
+	rates := sync.Map{}
+	parse := func(rate string) (quotaRate, error) {
+		if parsed, found := rates.Load(rate); found {
+			return parsed.(quotaRate), nil
+		}
+		parsed, err := parseQuotaRate(rate)
+		if err == nil {
+			rates.Store(rate, parsed)
+		}
+		return parsed, err
+	}
+
+	return func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			rate := RouteMeta(req, QuotaMetaKey)
+			if rate == "" {
+				rate = options.DefaultRate
+			}
+			if rate == "" {
+				return next(req, params)
+			}
+			quota, err := parse(rate)
+			if err != nil {
+				Log.Error(fmt.Sprintf("quota of `%s %s`: %v", req.Method, req.Pattern, err))
+				return next(req, params)
+			}
+
+			// unmatched requests have no pattern, each path counts on its own
+			route := req.Pattern
+			if route == "" {
+				route = req.URL.Path
+			}
+			count, resetAfter, err := store.Increment(req.Context(), req.Method+" "+route+" "+key(req), quota.window)
+			if err != nil {
+				Log.Error(fmt.Sprintf("quota of `%s %s`: %v", req.Method, req.Pattern, err))
+				return next(req, params)
+			}
+			resetIn := strconv.Itoa(int(math.Ceil(resetAfter.Seconds())))
+
+			var response *HttpResponse
+			if count > quota.limit {
+				response = NewHttpResponse(http.StatusTooManyRequests).
+					SetHeader("Content-Type", "text/plain").
+					SetHeader("Retry-After", resetIn).
+					SetBody("429 - Too many requests")
+			} else {
+				response = next(req, params)
+			}
+			return response.
+				SetHeader("X-RateLimit-Limit", strconv.FormatInt(quota.limit, 10)).
+				SetHeader("X-RateLimit-Remaining", strconv.FormatInt(max(quota.limit-count, 0), 10)).
+				SetHeader("X-RateLimit-Reset", resetIn)
+		}
+	}
+}
+
+func apiKeyOrClientIP(req *http.Request) string {
+	if apiKey := req.Header.Get(DefaultQuotaKeyHeader); apiKey != "" {
+		return "key:" + apiKey
+	}
+	return "ip:" + ClientIP(req)
+}
+
+var quotaWindows = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hour": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour,
+}
+
+func parseQuotaRate(rate string) (quotaRate, error) {
+	count, unit, found := strings.Cut(rate, "/")
+	limit, err := strconv.ParseInt(strings.TrimSpace(count), 10, 64)
+	if !found || err != nil || limit <= 0 {
+		return quotaRate{}, fmt.Errorf("invalid quota `%s`: expected a positive count like `100/min`", rate)
+	}
+	unit = strings.TrimSpace(unit)
+	window, known := quotaWindows[unit]
+	if !known {
+		window, err = time.ParseDuration(unit)
+		if err != nil || window <= 0 {
+			return quotaRate{}, fmt.Errorf("invalid quota `%s`: unknown window `%s`", rate, unit)
+		}
+	}
+	return quotaRate{limit: limit, window: window}, nil
+}
+
+// ----------- MEMORY QUOTA STORE -----------
+
+type quotaWindow struct {
+	count int64
+	reset time.Time
+}
+
+// quotaGeneration holds the windows of one length, rotated once per window length so that
+// windows left unused for a whole length, and so over, are dropped
+type quotaGeneration struct {
+	windows  map[string]*quotaWindow
+	previous map[string]*quotaWindow
+	rotated  time.Time
+}
+
+// MemoryQuotaStore counts the requests in memory, for a single instance. Windows are
+// aligned on the clock, e.g. minutes start at second 0, and dropped once over.
+type MemoryQuotaStore struct {
+	mu          sync.Mutex
+	generations map[time.Duration]*quotaGeneration
+	now         func() time.Time
+}
+
+// NewMemoryQuotaStore creates an empty store
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{generations: map[time.Duration]*quotaGeneration{}, now: time.Now}
+}
+
+func (s *MemoryQuotaStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	generation, found := s.generations[window]
+	if !found {
+		generation = &quotaGeneration{windows: map[string]*quotaWindow{}, rotated: now}
+		s.generations[window] = generation
+	}
+	generation.rotate(now, window)
+
+	current, found := generation.windows[key]
+	if !found {
+		current, found = generation.previous[key]
+		if found {
+			delete(generation.previous, key)
+			generation.windows[key] = current
+		}
+	}
+	if !found || !now.Before(current.reset) {
+		current = &quotaWindow{reset: now.Truncate(window).Add(window)}
+		generation.windows[key] = current
+	}
+	current.count++
+	return current.count, current.reset.Sub(now), nil
+}
+
+// rotate drops the windows left unused for a whole window length, over by then, once per
+// window length
+func (g *quotaGeneration) rotate(now time.Time, window time.Duration) {
+	if now.Sub(g.rotated) < window {
+		return
+	}
+	g.rotated = now
+	g.previous, g.windows = g.windows, map[string]*quotaWindow{}
+}
@@ -0,0 +1,143 @@
+package yagaw
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pho3b/tiny-logger/logs/log_level"
+)
+
+func TestQuota(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 30, 15, 0, time.UTC)
+	store := NewMemoryQuotaStore()
+	store.now = func() time.Time { return now }
+
+	router := NewRouter().Use(Quota(QuotaOptions{Store: store}))
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("served")
+	}
+	router.RegisterRoute(GET, "/search", handler).Meta(QuotaMetaKey, "3/min")
+	router.RegisterRoute(GET, "/export", handler).Meta(QuotaMetaKey, "1/hour")
+	router.RegisterRoute(GET, "/broken", handler).Meta(QuotaMetaKey, "many")
+	router.RegisterRoute(GET, "/free", handler)
+
+	serve := func(path string, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(string(GET), path, nil)
+		req.RemoteAddr = "192.0.2.1:5555"
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, req)
+		return rw
+	}
+	resetIn := func(rw *httptest.ResponseRecorder) int {
+		seconds, _ := strconv.Atoi(rw.Header().Get("X-RateLimit-Reset"))
+		return seconds
+	}
+
+	t.Run("countdown", func(t *testing.T) {
+		for _, remaining := range []string{"2", "1", "0"} {
+			rw := serve("/search", "key-a")
+			if rw.Code != http.StatusOK || rw.Header().Get("X-RateLimit-Limit") != "3" || rw.Header().Get("X-RateLimit-Remaining") != remaining {
+				t.Errorf("expected %s remaining, got %d %v", remaining, rw.Code, rw.Header())
+			}
+			if reset := resetIn(rw); reset != 45 {
+				t.Errorf("expected the reset at the end of the minute, got %d", reset)
+			}
+		}
+
+		rw := serve("/search", "key-a")
+		if rw.Code != http.StatusTooManyRequests || rw.Header().Get("X-RateLimit-Remaining") != "0" ||
+			rw.Header().Get("Retry-After") != rw.Header().Get("X-RateLimit-Reset") {
+			t.Errorf("expected a 429 with the quota headers, got %d %v", rw.Code, rw.Header())
+		}
+	})
+
+	t.Run("per key and route", func(t *testing.T) {
+		if rw := serve("/search", "key-b"); rw.Header().Get("X-RateLimit-Remaining") != "2" {
+			t.Errorf("expected another key to have its own quota, got %v", rw.Header())
+		}
+		if rw := serve("/search", ""); rw.Header().Get("X-RateLimit-Remaining") != "2" {
+			t.Errorf("expected the client address without a key, got %v", rw.Header())
+		}
+		if rw := serve("/export", "key-a"); rw.Code != http.StatusOK || rw.Header().Get("X-RateLimit-Limit") != "1" {
+			t.Errorf("expected another route to have its own quota, got %d %v", rw.Code, rw.Header())
+		}
+	})
+
+	t.Run("routes without a valid quota", func(t *testing.T) {
+		read := captureLog(t, log_level.ErrorLvlName)
+		for _, path := range []string{"/free", "/broken"} {
+			if rw := serve(path, "key-a"); rw.Code != http.StatusOK || rw.Header().Get("X-RateLimit-Limit") != "" {
+				t.Errorf("expected %s to be served without quota headers, got %d %v", path, rw.Code, rw.Header())
+			}
+		}
+		if !strings.Contains(read(), "invalid quota `many`") {
+			t.Errorf("expected the invalid quota to be logged, got %q", read())
+		}
+	})
+
+	t.Run("reset after the window", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		rw := serve("/search", "key-a")
+		if rw.Code != http.StatusOK || rw.Header().Get("X-RateLimit-Remaining") != "2" {
+			t.Errorf("expected a new window, got %d %v", rw.Code, rw.Header())
+		}
+	})
+}
+
+func TestMemoryQuotaStoreEviction(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 30, 15, 0, time.UTC)
+	store := NewMemoryQuotaStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	store.Increment(ctx, "a", time.Minute)
+	store.Increment(ctx, "b", time.Minute)
+	store.Increment(ctx, "daily", 24*time.Hour)
+
+	// Windows unused for a whole window length are over, the next rotation drops them
+	now = now.Add(time.Minute)
+	if count, _, _ := store.Increment(ctx, "a", time.Minute); count != 1 {
+		t.Errorf("expected a new window for a, got a count of %d", count)
+	}
+	minutes := store.generations[time.Minute]
+	if len(minutes.windows) != 1 || len(minutes.previous) != 1 {
+		t.Errorf("expected the window of b to wait for the next rotation, got %v %v", minutes.windows, minutes.previous)
+	}
+
+	now = now.Add(time.Minute)
+	store.Increment(ctx, "a", time.Minute)
+	if _, found := minutes.windows["a"]; !found || len(minutes.windows) != 1 || len(minutes.previous) != 0 {
+		t.Errorf("expected the window of b to be evicted, got %v %v", minutes.windows, minutes.previous)
+	}
+	if count, _, _ := store.Increment(ctx, "daily", 24*time.Hour); count != 2 {
+		t.Errorf("expected the daily window to be kept, got a count of %d", count)
+	}
+}
+
+func TestQuotaUnmatchedRequests(t *testing.T) {
+	store := NewMemoryQuotaStore()
+	router := NewRouter().Use(Quota(QuotaOptions{Store: store, DefaultRate: "1/min"}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+		return rw
+	}
+
+	for _, path := range []string{"/missing", "/other"} {
+		if rw := serve(path); rw.Code != http.StatusNotFound || rw.Header().Get("X-RateLimit-Remaining") != "0" {
+			t.Errorf("expected %s to have its own quota, got %d %v", path, rw.Code, rw.Header())
+		}
+	}
+	if rw := serve("/missing"); rw.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the quota of /missing to be exhausted, got %d", rw.Code)
+	}
+}
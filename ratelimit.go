@@ -0,0 +1,134 @@
+package yagaw
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ----------- RATE LIMITING -----------
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps the buckets used since the last rotation apart from the ones used in the
+// period before, which are full again by the next rotation and dropped all at once.
+type rateLimiter struct {
+	mu       sync.Mutex
+	rps      float64
+	burst    float64
+	idle     time.Duration
+	buckets  map[string]*tokenBucket
+	previous map[string]*tokenBucket
+	rotated  time.Time
+	key      func(req *http.Request) string
+	denied   http.Handler
+	now      func() time.Time
+}
+
+// RateLimitOption configures the middleware returned by RateLimit
+type RateLimitOption func(limiter *rateLimiter)
+
+// RateLimitKey sets the function telling apart the clients limited, ClientIP unless set,
+// e.g. to limit by API key.
+func RateLimitKey(key func(req *http.Request) string) RateLimitOption {
+	return func(limiter *rateLimiter) {
+		limiter.key = key
+	}
+}
+
+// RateLimitDenied sets the handler answering the requests over the limit, the Retry-After
+// header being already set. A 429 is sent unless set.
+func RateLimitDenied(denied http.Handler) RateLimitOption {
+	return func(limiter *rateLimiter) {
+		limiter.denied = denied
+	}
+}
+
+// RateLimit returns server middleware limiting every client, told apart by ClientIP, to
+// rps requests per second with bursts of burst requests, as a token bucket. Requests over
+// the limit get a 429 with a Retry-After header. The bucket of a client is dropped once
+// idle long enough to be full again, at most twice the time it takes, keeping the memory
+// used bounded by the active clients without ever walking all of them. It panics unless
+// rps and burst are positive.
+func RateLimit(rps float64, burst int, opts ...RateLimitOption) func(http.Handler) http.Handler {
+	limiter := newRateLimiter(rps, burst, opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			allowed, retryAfter := limiter.allow(limiter.key(req))
+			if allowed {
+				next.ServeHTTP(rw, req)
+				return
+			}
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			limiter.denied.ServeHTTP(rw, req)
+		})
+	}
+}
+
+func newRateLimiter(rps float64, burst int, opts ...RateLimitOption) *rateLimiter {
+	if rps <= 0 || burst <= 0 {
+		panic(fmt.Errorf("invalid rate limit of %g requests per second with bursts of %d", rps, burst))
+	}
+	limiter := &rateLimiter{
+		rps:     rps,
+		burst:   float64(burst),
+		idle:    time.Duration(float64(burst) / rps * float64(time.Second)),
+		buckets: map[string]*tokenBucket{},
+		key:     ClientIP,
+		denied:  http.HandlerFunc(tooManyRequests),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(limiter)
+	}
+	return limiter
+}
+
+// allow takes a token from the bucket of the key, or returns how long until one is back
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.rotate(now)
+
+	bucket, found := l.buckets[key]
+	if !found {
+		bucket, found = l.previous[key]
+		if found {
+			delete(l.previous, key)
+		} else {
+			bucket = &tokenBucket{tokens: l.burst, last: now}
+		}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rps)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - bucket.tokens) / l.rps * float64(time.Second))
+}
+
+// rotate drops the buckets left unused for a whole idle period, full again and so the same
+// as new ones, once per idle period
+func (l *rateLimiter) rotate(now time.Time) {
+	if now.Sub(l.rotated) < l.idle {
+		return
+	}
+	l.rotated = now
+	l.previous, l.buckets = l.buckets, map[string]*tokenBucket{}
+}
+
+func tooManyRequests(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.WriteHeader(http.StatusTooManyRequests)
+	io.WriteString(rw, "429 - Too many requests")
+}
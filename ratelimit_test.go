@@ -0,0 +1,100 @@
+package yagaw
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	newServer := func(opts ...RateLimitOption) *Server {
+		server := NewServer("127.0.0.1", 0).Use(RateLimit(1, 3, opts...))
+		server.GetRouter().RegisterRoute(GET, "/users", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK)
+		})
+		return server
+	}
+	serve := func(server *Server, remoteAddr string, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(string(GET), "/users", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Api-Key", apiKey)
+		rw := httptest.NewRecorder()
+		server.serveHTTP(rw, req)
+		return rw
+	}
+
+	t.Run("per client address", func(t *testing.T) {
+		server := newServer()
+		for i := range 3 {
+			// Another port is the same client
+			if rw := serve(server, fmt.Sprintf("192.0.2.1:%d", 1000+i), ""); rw.Code != http.StatusOK {
+				t.Fatalf("expected request %d of the burst to be served, got %d", i+1, rw.Code)
+			}
+		}
+		rw := serve(server, "192.0.2.1:5555", "")
+		if rw.Code != http.StatusTooManyRequests || rw.Header().Get("Retry-After") != "1" || rw.Body.String() != "429 - Too many requests" {
+			t.Errorf("expected a 429 with Retry-After, got %d %v %q", rw.Code, rw.Header(), rw.Body.String())
+		}
+		if rw := serve(server, "192.0.2.2:5555", ""); rw.Code != http.StatusOK {
+			t.Errorf("expected another client to be served, got %d", rw.Code)
+		}
+	})
+
+	t.Run("custom key and denied handler", func(t *testing.T) {
+		server := newServer(
+			RateLimitKey(func(req *http.Request) string { return req.Header.Get("X-Api-Key") }),
+			RateLimitDenied(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				http.Error(rw, "quota exceeded", http.StatusServiceUnavailable)
+			})),
+		)
+		// Requests of a key from different addresses share its bucket
+		for i := range 3 {
+			serve(server, fmt.Sprintf("192.0.2.%d:5555", i+1), "key-a")
+		}
+		rw := serve(server, "192.0.2.9:5555", "key-a")
+		if rw.Code != http.StatusServiceUnavailable || rw.Header().Get("Retry-After") == "" {
+			t.Errorf("expected the custom denied handler, got %d %v", rw.Code, rw.Header())
+		}
+		if rw := serve(server, "192.0.2.9:5555", "key-b"); rw.Code != http.StatusOK {
+			t.Errorf("expected key-b to be served, got %d", rw.Code)
+		}
+	})
+}
+
+func TestRateLimiterRefillAndEviction(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := newRateLimiter(2, 2)
+	limiter.now = func() time.Time { return now }
+
+	for _, key := range []string{"a", "a", "b"} {
+		if allowed, _ := limiter.allow(key); !allowed {
+			t.Fatalf("expected %s to be allowed", key)
+		}
+	}
+	allowed, retryAfter := limiter.allow("a")
+	if allowed || retryAfter != 500*time.Millisecond {
+		t.Errorf("expected a to wait 500ms, got %t %s", allowed, retryAfter)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if allowed, _ := limiter.allow("a"); !allowed {
+		t.Error("expected a token to be back after 500ms")
+	}
+	if len(limiter.buckets) != 2 {
+		t.Errorf("expected 2 buckets, got %d", len(limiter.buckets))
+	}
+
+	// Buckets unused for a whole idle period are full again, the next rotation drops them
+	now = now.Add(time.Second)
+	limiter.allow("c")
+	if len(limiter.buckets) != 1 || len(limiter.previous) != 2 {
+		t.Errorf("expected the buckets of a and b to wait for the next rotation, got %v %v", limiter.buckets, limiter.previous)
+	}
+	now = now.Add(time.Second)
+	limiter.allow("c")
+	if _, found := limiter.buckets["c"]; !found || len(limiter.buckets) != 1 || len(limiter.previous) != 0 {
+		t.Errorf("expected the idle buckets to be evicted, got %v %v", limiter.buckets, limiter.previous)
+	}
+}
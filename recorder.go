@@ -0,0 +1,108 @@
+package yagaw
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ----------- STATUS RECORDER -----------
+
+// StatusRecorder wraps a ResponseWriter to record the status and the size of the response
+// written through it, for middleware measuring responses like the stats counters. The
+// optional interfaces of the wrapped writer, http.Flusher, http.Hijacker and io.ReaderFrom,
+// are passed through, and http.ResponseController reaches the others through Unwrap.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+// NewStatusRecorder wraps the ResponseWriter
+func NewStatusRecorder(rw http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: rw}
+}
+
+// Status returns the status written, 200 when the handler wrote a body without one or
+// nothing at all, as net/http answers then.
+func (w *StatusRecorder) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// BytesWritten returns the number of body bytes written
+func (w *StatusRecorder) BytesWritten() int64 {
+	return w.written
+}
+
+// HeaderWritten reports whether the final status line and headers were sent, after which
+// they can't be changed anymore.
+func (w *StatusRecorder) HeaderWritten() bool {
+	return w.status != 0
+}
+
+// WriteHeader sends the status unless one was already sent, the later calls being ignored
+// rather than reported by net/http as superfluous.
+func (w *StatusRecorder) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	// Informational responses may precede the final one
+	if status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusRecorder) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	written, err := w.ResponseWriter.Write(data)
+	w.written += int64(written)
+	return written, err
+}
+
+// ReadFrom copies the reader into the response, through the ReadFrom of the wrapped writer
+// when it has one, e.g. to let net/http use sendfile for files.
+func (w *StatusRecorder) ReadFrom(reader io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	var written int64
+	var err error
+	if readerFrom, isReaderFrom := w.ResponseWriter.(io.ReaderFrom); isReaderFrom {
+		written, err = readerFrom.ReadFrom(reader)
+	} else {
+		// Hiding ReadFrom keeps io.Copy from calling back into this method
+		written, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, reader)
+	}
+	w.written += written
+	return written, err
+}
+
+// Flush sends the buffered data to the client when the wrapped writer supports it
+func (w *StatusRecorder) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets handlers take over the connection, e.g. for WebSockets, the status is then
+// reported as 101.
+func (w *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buffer, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, buffer, err
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (w *StatusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,140 @@
+package yagaw
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStatusRecorder(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(rw http.ResponseWriter)
+		status  int
+		written int64
+	}{
+		{"nothing written", func(rw http.ResponseWriter) {}, http.StatusOK, 0},
+		{"body without status", func(rw http.ResponseWriter) { rw.Write([]byte("hello")) }, http.StatusOK, 5},
+		{"status and body", func(rw http.ResponseWriter) {
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte("404 - "))
+			rw.Write([]byte("Not found"))
+		}, http.StatusNotFound, 15},
+		{"informational first", func(rw http.ResponseWriter) {
+			rw.WriteHeader(http.StatusEarlyHints)
+			rw.WriteHeader(http.StatusAccepted)
+		}, http.StatusAccepted, 0},
+		{"flushed", func(rw http.ResponseWriter) {
+			if err := http.NewResponseController(rw).Flush(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}, http.StatusOK, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			recorder := NewStatusRecorder(rw)
+			tt.handler(recorder)
+
+			if recorder.Status() != tt.status || recorder.BytesWritten() != tt.written {
+				t.Errorf("expected %d and %d bytes, got %d and %d", tt.status, tt.written, recorder.Status(), recorder.BytesWritten())
+			}
+			if int64(rw.Body.Len()) != tt.written {
+				t.Errorf("expected the body to reach the wrapped writer, got %q", rw.Body.String())
+			}
+		})
+	}
+}
+
+// readerFromWriter counts the copies going through its ReadFrom
+type readerFromWriter struct {
+	*httptest.ResponseRecorder
+	readFrom int
+}
+
+func (w *readerFromWriter) ReadFrom(reader io.Reader) (int64, error) {
+	w.readFrom++
+	return io.Copy(w.ResponseRecorder, reader)
+}
+
+func TestStatusRecorderInterfaces(t *testing.T) {
+	t.Run("double WriteHeader", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		recorder := NewStatusRecorder(rw)
+		if recorder.HeaderWritten() {
+			t.Error("expected no header written yet")
+		}
+		recorder.WriteHeader(http.StatusCreated)
+		recorder.WriteHeader(http.StatusInternalServerError)
+		if !recorder.HeaderWritten() || recorder.Status() != http.StatusCreated || rw.Code != http.StatusCreated {
+			t.Errorf("expected the first status to win, got %d and %d", recorder.Status(), rw.Code)
+		}
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		recorder := NewStatusRecorder(rw)
+		var flusher http.Flusher = recorder
+		flusher.Flush()
+		if !rw.Flushed || !recorder.HeaderWritten() {
+			t.Error("expected the flush to reach the wrapped writer")
+		}
+	})
+
+	t.Run("read from", func(t *testing.T) {
+		for _, passthrough := range []bool{true, false} {
+			rw := &readerFromWriter{ResponseRecorder: httptest.NewRecorder()}
+			recorder := NewStatusRecorder(rw)
+			if !passthrough {
+				recorder = NewStatusRecorder(rw.ResponseRecorder)
+			}
+			written, err := io.Copy(recorder, struct{ io.Reader }{strings.NewReader("file content")})
+			if err != nil || written != 12 || recorder.BytesWritten() != 12 || recorder.Status() != http.StatusOK {
+				t.Errorf("expected 12 bytes recorded with a 200, got %d %d %d %v", written, recorder.BytesWritten(), recorder.Status(), err)
+			}
+			if passthrough && rw.readFrom != 1 {
+				t.Errorf("expected the wrapped ReadFrom to be used, got %d calls", rw.readFrom)
+			}
+			if rw.Body.String() != "file content" {
+				t.Errorf("expected the body to reach the wrapped writer, got %q", rw.Body.String())
+			}
+		}
+	})
+
+	t.Run("hijack", func(t *testing.T) {
+		recorded := make(chan int, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			recorder := NewStatusRecorder(rw)
+			conn, buffer, err := recorder.Hijack()
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				recorded <- 0
+				return
+			}
+			defer conn.Close()
+			buffer.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+			buffer.Flush()
+			recorded <- recorder.Status()
+		}))
+		defer server.Close()
+
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n"))
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if !strings.HasPrefix(line, "HTTP/1.1 101") {
+			t.Errorf("expected the hijacked connection to answer, got %q", line)
+		}
+		if status := <-recorded; status != http.StatusSwitchingProtocols {
+			t.Errorf("expected 101 to be recorded, got %d", status)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package yagaw
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+)
+
+// ----------- PANIC RECOVERY -----------
+
+// DefaultRecoverBody is the body of the 500 responses sent by Recoverer
+const DefaultRecoverBody = "500 - Internal server error"
+
+type recoverSettings struct {
+	body    string
+	onPanic func(req *http.Request, recovered any)
+}
+
+// RecovererOption configures the middleware returned by Recoverer
+type RecovererOption func(settings *recoverSettings)
+
+// RecoverBody sets the body of the 500 responses, DefaultRecoverBody unless set
+func RecoverBody(body string) RecovererOption {
+	return func(settings *recoverSettings) {
+		settings.body = body
+	}
+}
+
+// OnPanic sets a function called with the recovered value of every panic, e.g. to report
+// it to an error tracking service.
+func OnPanic(fn func(req *http.Request, recovered any)) RecovererOption {
+	return func(settings *recoverSettings) {
+		settings.onPanic = fn
+	}
+}
+
+// Recoverer returns server middleware recovering the panics of the handlers it wraps, like
+// the router does for its routes: the stack trace is logged at the error level and the
+// client gets a 500. When the response was already started the connection is closed
+// instead, and http.ErrAbortHandler is passed along for net/http to abort silently.
+func Recoverer(opts ...RecovererOption) func(http.Handler) http.Handler {
+	settings := &recoverSettings{body: DefaultRecoverBody}
+	for _, opt := range opts {
+		opt(settings)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			recorder := NewStatusRecorder(rw)
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+				Log.Error(fmt.Sprintf("panic serving `%s %s`: %v\n%s", req.Method, req.URL.Path, recovered, debug.Stack()))
+				if settings.onPanic != nil {
+					settings.onPanic(req, recovered)
+				}
+
+				// A partial response can't be fixed, aborting tells the client it is broken
+				if recorder.HeaderWritten() {
+					panic(http.ErrAbortHandler)
+				}
+				// Headers describing the body the handler meant to send don't fit the error one
+				for _, header := range []string{"Content-Length", "Content-Encoding", "ETag", "Last-Modified"} {
+					rw.Header().Del(header)
+				}
+				rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				rw.Header().Set("X-Content-Type-Options", "nosniff")
+				rw.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(rw, settings.body)
+			}()
+			next.ServeHTTP(recorder, req)
+		})
+	}
+}
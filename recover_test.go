@@ -0,0 +1,114 @@
+package yagaw
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Pho3b/tiny-logger/logs/log_level"
+)
+
+func TestRecoverer(t *testing.T) {
+	read := captureLog(t, log_level.ErrorLvlName)
+	mu := sync.Mutex{}
+	reported := []any{}
+	router := NewRouter()
+	router.RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("hello")
+	})
+	ts := NewTestServerT(t, router)
+	ts.Server().Use(
+		Recoverer(RecoverBody("something broke"), OnPanic(func(req *http.Request, recovered any) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, recovered)
+		})),
+		func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/panic":
+					panic("broken middleware")
+				case "/partial":
+					rw.Write([]byte("half a response"))
+					http.NewResponseController(rw).Flush()
+					panic("broken stream")
+				case "/stale":
+					rw.Header().Set("Content-Length", "2048")
+					rw.Header().Set("Content-Encoding", "gzip")
+					rw.Header().Set("ETag", `"v1"`)
+					panic("broken encoder")
+				case "/abort":
+					panic(http.ErrAbortHandler)
+				}
+				next.ServeHTTP(rw, req)
+			})
+		},
+	)
+
+	get := func(path string) (int, string, error) {
+		res, err := ts.Get(path)
+		if err != nil {
+			return 0, "", err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return res.StatusCode, string(body), err
+	}
+
+	t.Run("500 response", func(t *testing.T) {
+		status, body, err := get("/panic")
+		if err != nil || status != http.StatusInternalServerError || body != "something broke" {
+			t.Errorf("expected a 500, got %d %q %v", status, body, err)
+		}
+		if !strings.Contains(read(), "panic serving `GET /panic`: broken middleware") || !strings.Contains(read(), "recover.go") {
+			t.Errorf("expected the panic and its stack trace to be logged, got %q", read())
+		}
+	})
+
+	t.Run("headers of the body dropped", func(t *testing.T) {
+		res, err := ts.Get("/stale")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil || res.StatusCode != http.StatusInternalServerError || string(body) != "something broke" {
+			t.Errorf("expected a plain 500, got %d %q %v", res.StatusCode, body, err)
+		}
+		if res.Header.Get("Content-Encoding") != "" || res.Header.Get("ETag") != "" {
+			t.Errorf("expected the headers of the handler body to be dropped, got %v", res.Header)
+		}
+	})
+
+	t.Run("server keeps serving", func(t *testing.T) {
+		if status, body, err := get("/hello"); err != nil || status != http.StatusOK || body != "hello" {
+			t.Errorf("expected the route to be served, got %d %q %v", status, body, err)
+		}
+	})
+
+	t.Run("response already started", func(t *testing.T) {
+		if _, _, err := get("/partial"); err == nil {
+			t.Error("expected the connection to be closed")
+		}
+	})
+
+	t.Run("abort passed along", func(t *testing.T) {
+		if _, _, err := get("/abort"); err == nil {
+			t.Error("expected the connection to be closed")
+		}
+		if strings.Contains(read(), "/abort") {
+			t.Errorf("expected aborted requests not to be logged, got %q", read())
+		}
+	})
+
+	// The client may see the connection closed before the handler returns
+	ts.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if expected := []any{"broken middleware", "broken encoder", "broken stream"}; !slices.Equal(reported, expected) {
+		t.Errorf("expected the recovered values to be reported, got %v", reported)
+	}
+}
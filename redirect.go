@@ -0,0 +1,90 @@
+package yagaw
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type redirectPart struct {
+	literal  string
+	param    string
+	catchAll bool
+}
+
+// Redirect registers a route answering with a redirect to the target, parameters of the
+// target like `{id}` are replaced with the values matched by the route path:
+// `Redirect(GET, "/u/{id}", "/users/{id}", 301)`.
+func (r *Router) Redirect(method HttpMethod, from string, to string, code int) *Route {
+	if code < 300 || code > 399 {
+		return &Route{router: r, err: fmt.Errorf("invalid redirect `%s %s`: status %d is not a redirect", method, from, code)}
+	}
+
+	fromParams, err := placeholderNames(from)
+	if err != nil {
+		return &Route{router: r, err: fmt.Errorf("invalid route `%s %s`: %w", method, from, err)}
+	}
+	target, err := parseRedirectTarget(to, fromParams)
+	if err != nil {
+		return &Route{router: r, err: fmt.Errorf("invalid redirect target `%s`: %w", to, err)}
+	}
+
+	return r.RegisterRoute(method, from, func(req *http.Request, _ Params) *HttpResponse {
+		location := strings.Builder{}
+		for _, part := range target {
+			if part.param == "" {
+				location.WriteString(part.literal)
+				continue
+			}
+			location.WriteString(escapePathValue(PathParam(req, part.param), part.catchAll))
+		}
+		return NewHttpResponse(code).SetHeader("Location", location.String())
+	})
+}
+
+func parseRedirectTarget(to string, fromParams map[string]bool) ([]redirectPart, error) {
+	if err := validateRoutePath(to); err != nil {
+		return nil, err
+	}
+
+	target := []redirectPart{}
+	cursor := 0
+	for {
+		open := strings.IndexByte(to[cursor:], '{')
+		if open < 0 {
+			break
+		}
+		open += cursor
+		end := closingBrace(to, open)
+
+		name, isCatchAll := strings.CutPrefix(to[open+1:end], "*")
+		if !fromParams[name] {
+			return nil, fmt.Errorf("parameter `%s` is not part of the route path", name)
+		}
+		target = append(target, redirectPart{literal: to[cursor:open]}, redirectPart{param: name, catchAll: isCatchAll})
+		cursor = end + 1
+	}
+	return append(target, redirectPart{literal: to[cursor:]}), nil
+}
+
+// placeholderNames returns the names of the parameters declared by a route path
+func placeholderNames(path string) (map[string]bool, error) {
+	if err := validateRoutePath(path); err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for cursor := 0; ; {
+		open := strings.IndexByte(path[cursor:], '{')
+		if open < 0 {
+			return names, nil
+		}
+		open += cursor
+		end := closingBrace(path, open)
+		cursor = end + 1
+
+		name, _, _ := strings.Cut(path[open+1:end], ":")
+		name = strings.TrimPrefix(strings.TrimSuffix(name, "?"), "*")
+		names[name] = true
+	}
+}
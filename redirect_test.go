@@ -0,0 +1,74 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	router := NewRouter()
+	router.Redirect(GET, "/old", "/new", http.StatusMovedPermanently)
+	router.Redirect(GET, "/u/{id}", "/users/{id}", http.StatusMovedPermanently)
+	router.Redirect(POST, "/u/{id}/posts/{postId:int}", "/users/{id}/posts/{postId}", http.StatusPermanentRedirect)
+	router.Redirect(GET, "/assets/{*path}", "https://cdn.example.com/{*path}", http.StatusFound)
+	router.Redirect(GET, "/archive/{year}/{month?}", "/blog/{year}", http.StatusSeeOther)
+
+	tests := []struct {
+		method   HttpMethod
+		target   string
+		status   int
+		location string
+	}{
+		{GET, "/old", http.StatusMovedPermanently, "/new"},
+		{GET, "/u/7", http.StatusMovedPermanently, "/users/7"},
+		{GET, "/u/john%20doe", http.StatusMovedPermanently, "/users/john%20doe"},
+		{GET, "/u/a%3Fb", http.StatusMovedPermanently, "/users/a%3Fb"},
+		{POST, "/u/7/posts/9", http.StatusPermanentRedirect, "/users/7/posts/9"},
+		{GET, "/assets/css/main%20file.css", http.StatusFound, "https://cdn.example.com/css/main%20file.css"},
+		{GET, "/archive/2024/05", http.StatusSeeOther, "/blog/2024"},
+		{GET, "/archive/2024", http.StatusSeeOther, "/blog/2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.target, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if location := rw.Header().Get("Location"); location != tt.location {
+				t.Errorf("expected Location %q, got %q", tt.location, location)
+			}
+		})
+	}
+}
+
+func TestRedirectRegistrationErrors(t *testing.T) {
+	router := NewRouter()
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		code int
+	}{
+		{"success status", "/old", "/new", http.StatusOK},
+		{"client error status", "/old", "/new", http.StatusNotFound},
+		{"unknown target parameter", "/u/{id}", "/users/{userId}", http.StatusMovedPermanently},
+		{"malformed target", "/u/{id}", "/users/{id", http.StatusMovedPermanently},
+		{"malformed route", "/u/{id", "/users", http.StatusMovedPermanently},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := router.Redirect(GET, tt.from, tt.to, tt.code).Err(); err == nil {
+				t.Error("expected a registration error")
+			}
+		})
+	}
+	if len(router.Routes()) != 0 {
+		t.Error("invalid redirects should not be registered")
+	}
+}
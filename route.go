@@ -0,0 +1,290 @@
+package yagaw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+var ErrDuplicateRouteName = errors.New("duplicate route name")
+
+// Route is the result of a registration, it gives access to the registration error and
+// allows naming the route for reverse URL generation.
+type Route struct {
+	router          *Router
+	handlerPackages []*RequestHandlerPackage
+	err             error
+}
+
+// Err returns the error that prevented the route from being registered or named
+func (rt *Route) Err() error {
+	return rt.err
+}
+
+// Name registers the route under a unique name usable with Router.URL, naming a route
+// that failed to register has no effect.
+func (rt *Route) Name(name string) *Route {
+	if rt.err != nil || len(rt.handlerPackages) == 0 {
+		return rt
+	}
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+
+	if named, exists := rt.router.namedRoutes[name]; exists && named.Path != rt.handlerPackages[0].Path {
+		rt.err = fmt.Errorf("%w: `%s` is already used by `%s`", ErrDuplicateRouteName, name, named.Path)
+		return rt
+	}
+
+	for _, handlerPackage := range rt.handlerPackages {
+		handlerPackage.name = name
+	}
+	rt.router.namedRoutes[name] = rt.handlerPackages[0]
+	return rt
+}
+
+// Priority makes the route win over every matching route with a lower priority, whatever
+// the default precedence. Routes have priority 0 unless set.
+func (rt *Route) Priority(priority int) *Route {
+	if rt.err != nil {
+		return rt
+	}
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+
+	for _, handlerPackage := range rt.handlerPackages {
+		handlerPackage.priority = priority
+	}
+	rt.router.prioritized = rt.router.prioritized || priority != 0
+	return rt
+}
+
+// Meta attaches a value to the route under the key, read by middleware and handlers with
+// RouteMeta, e.g. `Meta("rate", "100/min")` for the Quota middleware.
+func (rt *Route) Meta(key string, value string) *Route {
+	if rt.err != nil {
+		return rt
+	}
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+
+	// The map is replaced rather than updated, requests in flight may be reading it
+	for _, handlerPackage := range rt.handlerPackages {
+		meta := maps.Clone(handlerPackage.meta)
+		if meta == nil {
+			meta = map[string]string{}
+		}
+		meta[key] = value
+		handlerPackage.meta = meta
+	}
+	return rt
+}
+
+// RouteMeta returns the value attached with Meta under the key to the matched route, an
+// empty string when none is.
+func RouteMeta(req *http.Request, key string) string {
+	meta, _ := req.Context().Value(routeMetaKey{}).(map[string]string)
+	return meta[key]
+}
+
+type routeMetaKey struct{}
+
+func withRouteMeta(req *http.Request, meta map[string]string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), routeMetaKey{}, meta))
+}
+
+func (r *Router) newRoute(method HttpMethod, handlerPackage *RequestHandlerPackage) *Route {
+	registered, err := r.registerRoute(method, handlerPackage)
+	return &Route{router: r, handlerPackages: registered, err: err}
+}
+
+// ----------- REVERSE ROUTING -----------
+
+// URL builds the path of a named route replacing its parameters with the given values,
+// every parameter is required and its value must satisfy the parameter constraint.
+func (r *Router) URL(name string, params map[string]string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handlerPackage, exists := r.namedRoutes[name]
+	if !exists {
+		return "", fmt.Errorf("unknown route name `%s`", name)
+	}
+
+	path := handlerPackage.Path
+	builder := strings.Builder{}
+	cursor := 0
+	for {
+		open := strings.IndexByte(path[cursor:], '{')
+		if open < 0 {
+			break
+		}
+		open += cursor
+		end := closingBrace(path, open)
+
+		paramName, _, _ := strings.Cut(path[open+1:end], ":")
+		paramName, isCatchAll := strings.CutPrefix(paramName, "*")
+		paramName, isOptional := strings.CutSuffix(paramName, "?")
+		value, found := params[paramName]
+		if !found && isOptional {
+			// Optional parameters end the path, the slash or dot before them goes away too
+			builder.WriteString(path[cursor : open-1])
+			cursor = end + 1
+			break
+		}
+		if !found {
+			return "", fmt.Errorf("missing parameter `%s` for route `%s`", paramName, name)
+		}
+		if pattern := handlerPackage.paramPatterns[paramName]; pattern != nil && !pattern.MatchString(value) {
+			return "", fmt.Errorf("parameter `%s` value `%s` does not match `%s` for route `%s`", paramName, value, path[open:end+1], name)
+		}
+
+		builder.WriteString(path[cursor:open])
+		builder.WriteString(escapePathValue(value, isCatchAll))
+		cursor = end + 1
+	}
+	builder.WriteString(path[cursor:])
+
+	if builder.Len() == 0 {
+		return "/", nil
+	}
+	return builder.String(), nil
+}
+
+// NamedRoutes returns the registered path of every named route keyed by name
+func (r *Router) NamedRoutes() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make(map[string]string, len(r.namedRoutes))
+	for name, handlerPackage := range r.namedRoutes {
+		names[name] = handlerPackage.Path
+	}
+	return names
+}
+
+// ----------- INTROSPECTION -----------
+
+// RouteInfo describes a registered route as it was declared
+type RouteInfo struct {
+	Method     HttpMethod
+	Path       string
+	ParamNames []string
+	ParamTypes map[string]string
+	Name       string
+	AnyMethod  bool
+	Priority   int
+	Version    string
+	Deprecated bool
+	Aliases    []string
+}
+
+// Walk calls fn for every registered route ordered by method, following HttpMethods, then
+// by registration order. The pattern is the compiled regex for parametrized routes and the
+// path itself for the others, the walk stops at the first error returned by fn.
+func (r *Router) Walk(fn func(method HttpMethod, pattern string, info RouteInfo) error) error {
+	for _, route := range r.orderedRoutes() {
+		pattern := route.handlerPackage.Path
+		if route.handlerPackage.Pattern != nil {
+			pattern = route.handlerPackage.Pattern.String()
+		}
+		if err := fn(route.method, pattern, route.handlerPackage.info(route.method)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Routes returns a copy of every registered route in the Walk order
+func (r *Router) Routes() []RouteInfo {
+	orderedRoutes := r.orderedRoutes()
+	routes := make([]RouteInfo, len(orderedRoutes))
+	for i, route := range orderedRoutes {
+		routes[i] = route.handlerPackage.info(route.method)
+	}
+	return routes
+}
+
+// PrintRoutes writes an aligned table of the registered routes in the Walk order, with the
+// handler function name, the number of route and group middleware wrapping it and the
+// route priority.
+func (r *Router) PrintRoutes(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "METHOD\tPATH\tHANDLER\tMIDDLEWARE\tPRIORITY")
+
+	for _, route := range r.orderedRoutes() {
+		handlerPackage := route.handlerPackage
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\n", route.method, handlerPackage.Path, handlerName(handlerPackage.Handler), handlerPackage.middlewareCount(), handlerPackage.priority)
+	}
+	return table.Flush()
+}
+
+func (r *Router) String() string {
+	builder := strings.Builder{}
+	r.PrintRoutes(&builder)
+	return builder.String()
+}
+
+func (p *RequestHandlerPackage) info(method HttpMethod) RouteInfo {
+	return RouteInfo{
+		Method:     method,
+		Path:       p.Path,
+		ParamNames: slices.Clone(p.paramNames),
+		ParamTypes: maps.Clone(p.paramTypes),
+		Name:       p.name,
+		AnyMethod:  p.anyMethod,
+		Priority:   p.priority,
+		Version:    p.routeVersion(),
+		Deprecated: p.isDeprecated(),
+		Aliases:    p.aliasPaths(),
+	}
+}
+
+type orderedRoute struct {
+	method         HttpMethod
+	handlerPackage *RequestHandlerPackage
+}
+
+func (r *Router) orderedRoutes() []orderedRoute {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := []orderedRoute{}
+	r.eachRoute(func(method HttpMethod, handlerPackage *RequestHandlerPackage) {
+		routes = append(routes, orderedRoute{method, handlerPackage})
+	})
+	slices.SortFunc(routes, func(a, b orderedRoute) int {
+		if a.method != b.method {
+			return slices.Index(HttpMethods, a.method) - slices.Index(HttpMethods, b.method)
+		}
+		return a.handlerPackage.order - b.handlerPackage.order
+	})
+	return routes
+}
+
+// ----------- HELPERS -----------
+
+func handlerName(handler HttpRequestHandler) string {
+	if handler == nil {
+		return "-"
+	}
+	return runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+}
+
+// escapePathValue escapes a parameter value, catch-all values keep their slashes
+func escapePathValue(value string, keepSlashes bool) string {
+	if !keepSlashes {
+		return url.PathEscape(value)
+	}
+	parts := strings.Split(value, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
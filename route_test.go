@@ -0,0 +1,217 @@
+package yagaw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRouteURL(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/users/{id}/posts/{postId}", handler).Name("user-post")
+	router.RegisterRoute(GET, "/health", handler).Name("health")
+	router.RegisterRoute(GET, "/orders/{id:int}", handler).Name("order")
+	router.RegisterRoute(GET, "/files/{name}.{ext:alpha}", handler).Name("file")
+	router.RegisterRoute(GET, "/static/{*filepath}", handler).Name("static")
+	router.Group("/api").RegisterRoute(GET, "/items/{id}", handler).Name("api-item")
+	router.Any("/any/{id}", handler).Name("any")
+
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected string
+	}{
+		{"user-post", map[string]string{"id": "7", "postId": "9"}, "/users/7/posts/9"},
+		{"health", nil, "/health"},
+		{"order", map[string]string{"id": "42"}, "/orders/42"},
+		{"file", map[string]string{"name": "report", "ext": "pdf"}, "/files/report.pdf"},
+		{"static", map[string]string{"filepath": "css/main file.css"}, "/static/css/main%20file.css"},
+		{"api-item", map[string]string{"id": "1"}, "/api/items/1"},
+		{"any", map[string]string{"id": "1"}, "/any/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, err := router.URL(tt.name, tt.params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if url != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, url)
+			}
+		})
+	}
+
+	failures := []struct {
+		name    string
+		params  map[string]string
+		message string
+	}{
+		{"missing", nil, "unknown route name `missing`"},
+		{"user-post", map[string]string{"id": "7"}, "missing parameter `postId`"},
+		{"order", map[string]string{"id": "abc"}, "does not match `{id:int}`"},
+		{"user-post", map[string]string{"id": "a/b", "postId": "9"}, "does not match `{id}`"},
+	}
+
+	for _, tt := range failures {
+		t.Run("error "+tt.message, func(t *testing.T) {
+			_, err := router.URL(tt.name, tt.params)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected an error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+
+	named := router.NamedRoutes()
+	if len(named) != 7 || named["user-post"] != "/users/{id}/posts/{postId}" || named["api-item"] != "/api/items/{id}" {
+		t.Errorf("unexpected named routes %v", named)
+	}
+}
+
+func TestRouteNameErrors(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/users", handler).Name("users")
+	if err := router.RegisterRoute(POST, "/users", handler).Name("users").Err(); err != nil {
+		t.Errorf("naming another method of the same path should be allowed, got %v", err)
+	}
+
+	err := router.RegisterRoute(GET, "/accounts", handler).Name("users").Err()
+	if !errors.Is(err, ErrDuplicateRouteName) {
+		t.Errorf("expected ErrDuplicateRouteName, got %v", err)
+	}
+
+	invalid := router.RegisterRoute(GET, "/broken/{id", handler).Name("broken")
+	if !errors.Is(invalid.Err(), ErrMalformedPattern) {
+		t.Errorf("expected the registration error to be kept, got %v", invalid.Err())
+	}
+	if _, exists := router.NamedRoutes()["broken"]; exists {
+		t.Error("a route failing registration should not be named")
+	}
+}
+
+func TestRouterWalk(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(POST, "/users", handler)
+	router.RegisterRoute(GET, "/users/{id:int}/posts/{postId}", handler).Name("user-post")
+	router.RegisterRoute(GET, "/health", handler)
+	router.RegisterRoute(GET, "/users/{id}", handler)
+	router.RegisterRoute(DELETE, "/users/{id}", handler)
+
+	type walked struct {
+		method  HttpMethod
+		pattern string
+		info    string
+	}
+	expected := []walked{
+		{GET, "(?i)^/users/(-?[0-9]+)/posts/([^/]+)$", "/users/{id:int}/posts/{postId} [id postId] map[id:int] user-post"},
+		{GET, "/health", "/health [] map[] "},
+		{GET, "(?i)^/users/([^/]+)$", "/users/{id} [id] map[] "},
+		{DELETE, "(?i)^/users/([^/]+)$", "/users/{id} [id] map[] "},
+		{POST, "/users", "/users [] map[] "},
+	}
+
+	// The order must not depend on map iteration
+	for range 10 {
+		routes := []walked{}
+		err := router.Walk(func(method HttpMethod, pattern string, info RouteInfo) error {
+			routes = append(routes, walked{method, pattern, fmt.Sprint(info.Path, " ", info.ParamNames, " ", info.ParamTypes, " ", info.Name)})
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(routes, expected) {
+			t.Fatalf("expected routes %v, got %v", expected, routes)
+		}
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := router.Walk(func(method HttpMethod, pattern string, info RouteInfo) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected the walk to stop at the first error, got %v after %d calls", err, calls)
+	}
+}
+
+func listUsersHandler(req *http.Request, params Params) *HttpResponse {
+	return NewHttpResponse(http.StatusOK)
+}
+
+func deleteUserHandler(req *http.Request, params Params) *HttpResponse {
+	return NewHttpResponse(http.StatusNoContent)
+}
+
+func TestRouterPrintRoutes(t *testing.T) {
+	passThrough := func(next HttpRequestHandler) HttpRequestHandler { return next }
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/users", listUsersHandler)
+	api := router.Group("/api").Use(passThrough)
+	api.RegisterRouteWith(DELETE, "/users/{id:int}", deleteUserHandler, passThrough)
+	api.RegisterRoute(GET, "/users/{id}", listUsersHandler).Priority(5)
+
+	expected := strings.Join([]string{
+		"METHOD  PATH                 HANDLER                                     MIDDLEWARE  PRIORITY",
+		"GET     /users               github.com/Algatux/yagaw.listUsersHandler   0           0",
+		"GET     /api/users/{id}      github.com/Algatux/yagaw.listUsersHandler   1           5",
+		"DELETE  /api/users/{id:int}  github.com/Algatux/yagaw.deleteUserHandler  2           0",
+		"",
+	}, "\n")
+
+	output := strings.Builder{}
+	if err := router.PrintRoutes(&output); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.String() != expected {
+		t.Errorf("expected routes table\n%s\ngot\n%s", expected, output.String())
+	}
+	if router.String() != expected {
+		t.Errorf("expected String to render the routes table, got\n%s", router.String())
+	}
+}
+
+func TestRouteMeta(t *testing.T) {
+	router := NewRouter()
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(RouteMeta(req, "owner") + "," + RouteMeta(req, "rate"))
+	}
+	router.RegisterRoute(GET, "/users/{id}", handler).Meta("owner", "accounts").Meta("rate", "100/min").Meta("rate", "10/s")
+	router.RegisterRoute(GET, "/health", handler)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/users/42", "accounts,10/s"},
+		{"/health", ","},
+	}
+	for _, tt := range tests {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+		if rw.Body.String() != tt.expected {
+			t.Errorf("expected %q for %s, got %q", tt.expected, tt.path, rw.Body.String())
+		}
+	}
+}
@@ -1,161 +1,1126 @@
 package yagaw
 
 import (
+	"errors"
 	"fmt"
-	"iter"
+	"io"
 	"maps"
 	"net/http"
+	"net/url"
 	"regexp"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Pho3b/tiny-logger/logs/log_level"
 )
 
 type RequestHandlerPackage struct {
-	Handler   HttpRequestHandler
-	ParamList map[int]string
-	Params    Params
+	Handler          HttpRequestHandler
+	Path             string
+	ParamList        map[int]string
+	Pattern          *regexp.Regexp
+	paramNames       []string
+	paramTypes       map[string]string
+	paramPatterns    map[string]*regexp.Regexp
+	group            *Group
+	middleware       []Middleware
+	anyMethod        bool
+	name             string
+	order            int
+	matchers         []requestMatcher
+	candidates       []*RequestHandlerPackage
+	expanded         bool
+	priority         int
+	caseSensitive    bool
+	timeout          *time.Duration
+	consumes         []string
+	produces         []string
+	mergedMiddleware []Middleware
+	version          string
+	deprecated       bool
+	method           HttpMethod
+	aliasOf          *RequestHandlerPackage
+	aliases          []*RequestHandlerPackage
+	implicitHead     bool
+	paramPattern     string
+	meta             map[string]string
+}
+
+func (p *RequestHandlerPackage) clone() RequestHandlerPackage {
+	copied := *p
+	copied.ParamList = maps.Clone(p.ParamList)
+	copied.paramNames = slices.Clone(p.paramNames)
+	copied.paramTypes = maps.Clone(p.paramTypes)
+	copied.paramPatterns = maps.Clone(p.paramPatterns)
+	copied.middleware = slices.Clone(p.middleware)
+	copied.mergedMiddleware = slices.Clone(p.mergedMiddleware)
+	copied.aliases = slices.Clone(p.aliases)
+	return copied
+}
+
+// ParamType returns the named constraint declared for a parameter, e.g. `int` for `{id:int}`.
+func (p RequestHandlerPackage) ParamType(name string) string {
+	return p.paramTypes[name]
 }
+
 type RequestHandlerMap map[HttpMethod]map[string]RequestHandlerPackage
 
+// Router is safe for concurrent use, routes can be registered while serving requests.
 type Router struct {
-	routes RequestHandlerMap
+	mu                   sync.RWMutex
+	staticRoutes         map[HttpMethod]map[string]*RequestHandlerPackage
+	exactRoutes          map[HttpMethod]map[string]*RequestHandlerPackage
+	tree                 *routeNode
+	autoOptions          bool
+	autoHead             bool
+	autoRegisterHead     bool
+	skipCleanPath        bool
+	paramPattern         string
+	paramPatterns        []string
+	notFound             HttpRequestHandler
+	methodNotAllowed     HttpRequestHandler
+	mounts               []*mount
+	middleware           []Middleware
+	duplicatePolicy      DuplicatePolicy
+	trailingSlash        bool
+	caseSensitive        bool
+	namedRoutes          map[string]*RequestHandlerPackage
+	registrations        int
+	hosts                map[string]*Router
+	hostPatterns         []*hostPattern
+	prioritized          bool
+	useRawPath           bool
+	methodOverrides      []HttpMethod
+	strictConflicts      bool
+	cache                *routeCache
+	fallback             http.Handler
+	ignoreSlash          bool
+	defaultTimeout       time.Duration
+	consumes             []string
+	unsupportedMediaType HttpRequestHandler
+	produces             []string
+	notAcceptable        HttpRequestHandler
+	defaultVersion       string
+	maxPathLength        int
+	maxPathSegments      int
+}
+
+type DuplicatePolicy int
+
+const (
+	DuplicateOverwrite DuplicatePolicy = iota
+	DuplicateError
+	DuplicatePanic
+)
+
+var ErrDuplicateRoute = errors.New("duplicate route")
+var ErrInvalidRoute = errors.New("invalid route")
+
+type routeMatch struct {
+	handlerPackage *RequestHandlerPackage
+	pathParams     map[string]string
+	allowed        []HttpMethod
+	fallback       http.Handler
+	timeout        time.Duration
+	consumes       []string
+	accepted       []string
+	produces       []string
+	negotiated     string
+	deprecated     bool
 }
 
 // ----------- REQUEST ROUTING -----------
 func (r *Router) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	// Oversized paths are refused before any matching work, host patterns included
+	var answer HttpRequestHandler
+	if r.exceedsPathLimits(req) {
+		answer = uriTooLongHandler
+	}
+
+	// Host scoped routers take the request before the path is considered
+	if answer == nil {
+		if hostRouter, params := r.hostRouter(req.Host); hostRouter != nil {
+			hostReq := req
+			if len(params) > 0 {
+				hostReq = withHostParams(req, params)
+			}
+			hostRouter.ServeHTTP(rw, hostReq)
+			req.Pattern = hostReq.Pattern
+			return
+		}
+	}
+	original := req
+	if answer == nil {
+		req = r.overrideMethod(req)
+		req, answer = r.cleanRequest(req)
+	}
 	debugRequest(rw, req)
-	handlerPkg := r.findReqHandler(req)
-	response := handlerPkg.Handler(req, handlerPkg.Params)
+
+	// Handlers run outside the lock so that they can register routes themselves
+	r.mu.RLock()
+	var match routeMatch
+	if answer != nil {
+		match = routeMatch{handlerPackage: &RequestHandlerPackage{Handler: answer}}
+	} else {
+		match = r.negotiate(req, r.checkContentType(req, r.findReqHandler(req)))
+	}
+	// Middleware is composed at serve time so that it applies to routes registered before Use
+	var handler HttpRequestHandler
+	var meta map[string]string
+	if match.fallback == nil {
+		handler = chainMiddleware(withTimeout(match.handlerPackage.chain(), match.timeout), r.middleware)
+		meta = match.handlerPackage.meta
+	}
+	r.mu.RUnlock()
+
+	// The matched pattern is set on the received request as well, for the middleware
+	// wrapping the router to read once the request is served
+	if match.handlerPackage != nil && match.handlerPackage.Path != "" {
+		original.Pattern = match.handlerPackage.Path
+		req.Pattern = original.Pattern
+	}
+
+	// A panicking handler must not leave the client without a response
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			Log.Error(fmt.Sprintf("panic serving `%s %s`: %v\n%s", req.Method, req.URL.Path, recovered, debug.Stack()))
+			http.Error(rw, "500 - Internal server error", http.StatusInternalServerError)
+		}
+	}()
+
+	// The fallback handler gets the request as it was received
+	if match.fallback != nil {
+		match.fallback.ServeHTTP(rw, original)
+		return
+	}
+
+	// The Allow header is set before the handler runs so that custom handlers get it too
+	if len(match.allowed) > 0 {
+		rw.Header().Set("Allow", joinMethods(match.allowed))
+		req = withAllowedMethods(req, match.allowed)
+	}
+	if len(match.accepted) > 0 {
+		acceptHeader := "Accept-Post"
+		if req.Method == string(PATCH) {
+			acceptHeader = "Accept-Patch"
+		}
+		rw.Header().Set(acceptHeader, strings.Join(match.accepted, ", "))
+	}
+	if match.deprecated {
+		rw.Header().Set("Deprecation", "true")
+	}
+	if match.negotiated != "" {
+		rw.Header().Add("Vary", "Accept")
+		req = withNegotiatedType(req, match.negotiated)
+	}
+	if len(meta) > 0 {
+		req = withRouteMeta(req, meta)
+	}
+
+	// Routes without parameters get nil params, exact matches must not allocate
+	// Parameters captured from the host come along with the path ones
+	pathParams := match.pathParams
+	if inherited := hostParams(req); len(inherited) > 0 {
+		pathParams = mergeParams(inherited, pathParams)
+	}
+	var params Params
+	if len(pathParams) > 0 {
+		params = make(Params, len(pathParams))
+		req = withPathParams(req, pathParams)
+		for name, value := range pathParams {
+			params[name] = value
+		}
+	}
+	response := handler(req, params)
 
 	for key, header := range response.headers {
 		rw.Header().Set(key, header)
 	}
+	if response.writer != nil {
+		response.writer(rw)
+		return
+	}
 	rw.WriteHeader(response.status)
-	fmt.Fprint(rw, response.body)
+	io.WriteString(rw, response.body)
 }
 
 // ----------- PATTERN MATCHING -----------
-func (r *Router) findReqHandler(req *http.Request) RequestHandlerPackage {
-	// Direct match on Method, if not found fast exit to 404
-	_, methodFound := r.routes[HttpMethod(req.Method)]
-	if !methodFound {
-		return RequestHandlerPackage{Handler: routeNotFoundHandler}
+func (r *Router) findReqHandler(req *http.Request) routeMatch {
+	method := HttpMethod(req.Method)
+
+	// The path is matched decoded, an encoding that can't be decoded is the client fault
+	if req.URL.RawPath != "" {
+		if _, err := url.PathUnescape(req.URL.RawPath); err != nil {
+			return routeMatch{handlerPackage: &RequestHandlerPackage{Handler: badRequestHandler}}
+		}
+	}
+
+	// Raw paths are split before decoding so that `%2F` stays within its segment
+	path := req.URL.Path
+	if r.useRawPath {
+		path = req.URL.EscapedPath()
+	}
+
+	if match, found := r.match(req, method, path); found {
+		return match
 	}
 
-	// Direct match on Not parametrized route, if not found fast exit to 404
-	handlerPackage, routeFound := r.routes[HttpMethod(req.Method)][req.URL.Path]
-	if routeFound {
-		return handlerPackage
+	// Routes of the default version are served without the version prefix too
+	if match, found := r.matchDefaultVersion(req, method, path); found {
+		return match
 	}
 
-	// Matching on parametrized routes
-	key, matchFound := matchRoutePattern(maps.Keys(r.routes[HttpMethod(req.Method)]), req.URL.Path)
-	if matchFound {
-		// Extract the parametrized route and retrive parameters values
-		handlerPackage := r.routes[HttpMethod(req.Method)][key]
-		for i, param := range handlerPackage.ParamList {
-			parts := strings.Split(req.URL.Path, "/")
-			handlerPackage.Params[param] = parts[i+1]
+	// Without strict slashes the path is the same with or without the trailing slash
+	if r.ignoreSlash {
+		if toggled, found := toggleTrailingSlash(path); found {
+			if match, found := r.match(req, method, toggled); found {
+				return match
+			}
 		}
+	}
 
-		return r.routes[HttpMethod(req.Method)][key]
+	// Mounted handlers accept any method under their prefix
+	if mounted := r.findMount(path); mounted != nil {
+		return routeMatch{handlerPackage: &RequestHandlerPackage{Handler: mounted.handle, Path: mounted.prefix}}
+	}
+
+	// The path may exist with or without the trailing slash
+	if r.trailingSlash {
+		if canonical, found := toggleTrailingSlash(path); found {
+			if _, matched := r.match(req, method, canonical); matched {
+				location := canonical
+				if !r.useRawPath {
+					location = (&url.URL{Path: canonical}).EscapedPath()
+				}
+				return routeMatch{handlerPackage: &RequestHandlerPackage{Handler: redirectHandler(method, location)}}
+			}
+		}
+	}
+
+	// The path may still exist under other methods
+	allowed := r.allowedMethods(path)
+	if toggled, found := toggleTrailingSlash(path); len(allowed) == 0 && r.ignoreSlash && found {
+		allowed = r.allowedMethods(toggled)
+	}
+	if len(allowed) > 0 {
+		if method == OPTIONS && r.autoOptions {
+			return routeMatch{handlerPackage: &RequestHandlerPackage{Handler: autoOptionsHandler}, allowed: allowed}
+		}
+		// Routes registered for the method whose matchers all failed are not found
+		if !slices.Contains(allowed, method) && r.methodNotAllowed == nil && r.fallback != nil {
+			return routeMatch{fallback: r.fallback}
+		}
+		if !slices.Contains(allowed, method) {
+			return routeMatch{handlerPackage: &RequestHandlerPackage{Handler: handlerOr(r.methodNotAllowed, methodNotAllowedHandler)}, allowed: allowed}
+		}
 	}
 
 	// Still not found, drop the sponge
-	return RequestHandlerPackage{Handler: routeNotFoundHandler}
+	if r.notFound == nil && r.fallback != nil {
+		return routeMatch{fallback: r.fallback}
+	}
+	return routeMatch{handlerPackage: &RequestHandlerPackage{Handler: handlerOr(r.notFound, routeNotFoundHandler)}}
+}
+
+func (r *Router) match(req *http.Request, method HttpMethod, path string) (routeMatch, bool) {
+	handlerPackage, pathParams := r.lookup(req, method, path)
+	if handlerPackage != nil {
+		return routeMatch{handlerPackage: handlerPackage, pathParams: pathParams, timeout: r.routeTimeout(handlerPackage), consumes: r.routeConsumes(handlerPackage), produces: r.routeProduces(handlerPackage), deprecated: handlerPackage.isDeprecated()}, true
+	}
+
+	// HEAD requests without a dedicated handler are served by the GET one
+	if method == HEAD && r.autoHead {
+		if getPackage, pathParams := r.lookup(req, GET, path); getPackage != nil {
+			headPackage := *getPackage
+			headPackage.Handler = headHandler(getPackage.Handler)
+			return routeMatch{handlerPackage: &headPackage, pathParams: pathParams, timeout: r.routeTimeout(getPackage), consumes: r.routeConsumes(getPackage), produces: r.routeProduces(getPackage), deprecated: getPackage.isDeprecated()}, true
+		}
+	}
+
+	return routeMatch{}, false
+}
+
+func (r *Router) lookup(req *http.Request, method HttpMethod, path string) (*RequestHandlerPackage, map[string]string) {
+	if r.prioritized {
+		return r.lookupByPriority(req, method, path)
+	}
+
+	// Direct match on Not parametrized routes, case insensitive paths are stored lowercased
+	staticPath := r.staticPath(path)
+	if handlerPackage := r.exactRoutes[method][staticPath].resolve(req); handlerPackage != nil {
+		return handlerPackage.target(), nil
+	}
+	if handlerPackage := lowercaseRoute(r.staticRoutes[method], staticPath).resolve(req); handlerPackage != nil {
+		return handlerPackage.target(), nil
+	}
+
+	// Walking the tree of parametrized routes, matchers are evaluated after the cache
+	// since the walk doesn't depend on the request
+	handlerPackage, values, cached := r.cache.get(method, path)
+	if !cached {
+		handlerPackage, values = r.tree.match(method, path, nil, r.useRawPath)
+		if handlerPackage != nil {
+			r.cache.add(method, path, handlerPackage, values)
+		}
+	}
+	if handlerPackage = handlerPackage.resolve(req); handlerPackage != nil {
+		pathParams := make(map[string]string, len(handlerPackage.paramNames))
+		for i, name := range handlerPackage.paramNames {
+			pathParams[name] = values[i]
+		}
+
+		return handlerPackage.target(), pathParams
+	}
+
+	return nil, nil
+}
+
+// lookupByPriority considers every route matching the path and picks the one with the
+// highest priority, the default precedence deciding between equal priorities.
+func (r *Router) lookupByPriority(req *http.Request, method HttpMethod, path string) (*RequestHandlerPackage, map[string]string) {
+	var best *RequestHandlerPackage
+	var bestParams map[string]string
+	consider := func(handlerPackage *RequestHandlerPackage, values []string) bool {
+		handlerPackage = handlerPackage.resolve(req)
+		if handlerPackage == nil || (best != nil && handlerPackage.target().priority <= best.priority) {
+			return true
+		}
+		best, bestParams = handlerPackage.target(), nil
+		if len(handlerPackage.paramNames) > 0 {
+			bestParams = make(map[string]string, len(handlerPackage.paramNames))
+			for i, name := range handlerPackage.paramNames {
+				bestParams[name] = values[i]
+			}
+		}
+		return true
+	}
+
+	staticPath := r.staticPath(path)
+	if handlerPackage, found := r.exactRoutes[method][staticPath]; found {
+		consider(handlerPackage, nil)
+	}
+	if handlerPackage := lowercaseRoute(r.staticRoutes[method], staticPath); handlerPackage != nil {
+		consider(handlerPackage, nil)
+	}
+	r.tree.matchEach(method, path, nil, r.useRawPath, consider)
+
+	return best, bestParams
 }
 
-func matchRoutePattern(keysIter iter.Seq[string], path string) (string, bool) {
-	for k := range keysIter {
-		re := regexp.MustCompile(fmt.Sprintf("(?i)%s", k))
-		record := re.FindString(path)
-		if len(record) != 0 {
-			return k, true
+// staticPath returns the decoded path used for routes without parameters. Escaped paths
+// with an encoded slash give the empty path, which no route has, since their decoded form
+// would have more segments than the tree sees.
+func (r *Router) staticPath(path string) string {
+	if !r.useRawPath {
+		return path
+	}
+	if hasEncodedSlash(path) {
+		return ""
+	}
+	if decoded, err := url.PathUnescape(path); err == nil {
+		return decoded
+	}
+	return path
+}
+
+// hasEncodedSlash reports whether the escaped path holds a `%2F`, in either case
+func hasEncodedSlash(path string) bool {
+	for i := 0; i+2 < len(path); i++ {
+		if path[i] == '%' && path[i+1] == '2' && (path[i+2] == 'F' || path[i+2] == 'f') {
+			return true
+		}
+	}
+	return false
+}
+
+// lowercaseRoute returns the route of the path in a table keyed by lowercased paths, ASCII
+// paths are lowercased on the stack so that the lookup doesn't allocate.
+func lowercaseRoute(routes map[string]*RequestHandlerPackage, path string) *RequestHandlerPackage {
+	var buffer [128]byte
+	if len(path) > len(buffer) {
+		return routes[strings.ToLower(path)]
+	}
+	for i := 0; i < len(path); i++ {
+		char := path[i]
+		if char >= utf8.RuneSelf {
+			return routes[strings.ToLower(path)]
+		}
+		if 'A' <= char && char <= 'Z' {
+			char += 'a' - 'A'
+		}
+		buffer[i] = char
+	}
+	return routes[string(buffer[:len(path)])]
+}
+
+func (r *Router) allowedMethods(path string) []HttpMethod {
+	found := make(map[HttpMethod]bool)
+	staticPath := r.staticPath(path)
+	for method, staticRoutes := range r.staticRoutes {
+		if _, exists := staticRoutes[strings.ToLower(staticPath)]; exists {
+			found[method] = true
+		}
+	}
+	for method, exactRoutes := range r.exactRoutes {
+		if _, exists := exactRoutes[staticPath]; exists {
+			found[method] = true
+		}
+	}
+	r.tree.collectMethods(path, found, r.useRawPath)
+	if len(found) > 0 && r.autoOptions {
+		found[OPTIONS] = true
+	}
+	if found[GET] && r.autoHead {
+		found[HEAD] = true
+	}
+
+	allowed := []HttpMethod{}
+	for _, method := range HttpMethods {
+		if found[method] {
+			allowed = append(allowed, method)
 		}
 	}
-	return "", false
+	return allowed
 }
 
 // ----------- ROUTE REGISTRATION -----------
-func (r *Router) RegisterRoute(method HttpMethod, path string, handler HttpRequestHandler) {
-	if r.routes[method] == nil {
-		r.routes[method] = make(map[string]RequestHandlerPackage)
+// RegisterRoute registers the handler for the method and path, registration errors are
+// available through the Err method of the returned route. Unknown methods, nil handlers
+// and paths not starting with `/` are errors wrapping ErrInvalidRoute.
+func (r *Router) RegisterRoute(method HttpMethod, path string, handler HttpRequestHandler) *Route {
+	return r.newRoute(method, &RequestHandlerPackage{Handler: handler, Path: path})
+}
+
+// MustRegisterRoute is like RegisterRoute but panics when the route cannot be registered
+func (r *Router) MustRegisterRoute(method HttpMethod, path string, handler HttpRequestHandler) *Route {
+	route := r.RegisterRoute(method, path, handler)
+	if route.err != nil {
+		panic(route.err)
 	}
+	return route
+}
+
+// RegisterRouteWith registers a route wrapped by its own middleware, which runs inside
+// the router and group middleware.
+func (r *Router) RegisterRouteWith(method HttpMethod, path string, handler HttpRequestHandler, mw ...Middleware) *Route {
+	return r.newRoute(method, &RequestHandlerPackage{Handler: handler, Path: path, middleware: mw})
+}
+
+// Handle registers a standard http.Handler, path parameters are available to it through
+// PathParam and PathParams like for any other handler.
+func (r *Router) Handle(method HttpMethod, path string, handler http.Handler) *Route {
+	if handler == nil {
+		return r.RegisterRoute(method, path, nil)
+	}
+	return r.RegisterRoute(method, path, adaptHandler(handler))
+}
 
-	type paramSearch struct {
-		start int
-		end   int
-		pos   int
-		name  string
+func (r *Router) HandleFunc(method HttpMethod, path string, handler func(http.ResponseWriter, *http.Request)) *Route {
+	if handler == nil {
+		return r.RegisterRoute(method, path, nil)
 	}
+	return r.Handle(method, path, http.HandlerFunc(handler))
+}
 
-	// Searching for url parameters patterns
-	paramList := []paramSearch{}
-	pathDepth := -1
-	found := false
-	foundAt := 0
+// Verb helpers are chainable, registration errors are logged instead of returned
+func (r *Router) Get(path string, handler HttpRequestHandler) *Router {
+	return r.chainRoute(GET, path, handler)
+}
 
-	paramNameBuilder := strings.Builder{}
-	for i, c := range path {
-		switch c {
-		case '/':
-			pathDepth++
-		case '{':
-			found = true
-			foundAt = i
-		case '}':
-			found = false
-			paramList = append(paramList, paramSearch{
-				start: foundAt,
-				end:   i,
-				pos:   pathDepth,
-				name:  paramNameBuilder.String(),
-			})
-			paramNameBuilder.Reset()
+func (r *Router) Post(path string, handler HttpRequestHandler) *Router {
+	return r.chainRoute(POST, path, handler)
+}
+
+func (r *Router) Put(path string, handler HttpRequestHandler) *Router {
+	return r.chainRoute(PUT, path, handler)
+}
+
+func (r *Router) Patch(path string, handler HttpRequestHandler) *Router {
+	return r.chainRoute(PATCH, path, handler)
+}
+
+func (r *Router) Delete(path string, handler HttpRequestHandler) *Router {
+	return r.chainRoute(DELETE, path, handler)
+}
+
+func (r *Router) Head(path string, handler HttpRequestHandler) *Router {
+	return r.chainRoute(HEAD, path, handler)
+}
+
+func (r *Router) Options(path string, handler HttpRequestHandler) *Router {
+	return r.chainRoute(OPTIONS, path, handler)
+}
+
+func (r *Router) chainRoute(method HttpMethod, path string, handler HttpRequestHandler) *Router {
+	if err := r.RegisterRoute(method, path, handler).Err(); err != nil {
+		Log.Error(err)
+	}
+	return r
+}
+
+// Any registers the handler for every HTTP method, registrations for a specific method on
+// the same path always take precedence over it, whatever the registration order.
+func (r *Router) Any(path string, handler HttpRequestHandler) *Route {
+	return r.registerAny(&RequestHandlerPackage{Handler: handler, Path: path})
+}
+
+func (r *Router) registerAny(template *RequestHandlerPackage) *Route {
+	route := &Route{router: r}
+	for _, method := range HttpMethods {
+		handlerPackage := *template
+		handlerPackage.anyMethod = true
+		registered, err := r.registerRoute(method, &handlerPackage)
+		route.handlerPackages = append(route.handlerPackages, registered...)
+		if route.err = err; err != nil {
+			return route
 		}
-		if found && c != '{' {
-			paramNameBuilder.WriteRune(c)
+	}
+	return route
+}
+
+func (r *Router) registerRoute(method HttpMethod, handlerPackage *RequestHandlerPackage) ([]*RequestHandlerPackage, error) {
+	if err := validateRoute(method, handlerPackage); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	handlerPackage.caseSensitive = r.caseSensitive
+	handlerPackage.paramPattern = r.paramPattern
+	head := *handlerPackage
+	registered, err := r.addRoute(method, handlerPackage, r.duplicatePolicy)
+	if err != nil || method != GET || handlerPackage.anyMethod || !r.autoRegisterHead {
+		return registered, err
+	}
+
+	// The HEAD route shares the GET registration, it is returned along with it
+	head.Handler = headHandler(head.Handler)
+	head.implicitHead = true
+	heads, err := r.addRoute(HEAD, &head, r.duplicatePolicy)
+	return append(registered, heads...), err
+}
+
+// validateRoute reports the registrations that could never serve a request: unknown
+// methods, missing handlers and paths not starting with a slash.
+func validateRoute(method HttpMethod, handlerPackage *RequestHandlerPackage) error {
+	switch {
+	case !slices.Contains(HttpMethods, method):
+		return fmt.Errorf("%w `%s %s`: unknown method `%s`", ErrInvalidRoute, method, handlerPackage.Path, method)
+	case handlerPackage.Handler == nil:
+		return fmt.Errorf("%w `%s %s`: nil handler", ErrInvalidRoute, method, handlerPackage.Path)
+	}
+	return validatePath(method, handlerPackage.Path)
+}
+
+func validatePath(method HttpMethod, path string) error {
+	switch {
+	case path == "":
+		return fmt.Errorf("%w `%s`: empty path", ErrInvalidRoute, method)
+	case path[0] != '/':
+		return fmt.Errorf("%w `%s %s`: path must start with `/`", ErrInvalidRoute, method, path)
+	}
+	return nil
+}
+
+func (r *Router) addRoute(method HttpMethod, handlerPackage *RequestHandlerPackage, policy DuplicatePolicy) ([]*RequestHandlerPackage, error) {
+	paths, err := expandOptionalParam(r.normalizeSlash(handlerPackage.Path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid route `%s %s`: %w", method, handlerPackage.Path, err)
+	}
+
+	// Routes ending with an optional parameter are stored once with and once without it
+	template := *handlerPackage
+	registered := []*RequestHandlerPackage{}
+	for i, path := range paths {
+		if i > 0 {
+			expanded := template
+			expanded.expanded = true
+			handlerPackage = &expanded
+		}
+		if err := r.storeRoute(method, handlerPackage, path, policy); err != nil {
+			return registered, err
+		}
+		registered = append(registered, handlerPackage)
+	}
+	return registered, nil
+}
+
+func (r *Router) storeRoute(method HttpMethod, handlerPackage *RequestHandlerPackage, path string, policy DuplicatePolicy) error {
+	parsed, err := parseRoutePath(path, handlerPackage.caseSensitive, handlerPackage.paramPattern)
+	if err != nil {
+		return fmt.Errorf("invalid route `%s %s`: %w", method, handlerPackage.Path, err)
+	}
+	r.cache.clear()
+	r.registrations++
+	handlerPackage.order = r.registrations
+	handlerPackage.method = method
+
+	// Not parametrized routes are stored as they are, no pattern matching needed
+	if len(parsed.paramNames) == 0 && handlerPackage.caseSensitive {
+		if r.exactRoutes[method] == nil {
+			r.exactRoutes[method] = make(map[string]*RequestHandlerPackage)
+		}
+		return storeHandler(policy, method, r.exactRoutes[method], path, handlerPackage)
+	}
+	if len(parsed.paramNames) == 0 {
+		if r.staticRoutes[method] == nil {
+			r.staticRoutes[method] = make(map[string]*RequestHandlerPackage)
 		}
+		return storeHandler(policy, method, r.staticRoutes[method], strings.ToLower(path), handlerPackage)
+	}
+
+	handlerPackage.ParamList = parsed.paramList
+	handlerPackage.Pattern = parsed.pattern
+	handlerPackage.paramNames = parsed.paramNames
+	handlerPackage.paramTypes = parsed.paramTypes
+	handlerPackage.paramPatterns = parsed.paramPatterns
+
+	node := r.tree.insert(parsed.segments)
+	return storeHandler(policy, method, node.handlers, method, handlerPackage)
+}
+
+// storeHandler saves the handler under the given key applying the duplicate policy. A method
+// specific handler is never replaced by a handler registered through Any, nor is that
+// considered a duplicate. The same goes for explicit HEAD handlers and the implicit ones.
+func storeHandler[K comparable](policy DuplicatePolicy, method HttpMethod, handlers map[K]*RequestHandlerPackage, key K, handlerPackage *RequestHandlerPackage) error {
+	registered, exists := handlers[key]
+	if exists && registered.anyMethod != handlerPackage.anyMethod {
+		if !registered.anyMethod {
+			return nil
+		}
+		exists = false
+	}
+	if exists && registered.implicitHead != handlerPackage.implicitHead {
+		if !registered.implicitHead {
+			return nil
+		}
+		exists = false
+	}
+
+	if !exists {
+		handlerPackage.candidates = []*RequestHandlerPackage{handlerPackage}
+		handlers[key] = handlerPackage
+		return nil
+	}
+
+	// Routes sharing method and pattern are told apart by their matchers, the last one
+	// registered without matchers being the fallback
+	if fallback := registered.fallback(); fallback != nil && policy != DuplicateOverwrite {
+		err := fmt.Errorf("%w: `%s %s` conflicts with `%s %s`", ErrDuplicateRoute, method, handlerPackage.Path, method, fallback.Path)
+		if policy == DuplicatePanic {
+			panic(err)
+		}
+		return err
+	}
+	registered.candidates = append(registered.candidates, handlerPackage)
+	return nil
+}
+
+// UnregisterRoute removes the route registered for the method and path, the path is read
+// like in RegisterRoute so `{userId}` removes a route registered as `{id}`. Removing a GET
+// route removes the HEAD one registered along with it. It reports whether a route was
+// removed.
+func (r *Router) UnregisterRoute(method HttpMethod, path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	paths, err := expandOptionalParam(r.normalizeSlash(path))
+	if err != nil {
+		return false
 	}
 
-	pathBuilder := strings.Builder{}
-	lastPos := 0
-	reqParamList := map[int]string{}
+	r.cache.clear()
+	removedAny := false
+	for _, path := range paths {
+		removed := r.removeRoute(method, path)
+		if removed == nil {
+			continue
+		}
+		removedAny = true
+		r.releaseRoute(removed)
 
-	for _, param := range paramList {
-		pathBuilder.WriteString(path[lastPos:param.start])
-		pathBuilder.WriteString("([a-z0-9-_]+)")
-		lastPos = param.end + 1
-		reqParamList[param.pos] = param.name
+		if head := r.findSlot(HEAD, path, removed.caseSensitive, removed.paramPattern); method == GET && head != nil && head.implicitHead {
+			r.releaseRoute(r.removeRoute(HEAD, path))
+		}
 	}
-	pathBuilder.WriteString(path[lastPos:])
-	newPath := pathBuilder.String()
-	if len(paramList) > 0 {
-		newPath = "^" + newPath + "$"
+	return removedAny
+}
+
+// releaseRoute drops the names and aliases of the removed routes
+func (r *Router) releaseRoute(removed *RequestHandlerPackage) {
+	for _, candidate := range removed.candidates {
+		if candidate.name != "" && r.namedRoutes[candidate.name] == candidate {
+			r.renameRoute(candidate.name)
+		}
+		r.removeAliases(candidate)
+		if primary := candidate.aliasOf; primary != nil {
+			primary.aliases = slices.DeleteFunc(primary.aliases, func(alias *RequestHandlerPackage) bool { return alias.Path == candidate.Path })
+		}
+	}
+}
+
+func (r *Router) removeRoute(method HttpMethod, path string) *RequestHandlerPackage {
+	// Routes may have been registered before or after toggling case sensitivity or changing
+	// the default parameter pattern
+	for _, caseSensitive := range []bool{r.caseSensitive, !r.caseSensitive} {
+		for _, paramPattern := range append([]string{""}, r.paramPatterns...) {
+			parsed, err := parseRoutePath(path, caseSensitive, paramPattern)
+			if err != nil {
+				continue
+			}
+
+			routes, key := r.staticRoutes[method], strings.ToLower(path)
+			if caseSensitive {
+				routes, key = r.exactRoutes[method], path
+			}
+			if handlerPackage, exists := routes[key]; len(parsed.paramNames) == 0 && exists {
+				delete(routes, key)
+				return handlerPackage
+			}
+
+			if handlerPackage := r.tree.remove(parsed.segments, method); handlerPackage != nil {
+				return handlerPackage
+			}
+		}
 	}
+	return nil
+}
 
-	r.routes[method][newPath] = RequestHandlerPackage{Handler: handler, ParamList: reqParamList, Params: make(Params)}
+// renameRoute points the name to another route still registered with it, if any
+func (r *Router) renameRoute(name string) {
+	delete(r.namedRoutes, name)
+	r.eachRoute(func(_ HttpMethod, handlerPackage *RequestHandlerPackage) {
+		if handlerPackage.name == name {
+			r.namedRoutes[name] = handlerPackage
+		}
+	})
 }
 
+// RegisteredRoutes returns a deep copy of the registered routes keyed by method and path,
+// changing it has no effect on the router.
+//
+// Deprecated: use Routes, which lists the routes in a stable order.
 func (r *Router) RegisteredRoutes() *RequestHandlerMap {
-	return &r.routes
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routes := make(RequestHandlerMap)
+	addRoute := func(method HttpMethod, handlerPackage *RequestHandlerPackage) {
+		if routes[method] == nil {
+			routes[method] = make(map[string]RequestHandlerPackage)
+		}
+		routes[method][handlerPackage.Path] = handlerPackage.clone()
+	}
+
+	r.eachRoute(addRoute)
+
+	return &routes
+}
+
+func (r *Router) eachRoute(fn func(method HttpMethod, handlerPackage *RequestHandlerPackage)) {
+	for _, routeMaps := range []map[HttpMethod]map[string]*RequestHandlerPackage{r.staticRoutes, r.exactRoutes} {
+		for method, staticRoutes := range routeMaps {
+			for _, handlerPackage := range staticRoutes {
+				handlerPackage.eachCandidate(method, fn)
+			}
+		}
+	}
+	r.tree.walk(func(method HttpMethod, handlerPackage *RequestHandlerPackage) {
+		handlerPackage.eachCandidate(method, fn)
+	})
+}
+
+// ----------- ROUTER OPTIONS -----------
+
+// EnableAutoOptions makes the router answer OPTIONS requests for registered paths with
+// a 204 and the Allow header, unless an OPTIONS handler was registered for the path.
+func (r *Router) EnableAutoOptions(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.autoOptions = enable
+	return r
+}
+
+// AutoHead makes HEAD requests without a dedicated handler fall back to the GET handler
+// of the same path, the body is discarded while status and headers are preserved.
+func (r *Router) AutoHead(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.autoHead = enable
+	return r
+}
+
+// AutoRegisterHead makes every GET route registered from now on register a HEAD route too,
+// serving the GET handler without the body. Unlike AutoHead the HEAD routes are part of the
+// route table, an explicit HEAD registration replaces them.
+func (r *Router) AutoRegisterHead(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.autoRegisterHead = enable
+	return r
+}
+
+// SetNotFoundHandler replaces the handler used for unmatched requests, a nil handler
+// restores the default plain text 404. It takes precedence over the fallback handler.
+func (r *Router) SetNotFoundHandler(handler HttpRequestHandler) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notFound = handler
+	return r
+}
+
+// SetMethodNotAllowedHandler replaces the handler used when the path exists under other
+// methods only. The router sets the Allow header before the handler runs, the allowed
+// methods can also be read with AllowedMethods. It takes precedence over the fallback handler.
+func (r *Router) SetMethodNotAllowedHandler(handler HttpRequestHandler) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methodNotAllowed = handler
+	return r
+}
+
+// SetFallback delegates the requests the router would answer with its default 404 or 405
+// to another http.Handler, e.g. a legacy mux during a migration. The fallback gets the
+// request as it was received and runs without the router middleware, a nil handler
+// removes it.
+func (r *Router) SetFallback(handler http.Handler) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = handler
+	return r
+}
+
+// RedirectTrailingSlash makes requests missing a route redirect to the same path with the
+// trailing slash added or removed, when that one matches. GET and HEAD requests get a 301,
+// other methods a 308 so that clients repeat the request with the same method and body.
+func (r *Router) RedirectTrailingSlash(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trailingSlash = enable
+	return r
+}
+
+// UseRawPath sets whether the router matches the escaped path, as it does by default: the
+// escaped path is split on `/`, then every segment is decoded once and matched, literals and
+// parameter patterns alike. An encoded slash stays inside its segment, `/repos/{name:.+}`
+// matches `/repos/org%2Fproject` with name `org/project` while `/repos/{name}` does not. When
+// disabled the decoded path is matched and `%2F` separates segments like `/`.
+func (r *Router) UseRawPath(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.useRawPath = enable
+	r.cache.clear()
+	return r
+}
+
+// EnableRouteCache keeps the result of the last size parametrized route lookups, keyed by
+// method and path, so that hot paths skip the tree walk. The cache is emptied whenever a
+// route is registered or removed, a size of 0 disables it. It is not used by routers with
+// route priorities, their lookup considering every matching route.
+func (r *Router) EnableRouteCache(size int) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = nil
+	if size > 0 {
+		r.cache = newRouteCache(size)
+	}
+	return r
+}
+
+// StrictSlash sets whether the trailing slash is significant, as it is by default so that
+// `/users` and `/users/` are distinct routes. When disabled the trailing slash is dropped
+// from the routes registered from now on and requests match with or without it.
+func (r *Router) StrictSlash(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ignoreSlash = !enable
+	return r
+}
+
+// CaseSensitive makes the routes registered from now on match the request path case
+// sensitively, routes are case insensitive by default so that `/Users/123` matches
+// `/users/{id}`. Routes registered before the call keep their behavior.
+func (r *Router) CaseSensitive(enable bool) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.caseSensitive = enable
+	return r
+}
+
+// DefaultParamPattern sets the pattern of the parameters without constraint for the routes
+// registered from now on, e.g. `[^/]+` (the default) or `[a-z0-9-]+`. Routes registered
+// before the call keep their pattern, an invalid pattern makes the registrations fail.
+func (r *Router) DefaultParamPattern(pattern string) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paramPattern = pattern
+	if pattern != "" && !slices.Contains(r.paramPatterns, pattern) {
+		r.paramPatterns = append(r.paramPatterns, pattern)
+	}
+	return r
+}
+
+// OnDuplicate sets what happens when a route is registered again for the same method and
+// pattern, `{id}` and `{userId}` at the same position being the same pattern. The default
+// DuplicateOverwrite replaces the previous handler.
+func (r *Router) OnDuplicate(policy DuplicatePolicy) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.duplicatePolicy = policy
+	return r
 }
 
 // ----------- DEFALUT HANDLERS -----------
 
+// handlerOr returns the handler, the default one when it's not set
+func handlerOr(handler HttpRequestHandler, defaultHandler HttpRequestHandler) HttpRequestHandler {
+	if handler == nil {
+		return defaultHandler
+	}
+	return handler
+}
+
 func routeNotFoundHandler(req *http.Request, _ Params) *HttpResponse {
 	return NewHttpResponse(http.StatusNotFound).
 		SetHeader("Content-Type", "text/plain").
 		SetBody("404 - Page not found")
 }
 
+func methodNotAllowedHandler(req *http.Request, _ Params) *HttpResponse {
+	return NewHttpResponse(http.StatusMethodNotAllowed).
+		SetHeader("Content-Type", "text/plain").
+		SetBody("405 - Method not allowed")
+}
+
+func badRequestHandler(req *http.Request, _ Params) *HttpResponse {
+	return NewHttpResponse(http.StatusBadRequest).
+		SetHeader("Content-Type", "text/plain").
+		SetBody("400 - Bad request")
+}
+
+func uriTooLongHandler(req *http.Request, _ Params) *HttpResponse {
+	return NewHttpResponse(http.StatusRequestURITooLong).
+		SetHeader("Content-Type", "text/plain").
+		SetBody("414 - URI too long")
+}
+
+func timeoutHandler(req *http.Request, _ Params) *HttpResponse {
+	return NewHttpResponse(http.StatusServiceUnavailable).
+		SetHeader("Content-Type", "text/plain").
+		SetBody("503 - Service unavailable")
+}
+
+func unsupportedMediaTypeHandler(req *http.Request, _ Params) *HttpResponse {
+	return NewHttpResponse(http.StatusUnsupportedMediaType).
+		SetHeader("Content-Type", "text/plain").
+		SetBody("415 - Unsupported media type")
+}
+
+func notAcceptableHandler(req *http.Request, _ Params) *HttpResponse {
+	return NewHttpResponse(http.StatusNotAcceptable).
+		SetHeader("Content-Type", "text/plain").
+		SetBody("406 - Not acceptable")
+}
+
+func autoOptionsHandler(req *http.Request, _ Params) *HttpResponse {
+	return NewHttpResponse(http.StatusNoContent)
+}
+
+func redirectHandler(method HttpMethod, location string) HttpRequestHandler {
+	status := http.StatusPermanentRedirect
+	if method == GET || method == HEAD {
+		status = http.StatusMovedPermanently
+	}
+
+	return func(req *http.Request, _ Params) *HttpResponse {
+		if req.URL.RawQuery != "" {
+			return NewHttpResponse(status).SetHeader("Location", location+"?"+req.URL.RawQuery)
+		}
+		return NewHttpResponse(status).SetHeader("Location", location)
+	}
+}
+
+func headHandler(getHandler HttpRequestHandler) HttpRequestHandler {
+	return func(req *http.Request, params Params) *HttpResponse {
+		response := getHandler(req, params)
+		if writer := response.writer; writer != nil {
+			response.writer = func(rw http.ResponseWriter) {
+				writer(bodylessResponseWriter{rw})
+			}
+			return response
+		}
+		if _, found := response.headers["Content-Length"]; !found {
+			response.SetHeader("Content-Length", strconv.Itoa(len(response.body)))
+		}
+		return response.SetBody("")
+	}
+}
+
 // ----------- HELPERS -----------
+func adaptHandler(handler http.Handler) HttpRequestHandler {
+	return func(req *http.Request, _ Params) *HttpResponse {
+		return delegatedResponse(handler, req)
+	}
+}
+
+type bodylessResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w bodylessResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+// normalizeSlash drops the trailing slash of a registered path unless slashes are strict
+func (r *Router) normalizeSlash(path string) string {
+	if r.ignoreSlash && len(path) > 1 {
+		return strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+func toggleTrailingSlash(path string) (string, bool) {
+	if path == "/" || path == "" {
+		return "", false
+	}
+	if trimmed, found := strings.CutSuffix(path, "/"); found {
+		return trimmed, true
+	}
+	return path + "/", true
+}
+
+func joinMethods(methods []HttpMethod) string {
+	names := make([]string, len(methods))
+	for i, method := range methods {
+		names[i] = string(method)
+	}
+	return strings.Join(names, ", ")
+}
+
 func debugRequest(_ http.ResponseWriter, req *http.Request) {
+	// Checked first since boxing the arguments allocates even when nothing is logged
+	if Log.GetLogLvlIntValue() < log_level.DebugLvl {
+		return
+	}
 	Log.Debug("Received request:", req.Method, req.URL.Path)
 }
 
 // ----------- CONSTRUCTOR -----------
 func NewRouter() *Router {
 	return &Router{
-		routes: make(RequestHandlerMap),
+		staticRoutes:    make(map[HttpMethod]map[string]*RequestHandlerPackage),
+		exactRoutes:     make(map[HttpMethod]map[string]*RequestHandlerPackage),
+		namedRoutes:     make(map[string]*RequestHandlerPackage),
+		tree:            newRouteNode(nil),
+		useRawPath:      true,
+		maxPathLength:   DefaultMaxPathLength,
+		maxPathSegments: DefaultMaxPathSegments,
 	}
 }
@@ -1,8 +1,14 @@
 package yagaw
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -27,6 +33,115 @@ func TestRegisterRoute(t *testing.T) {
 	}
 }
 
+func TestRegisterRouteRegexMetacharacters(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).
+			SetBody("matched")
+	}
+
+	routes := []string{
+		"/files/v1.2/report",
+		"/search/c++",
+		"/calc/(sum)",
+		"/price/$10",
+		"/broken/(",
+		"/files/{id}/v1.2",
+	}
+	for _, route := range routes {
+		if err := router.RegisterRoute(GET, route, handler).Err(); err != nil {
+			t.Fatalf("unexpected error registering %q: %v", route, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		shouldAct bool
+	}{
+		{"literal dot", "/files/v1.2/report", true},
+		{"dot is not a wildcard", "/files/v1X2/report", false},
+		{"literal plus signs", "/search/c++", true},
+		{"plus is not a repetition", "/search/cc", false},
+		{"literal parentheses", "/calc/(sum)", true},
+		{"parentheses are not a group", "/calc/sum", false},
+		{"literal dollar sign", "/price/$10", true},
+		{"dollar is not an anchor", "/price/10", false},
+		{"unbalanced parenthesis", "/broken/(", true},
+		{"literal dot after a parameter", "/files/123/v1.2", true},
+		{"dot after a parameter is not a wildcard", "/files/123/v1X2", false},
+		{"literal route is not a prefix", "/files/v1.2/report/extra", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(string(GET), tt.path, nil)
+			rw := httptest.NewRecorder()
+
+			router.ServeHTTP(rw, req)
+
+			if tt.shouldAct {
+				if rw.Code != http.StatusOK {
+					t.Errorf("expected status 200, got %d", rw.Code)
+				}
+			} else {
+				if rw.Code != http.StatusNotFound {
+					t.Errorf("expected 404, got %d", rw.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisteredRoutesReportsStaticAndDynamicRoutes(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/users", handler)
+	router.RegisterRoute(GET, "/users/{id}", handler)
+
+	routes := *router.RegisteredRoutes()
+
+	static, exists := routes[GET]["/users"]
+	if !exists {
+		t.Fatal("static route should be reported by its registered path")
+	}
+	if static.Pattern != nil {
+		t.Error("static route should not carry a pattern")
+	}
+
+	dynamic, exists := routes[GET]["/users/{id}"]
+	if !exists {
+		t.Fatal("dynamic route should be reported by its registered path")
+	}
+	if dynamic.Pattern == nil {
+		t.Error("dynamic route should carry its compiled pattern")
+	}
+}
+
+func TestServeHTTPExactPathIgnoresCase(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/Users/Me", handler)
+
+	for _, path := range []string{"/Users/Me", "/users/me", "/USERS/ME"} {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+
+		if rw.Code != http.StatusOK {
+			t.Errorf("expected status 200 for %q, got %d", path, rw.Code)
+		}
+	}
+}
+
 func TestServeHTTPExactPath(t *testing.T) {
 	router := NewRouter()
 
@@ -200,8 +315,53 @@ func TestServeHTTPMethodNotFound(t *testing.T) {
 
 	router.ServeHTTP(rw, req)
 
-	if rw.Code != http.StatusNotFound {
-		t.Errorf("expected 404 for unsupported method, got %d", rw.Code)
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for unsupported method, got %d", rw.Code)
+	}
+	if allow := rw.Header().Get("Allow"); allow != "GET" {
+		t.Errorf("expected Allow header 'GET', got %q", allow)
+	}
+}
+
+func TestServeHTTPMethodNotAllowed(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/resource", handler)
+	router.RegisterRoute(POST, "/resource", handler)
+	router.RegisterRoute(GET, "/users/{id}", handler)
+	router.RegisterRoute(PUT, "/users/{userId}", handler)
+	router.RegisterRoute(PATCH, "/users/me", handler)
+
+	tests := []struct {
+		name   string
+		method HttpMethod
+		path   string
+		status int
+		allow  string
+	}{
+		{"static route", DELETE, "/resource", http.StatusMethodNotAllowed, "GET, POST"},
+		{"parameterized route", DELETE, "/users/123", http.StatusMethodNotAllowed, "GET, PUT"},
+		{"static and parameterized routes", DELETE, "/users/me", http.StatusMethodNotAllowed, "GET, PUT, PATCH"},
+		{"registered method still served", POST, "/resource", http.StatusOK, ""},
+		{"unknown path", DELETE, "/unknown", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if allow := rw.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("expected Allow header %q, got %q", tt.allow, allow)
+			}
+		})
 	}
 }
 
@@ -294,6 +454,53 @@ func BenchmarkServeHTTPExact(b *testing.B) {
 	}
 }
 
+// discardResponseWriter keeps the allocations of the recorder out of the measures
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w discardResponseWriter) Header() http.Header         { return w.header }
+func (w discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w discardResponseWriter) WriteHeader(int)             {}
+
+// WriteString is implemented by the net/http response writer as well
+func (w discardResponseWriter) WriteString(s string) (int, error) { return len(s), nil }
+
+// The handler response is built once so that only the router work is measured
+func newExactMatchRouter() (*Router, *http.Request, http.ResponseWriter) {
+	response := NewHttpResponse(http.StatusOK).SetBody("users")
+	router := NewRouter()
+	router.RegisterRoute(GET, "/users", func(req *http.Request, params Params) *HttpResponse { return response })
+	router.CaseSensitive(true).RegisterRoute(GET, "/Exact", func(req *http.Request, params Params) *HttpResponse { return response })
+	router.RegisterRoute(GET, "/users/{id}", func(req *http.Request, params Params) *HttpResponse { return response })
+
+	return router, httptest.NewRequest(string(GET), "/users", nil), discardResponseWriter{http.Header{}}
+}
+
+func TestServeHTTPExactMatchDoesNotAllocate(t *testing.T) {
+	router, req, rw := newExactMatchRouter()
+
+	for _, path := range []string{"/users", "/USERS", "/Exact"} {
+		req.URL.Path = path
+		if allocs := testing.AllocsPerRun(100, func() { router.ServeHTTP(rw, req) }); allocs != 0 {
+			t.Errorf("expected no allocation serving %s, got %v per request", path, allocs)
+		}
+	}
+}
+
+func BenchmarkServeHTTPExactRouterOnly(b *testing.B) {
+	router, req, rw := newExactMatchRouter()
+	if allocs := testing.AllocsPerRun(100, func() { router.ServeHTTP(rw, req) }); allocs != 0 {
+		b.Fatalf("expected no allocation per request, got %v", allocs)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(rw, req)
+	}
+}
+
 func BenchmarkServeHTTPPattern(b *testing.B) {
 	router := NewRouter()
 	handler := func(req *http.Request, params Params) *HttpResponse {
@@ -309,3 +516,1189 @@ func BenchmarkServeHTTPPattern(b *testing.B) {
 		router.ServeHTTP(rw, req)
 	}
 }
+
+func BenchmarkServeHTTPPatternManyRoutes(b *testing.B) {
+	router := NewRouter()
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+	for i := 0; i < 50; i++ {
+		router.RegisterRoute(GET, fmt.Sprintf("/resource%d/{id}", i), handler)
+	}
+
+	req := httptest.NewRequest(string(GET), "/resource49/123", nil)
+	rw := httptest.NewRecorder()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(rw, req)
+	}
+}
+
+func TestAutoOptions(t *testing.T) {
+	router := NewRouter().EnableAutoOptions(true)
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("handled")
+	}
+	optionsHandler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("custom options")
+	}
+
+	router.RegisterRoute(GET, "/resource", handler)
+	router.RegisterRoute(POST, "/resource", handler)
+	router.RegisterRoute(GET, "/users/{id}", handler)
+	router.RegisterRoute(DELETE, "/users/{id}", handler)
+	router.RegisterRoute(GET, "/custom", handler)
+	router.RegisterRoute(OPTIONS, "/custom", optionsHandler)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		allow  string
+		body   string
+	}{
+		{"static route", "/resource", http.StatusNoContent, "GET, OPTIONS, POST", ""},
+		{"parameterized route", "/users/123", http.StatusNoContent, "GET, OPTIONS, DELETE", ""},
+		{"explicit handler wins", "/custom", http.StatusOK, "", "custom options"},
+		{"unknown path", "/unknown", http.StatusNotFound, "", "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(OPTIONS), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if allow := rw.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("expected Allow header %q, got %q", tt.allow, allow)
+			}
+			if rw.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestAutoOptionsDisabledByDefault(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/resource", handler)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(OPTIONS), "/resource", nil))
+
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rw.Code)
+	}
+	if allow := rw.Header().Get("Allow"); allow != "GET" {
+		t.Errorf("expected Allow header 'GET', got %q", allow)
+	}
+}
+
+func TestAutoHead(t *testing.T) {
+	router := NewRouter().AutoHead(true)
+
+	getHandler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusAccepted).
+			SetHeader("Content-Type", "application/json").
+			SetBody(`{"id":"` + PathParam(req, "id") + `"}`)
+	}
+	headHandler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).
+			SetHeader("X-Explicit", "true")
+	}
+
+	router.RegisterRoute(GET, "/users", getHandler)
+	router.RegisterRoute(GET, "/users/{id}", getHandler)
+	router.RegisterRoute(GET, "/explicit", getHandler)
+	router.RegisterRoute(HEAD, "/explicit", headHandler)
+
+	tests := []struct {
+		name          string
+		path          string
+		status        int
+		contentLength string
+	}{
+		{"static route", "/users", http.StatusAccepted, "9"},
+		{"parameterized route", "/users/42", http.StatusAccepted, "11"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(HEAD), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if rw.Body.Len() != 0 {
+				t.Errorf("expected an empty body, got %q", rw.Body.String())
+			}
+			if contentType := rw.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("expected Content-Type 'application/json', got %q", contentType)
+			}
+			if contentLength := rw.Header().Get("Content-Length"); contentLength != tt.contentLength {
+				t.Errorf("expected Content-Length %q, got %q", tt.contentLength, contentLength)
+			}
+		})
+	}
+
+	t.Run("explicit HEAD handler wins", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(HEAD), "/explicit", nil))
+
+		if rw.Code != http.StatusOK || rw.Header().Get("X-Explicit") != "true" {
+			t.Errorf("expected the explicit HEAD handler to run, got status %d", rw.Code)
+		}
+	})
+
+	t.Run("HEAD listed as allowed", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(DELETE), "/users", nil))
+
+		if allow := rw.Header().Get("Allow"); allow != "GET, HEAD" {
+			t.Errorf("expected Allow header 'GET, HEAD', got %q", allow)
+		}
+	})
+}
+
+func TestAutoRegisterHead(t *testing.T) {
+	router := NewRouter().AutoRegisterHead(true)
+
+	getHandler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusAccepted).
+			SetHeader("Content-Type", "application/json").
+			SetBody(`{"id":"` + PathParam(req, "id") + `"}`)
+	}
+	headHandler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).
+			SetHeader("X-Explicit", "true")
+	}
+
+	router.RegisterRoute(GET, "/users", getHandler)
+	router.RegisterRoute(GET, "/users/{id}", getHandler).Name("user")
+	router.RegisterRoute(GET, "/explicit", getHandler)
+	router.RegisterRoute(HEAD, "/explicit", headHandler)
+	router.RegisterRoute(HEAD, "/before", headHandler)
+	router.RegisterRoute(GET, "/before", getHandler)
+
+	tests := []struct {
+		path          string
+		status        int
+		contentLength string
+		explicit      string
+	}{
+		{"/users", http.StatusAccepted, "9", ""},
+		{"/users/42", http.StatusAccepted, "11", ""},
+		{"/explicit", http.StatusOK, "", "true"},
+		{"/before", http.StatusOK, "", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(HEAD), tt.path, nil))
+
+			if rw.Code != tt.status || rw.Body.Len() != 0 {
+				t.Errorf("expected status %d with an empty body, got %d %q", tt.status, rw.Code, rw.Body.String())
+			}
+			if contentLength := rw.Header().Get("Content-Length"); contentLength != tt.contentLength {
+				t.Errorf("expected Content-Length %q, got %q", tt.contentLength, contentLength)
+			}
+			if explicit := rw.Header().Get("X-Explicit"); explicit != tt.explicit {
+				t.Errorf("expected X-Explicit %q, got %q", tt.explicit, explicit)
+			}
+		})
+	}
+
+	t.Run("listed as routes", func(t *testing.T) {
+		heads := []string{}
+		for _, route := range router.Routes() {
+			if route.Method == HEAD {
+				heads = append(heads, route.Path+" "+route.Name)
+			}
+		}
+		expected := []string{"/users ", "/users/{id} user", "/explicit ", "/before "}
+		if !slices.Equal(heads, expected) {
+			t.Errorf("expected HEAD routes %q, got %q", expected, heads)
+		}
+	})
+
+	t.Run("HEAD listed as allowed", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(DELETE), "/users/42", nil))
+
+		if allow := rw.Header().Get("Allow"); allow != "GET, HEAD" {
+			t.Errorf("expected Allow header 'GET, HEAD', got %q", allow)
+		}
+	})
+
+	t.Run("unregister GET", func(t *testing.T) {
+		router.UnregisterRoute(GET, "/users/{id}")
+		router.UnregisterRoute(GET, "/explicit")
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(HEAD), "/users/42", nil))
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("expected the implicit HEAD route to be removed, got %d", rw.Code)
+		}
+		rw = httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(HEAD), "/explicit", nil))
+		if rw.Code != http.StatusOK || rw.Header().Get("X-Explicit") != "true" {
+			t.Errorf("expected the explicit HEAD route to be kept, got %d", rw.Code)
+		}
+	})
+}
+
+func TestAutoHeadDisabledByDefault(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("body")
+	}
+
+	router.RegisterRoute(GET, "/users", handler)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(HEAD), "/users", nil))
+
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rw.Code)
+	}
+}
+
+func TestSetNotFoundHandler(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+	notFoundHandler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusNotFound).
+			SetHeader("Content-Type", "application/json").
+			SetBody(`{"error":"not_found"}`)
+	}
+
+	router.RegisterRoute(GET, "/users/{id}", handler)
+	router.SetNotFoundHandler(notFoundHandler)
+
+	tests := []struct {
+		name   string
+		method HttpMethod
+		path   string
+	}{
+		{"unknown path", GET, "/posts"},
+		{"method without any route", PATCH, "/posts"},
+		{"unmatched parameter", GET, "/users/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+			if rw.Code != http.StatusNotFound {
+				t.Errorf("expected 404, got %d", rw.Code)
+			}
+			if contentType := rw.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("expected Content-Type 'application/json', got %q", contentType)
+			}
+			if rw.Body.String() != `{"error":"not_found"}` {
+				t.Errorf("expected the custom body, got %q", rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("nil restores the default", func(t *testing.T) {
+		router.SetNotFoundHandler(nil)
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/posts", nil))
+
+		if rw.Body.String() != "404 - Page not found" {
+			t.Errorf("expected the default body, got %q", rw.Body.String())
+		}
+	})
+}
+
+func TestSetMethodNotAllowedHandler(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	var receivedMethod, receivedPath string
+	methodNotAllowedHandler := func(req *http.Request, params Params) *HttpResponse {
+		receivedMethod, receivedPath = req.Method, req.URL.Path
+		return NewHttpResponse(http.StatusMethodNotAllowed).
+			SetHeader("Content-Type", "application/json").
+			SetBody(fmt.Sprintf(`{"error":"method_not_allowed","allowed":%q}`, joinMethods(AllowedMethods(req))))
+	}
+
+	router.RegisterRoute(GET, "/resource", handler)
+	router.RegisterRoute(POST, "/resource", handler)
+	router.SetMethodNotAllowedHandler(methodNotAllowedHandler)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(DELETE), "/resource", nil))
+
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rw.Code)
+	}
+	if allow := rw.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("expected Allow header 'GET, POST', got %q", allow)
+	}
+	if rw.Body.String() != `{"error":"method_not_allowed","allowed":"GET, POST"}` {
+		t.Errorf("unexpected body %q", rw.Body.String())
+	}
+	if receivedMethod != string(DELETE) || receivedPath != "/resource" {
+		t.Errorf("expected the original request, got %s %s", receivedMethod, receivedPath)
+	}
+
+	t.Run("nil restores the default", func(t *testing.T) {
+		router.SetMethodNotAllowedHandler(nil)
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(DELETE), "/resource", nil))
+
+		if rw.Header().Get("Content-Type") != "text/plain" || rw.Body.String() != "405 - Method not allowed" {
+			t.Errorf("expected the default plain text body, got %q", rw.Body.String())
+		}
+		if allow := rw.Header().Get("Allow"); allow != "GET, POST" {
+			t.Errorf("expected Allow header 'GET, POST', got %q", allow)
+		}
+	})
+}
+
+func TestVerbHelpers(t *testing.T) {
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + ":" + PathParam(req, "id"))
+		}
+	}
+
+	router := NewRouter()
+	router.
+		Get("/items/{id}", handlerFor("GET")).
+		Post("/items/{id}", handlerFor("POST")).
+		Put("/items/{id}", handlerFor("PUT")).
+		Patch("/items/{id}", handlerFor("PATCH")).
+		Delete("/items/{id}", handlerFor("DELETE")).
+		Head("/items/{id}", handlerFor("HEAD")).
+		Options("/items/{id}", handlerFor("OPTIONS"))
+
+	group := NewRouter()
+	group.Group("/api").
+		Get("/items/{id}", handlerFor("GET")).
+		Post("/items/{id}", handlerFor("POST")).
+		Put("/items/{id}", handlerFor("PUT")).
+		Patch("/items/{id}", handlerFor("PATCH")).
+		Delete("/items/{id}", handlerFor("DELETE")).
+		Head("/items/{id}", handlerFor("HEAD")).
+		Options("/items/{id}", handlerFor("OPTIONS"))
+
+	methods := []HttpMethod{GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS}
+	targets := []struct {
+		name   string
+		router *Router
+		path   string
+	}{
+		{"router", router, "/items/7"},
+		{"group", group, "/api/items/7"},
+	}
+
+	for _, target := range targets {
+		for _, method := range methods {
+			t.Run(target.name+" "+string(method), func(t *testing.T) {
+				rw := httptest.NewRecorder()
+				target.router.ServeHTTP(rw, httptest.NewRequest(string(method), target.path, nil))
+
+				if rw.Code != http.StatusOK {
+					t.Fatalf("expected status 200, got %d", rw.Code)
+				}
+				if expected := string(method) + ":7"; rw.Body.String() != expected {
+					t.Errorf("expected %q, got %q", expected, rw.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestAny(t *testing.T) {
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(DELETE, "/health", handlerFor("delete"))
+	router.Any("/health", handlerFor("any"))
+	router.RegisterRoute(POST, "/health", handlerFor("post"))
+	router.Any("/proxy/{id}", handlerFor("any proxy"))
+	router.RegisterRoute(PUT, "/proxy/{name}", handlerFor("put proxy"))
+
+	tests := []struct {
+		method   HttpMethod
+		path     string
+		expected string
+	}{
+		{GET, "/health", "any"},
+		{PATCH, "/health", "any"},
+		{CONNECT, "/health", "any"},
+		{POST, "/health", "post"},
+		{DELETE, "/health", "delete"},
+		{GET, "/proxy/1", "any proxy"},
+		{PUT, "/proxy/1", "put proxy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method)+" "+tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+			if rw.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("registered routes are expanded", func(t *testing.T) {
+		routes := *router.RegisteredRoutes()
+		for _, method := range HttpMethods {
+			if _, exists := routes[method]["/health"]; !exists {
+				t.Errorf("expected %s /health to be registered", method)
+			}
+		}
+	})
+
+	t.Run("a later Any does not replace method specific routes", func(t *testing.T) {
+		router.Any("/health", handlerFor("any again"))
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(POST), "/health", nil))
+		if rw.Body.String() != "post" {
+			t.Errorf("expected 'post', got %q", rw.Body.String())
+		}
+
+		rw = httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/health", nil))
+		if rw.Body.String() != "any again" {
+			t.Errorf("expected 'any again', got %q", rw.Body.String())
+		}
+	})
+}
+
+func TestHandle(t *testing.T) {
+	router := NewRouter().AutoHead(true)
+
+	var nativeReq, standardReq *http.Request
+	native := func(req *http.Request, params Params) *HttpResponse {
+		nativeReq = req
+		return NewHttpResponse(http.StatusOK)
+	}
+	standard := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		standardReq = req
+		rw.Header().Set("Content-Type", "text/plain")
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte("user " + PathParam(req, "id")))
+	})
+
+	router.RegisterRoute(GET, "/native/{id}", native)
+	router.Handle(GET, "/standard/{id}", standard)
+	router.HandleFunc(POST, "/func/{id}", standard)
+	router.Group("/api").Handle(GET, "/users/{id}", standard)
+
+	tests := []struct {
+		method HttpMethod
+		path   string
+		body   string
+	}{
+		{GET, "/standard/42", "user 42"},
+		{POST, "/func/7", "user 7"},
+		{GET, "/api/users/3", "user 3"},
+		{HEAD, "/standard/42", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method)+" "+tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+			if rw.Code != http.StatusCreated {
+				t.Errorf("expected status 201, got %d", rw.Code)
+			}
+			if rw.Header().Get("Content-Type") != "text/plain" {
+				t.Errorf("expected Content-Type 'text/plain', got %q", rw.Header().Get("Content-Type"))
+			}
+			if rw.Body.String() != tt.body {
+				t.Errorf("expected %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("same request as a native handler", func(t *testing.T) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(string(GET), "/native/1", nil))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(string(GET), "/standard/1", nil))
+
+		if nativeReq.Method != standardReq.Method || PathParam(nativeReq, "id") != PathParam(standardReq, "id") {
+			t.Errorf("expected both handlers to see the same method and params")
+		}
+		if len(PathParams(nativeReq)) != len(PathParams(standardReq)) {
+			t.Errorf("expected the same params, got %v and %v", PathParams(nativeReq), PathParams(standardReq))
+		}
+	})
+}
+
+func TestOnDuplicate(t *testing.T) {
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+	serve := func(router *Router, path string) string {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+		return rw.Body.String()
+	}
+
+	duplicates := []struct {
+		name   string
+		first  string
+		second string
+		path   string
+	}{
+		{"static route", "/users", "/users", "/users"},
+		{"parameterized route", "/users/{id}", "/users/{id}", "/users/1"},
+		{"different parameter names", "/users/{id}", "/users/{userId}", "/users/1"},
+		{"same constraint", "/orders/{id:int}", "/orders/{orderId:int}", "/orders/1"},
+	}
+
+	for _, tt := range duplicates {
+		t.Run("overwrite "+tt.name, func(t *testing.T) {
+			router := NewRouter()
+			router.RegisterRoute(GET, tt.first, handlerFor("first"))
+
+			if err := router.RegisterRoute(GET, tt.second, handlerFor("second")).Err(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if body := serve(router, tt.path); body != "second" {
+				t.Errorf("expected the second handler to win, got %q", body)
+			}
+		})
+
+		t.Run("error "+tt.name, func(t *testing.T) {
+			router := NewRouter().OnDuplicate(DuplicateError)
+			router.RegisterRoute(GET, tt.first, handlerFor("first"))
+
+			err := router.RegisterRoute(GET, tt.second, handlerFor("second")).Err()
+			if !errors.Is(err, ErrDuplicateRoute) {
+				t.Fatalf("expected ErrDuplicateRoute, got %v", err)
+			}
+			if body := serve(router, tt.path); body != "first" {
+				t.Errorf("expected the first handler to be kept, got %q", body)
+			}
+		})
+
+		t.Run("panic "+tt.name, func(t *testing.T) {
+			router := NewRouter().OnDuplicate(DuplicatePanic)
+			router.RegisterRoute(GET, tt.first, handlerFor("first"))
+
+			defer func() {
+				recovered := recover()
+				err, isError := recovered.(error)
+				if !isError || !errors.Is(err, ErrDuplicateRoute) {
+					t.Errorf("expected a panic with ErrDuplicateRoute, got %v", recovered)
+				}
+			}()
+			router.RegisterRoute(GET, tt.second, handlerFor("second"))
+		})
+	}
+
+	t.Run("not duplicates", func(t *testing.T) {
+		router := NewRouter().OnDuplicate(DuplicateError)
+
+		registrations := []struct {
+			method HttpMethod
+			path   string
+		}{
+			{GET, "/users/{id}"},
+			{POST, "/users/{id}"},
+			{GET, "/users/{id:int}"},
+			{GET, "/users/me"},
+			{GET, "/users/{*rest}"},
+		}
+		for _, registration := range registrations {
+			if err := router.RegisterRoute(registration.method, registration.path, handlerFor("")).Err(); err != nil {
+				t.Errorf("unexpected error for %s %s: %v", registration.method, registration.path, err)
+			}
+		}
+		if err := router.Any("/users/{id}", handlerFor("")).Err(); err != nil {
+			t.Errorf("unexpected error registering Any over method specific routes: %v", err)
+		}
+	})
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+
+	router := NewRouter().RedirectTrailingSlash(true)
+	router.RegisterRoute(GET, "/users", handler("users"))
+	router.RegisterRoute(POST, "/users", handler("create"))
+	router.RegisterRoute(GET, "/docs/", handler("docs"))
+	router.RegisterRoute(GET, "/users/{id}", handler("user"))
+	router.RegisterRoute(GET, "/both", handler("without"))
+	router.RegisterRoute(GET, "/both/", handler("with"))
+
+	tests := []struct {
+		method   HttpMethod
+		path     string
+		status   int
+		location string
+		body     string
+	}{
+		{GET, "/users/", http.StatusMovedPermanently, "/users", ""},
+		{GET, "/users/?page=2", http.StatusMovedPermanently, "/users?page=2", ""},
+		{POST, "/users/", http.StatusPermanentRedirect, "/users", ""},
+		{GET, "/docs", http.StatusMovedPermanently, "/docs/", ""},
+		{GET, "/users/42/", http.StatusMovedPermanently, "/users/42", ""},
+		{GET, "/both", http.StatusOK, "", "without"},
+		{GET, "/both/", http.StatusOK, "", "with"},
+		{GET, "/missing/", http.StatusNotFound, "", "404 - Page not found"},
+		{PUT, "/users/", http.StatusNotFound, "", "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method)+" "+tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if location := rw.Header().Get("Location"); location != tt.location {
+				t.Errorf("expected Location %q, got %q", tt.location, location)
+			}
+			if rw.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		router := NewRouter()
+		router.RegisterRoute(GET, "/users", handler("users"))
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/users/", nil))
+
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("expected status 404, got %d", rw.Code)
+		}
+	})
+}
+
+func TestCaseSensitive(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "key"))
+	}
+
+	insensitive := NewRouter()
+	insensitive.RegisterRoute(GET, "/Status", handler)
+	insensitive.RegisterRoute(GET, "/objects/{key}", handler)
+
+	sensitive := NewRouter().CaseSensitive(true)
+	sensitive.RegisterRoute(GET, "/Status", handler)
+	sensitive.RegisterRoute(GET, "/objects/{key}", handler)
+	sensitive.RegisterRoute(GET, "/Files/{key}", handler)
+
+	tests := []struct {
+		name   string
+		router *Router
+		path   string
+		status int
+		body   string
+	}{
+		{"insensitive static exact case", insensitive, "/Status", http.StatusOK, ""},
+		{"insensitive static other case", insensitive, "/STATUS", http.StatusOK, ""},
+		{"insensitive dynamic other case", insensitive, "/OBJECTS/AbC", http.StatusOK, "AbC"},
+		{"sensitive static exact case", sensitive, "/Status", http.StatusOK, ""},
+		{"sensitive static other case", sensitive, "/status", http.StatusNotFound, "404 - Page not found"},
+		{"sensitive dynamic exact case", sensitive, "/objects/AbC-_09", http.StatusOK, "AbC-_09"},
+		{"sensitive dynamic other case", sensitive, "/Objects/AbC", http.StatusNotFound, "404 - Page not found"},
+		{"sensitive literal before parameter", sensitive, "/Files/x", http.StatusOK, "x"},
+		{"sensitive literal other case", sensitive, "/files/x", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			tt.router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if rw.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("applies to routes registered after the call", func(t *testing.T) {
+		router := NewRouter()
+		router.RegisterRoute(GET, "/before", handler)
+		router.CaseSensitive(true)
+		router.RegisterRoute(GET, "/after", handler)
+
+		for path, status := range map[string]int{"/BEFORE": http.StatusOK, "/AFTER": http.StatusNotFound, "/after": http.StatusOK} {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+			if rw.Code != status {
+				t.Errorf("expected status %d for %s, got %d", status, path, rw.Code)
+			}
+		}
+		if len((*router.RegisteredRoutes())[GET]) != 2 {
+			t.Error("expected both routes to be listed")
+		}
+	})
+}
+
+func TestConcurrentRegistrationAndServing(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+	router.RegisterRoute(GET, "/ready", handler)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for worker := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				rw := httptest.NewRecorder()
+				path := fmt.Sprintf("/items/%d/%d", worker, i%50)
+				router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+
+				rw = httptest.NewRecorder()
+				router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/ready", nil))
+				if rw.Code != http.StatusOK {
+					t.Errorf("expected status 200 for an existing route, got %d", rw.Code)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := range 200 {
+		router.RegisterRoute(GET, fmt.Sprintf("/static/%d", i), handler)
+		router.RegisterRoute(GET, fmt.Sprintf("/items/{worker}/%d", i), handler)
+		if i%50 == 0 {
+			router.Use(func(next HttpRequestHandler) HttpRequestHandler { return next })
+			router.Group("/group").Use(func(next HttpRequestHandler) HttpRequestHandler { return next })
+			router.Mount(fmt.Sprintf("/mount/%d", i), http.NotFoundHandler())
+			router.RegisteredRoutes()
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/items/1/199", nil))
+	if rw.Code != http.StatusOK {
+		t.Errorf("expected routes registered during traffic to be served, got %d", rw.Code)
+	}
+}
+
+func TestUnregisterRoute(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+	status := func(router *Router, method HttpMethod, path string) int {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(method), path, nil))
+		return rw.Code
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/hooks", handler)
+	router.RegisterRoute(GET, "/hooks/{id}", handler).Name("hook")
+	router.RegisterRoute(POST, "/hooks/{id}", handler)
+	router.RegisterRoute(GET, "/hooks/{id}/deliveries", handler)
+	router.RegisterRoute(GET, "/files/{*path}", handler)
+	router.CaseSensitive(true).RegisterRoute(GET, "/Exact", handler)
+
+	if !router.UnregisterRoute(GET, "/hooks") {
+		t.Error("expected the static route to be removed")
+	}
+	if status(router, GET, "/hooks") != http.StatusNotFound {
+		t.Error("expected a removed static route to return 404")
+	}
+
+	if !router.UnregisterRoute(GET, "/hooks/{hookId}") {
+		t.Error("expected the route to be removed using another parameter name")
+	}
+	if code := status(router, GET, "/hooks/1"); code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for the method still registered on the path, got %d", code)
+	}
+	if code := status(router, GET, "/hooks/1/deliveries"); code != http.StatusOK {
+		t.Errorf("expected the nested route to be kept, got %d", code)
+	}
+	if _, err := router.URL("hook", map[string]string{"id": "1"}); err == nil {
+		t.Error("expected the name of the removed route to be released")
+	}
+
+	if !router.UnregisterRoute(GET, "/files/{*rest}") || status(router, GET, "/files/a/b") != http.StatusNotFound {
+		t.Error("expected the catch-all route to be removed")
+	}
+	if !router.UnregisterRoute(GET, "/Exact") || status(router, GET, "/Exact") != http.StatusNotFound {
+		t.Error("expected the case sensitive route to be removed")
+	}
+
+	for _, removal := range []struct {
+		method HttpMethod
+		path   string
+	}{
+		{GET, "/hooks"},
+		{PUT, "/hooks/{id}"},
+		{GET, "/missing/{id}"},
+		{GET, "/hooks/{id"},
+	} {
+		if router.UnregisterRoute(removal.method, removal.path) {
+			t.Errorf("expected nothing to be removed for %s %s", removal.method, removal.path)
+		}
+	}
+
+	router.UnregisterRoute(POST, "/hooks/{id}")
+	router.UnregisterRoute(GET, "/hooks/{id}/deliveries")
+	if routes := *router.RegisteredRoutes(); len(routes[GET])+len(routes[POST]) != 0 {
+		t.Errorf("expected no routes left, got %v", routes)
+	}
+	if !router.tree.empty() {
+		t.Error("expected the route tree to be pruned")
+	}
+}
+
+func TestRegisteredRoutesAreCopies(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "id"))
+	}
+	broken := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusTeapot)
+	}
+	router.RegisterRoute(GET, "/users/{id}", handler)
+	router.RegisterRoute(GET, "/health", handler)
+
+	registered := *router.RegisteredRoutes()
+	user := registered[GET]["/users/{id}"]
+	user.Handler = broken
+	user.ParamList[1] = "other"
+	registered[GET]["/users/{id}"] = user
+	delete(registered[GET], "/health")
+	delete(registered, GET)
+
+	routes := router.Routes()
+	routes[0].ParamNames[0] = "other"
+	routes[0].Path = "/changed"
+
+	for path, expected := range map[string]string{"/users/7": "7", "/health": ""} {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+		if rw.Code != http.StatusOK || rw.Body.String() != expected {
+			t.Errorf("expected %s to be served unchanged, got %d %q", path, rw.Code, rw.Body.String())
+		}
+	}
+
+	if paramList := (*router.RegisteredRoutes())[GET]["/users/{id}"].ParamList; paramList[1] != "id" {
+		t.Errorf("expected the param list to be unchanged, got %v", paramList)
+	}
+
+	expected := []RouteInfo{
+		{Method: GET, Path: "/users/{id}", ParamNames: []string{"id"}, ParamTypes: map[string]string{}},
+		{Method: GET, Path: "/health"},
+	}
+	if routes := router.Routes(); fmt.Sprint(routes) != fmt.Sprint(expected) {
+		t.Errorf("expected routes %v, got %v", expected, routes)
+	}
+}
+
+func TestServeHTTPRecoversFromPanics(t *testing.T) {
+	router := NewRouter()
+	router.RegisterRoute(GET, "/panic", func(req *http.Request, params Params) *HttpResponse {
+		panic("boom")
+	})
+	router.Handle(GET, "/panic/std", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		panic(errors.New("boom"))
+	}))
+	router.RegisterRoute(GET, "/ok", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("ok")
+	})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, path := range []string{"/panic", "/panic/std", "/ok", "/panic", "/ok"} {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error requesting %s: %v", path, err)
+		}
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+
+		expectedStatus, expectedBody := http.StatusInternalServerError, "500 - Internal server error\n"
+		if path == "/ok" {
+			expectedStatus, expectedBody = http.StatusOK, "ok"
+		}
+		if response.StatusCode != expectedStatus || string(body) != expectedBody {
+			t.Errorf("expected %d %q for %s, got %d %q", expectedStatus, expectedBody, path, response.StatusCode, string(body))
+		}
+	}
+}
+
+func TestFallbackHandler(t *testing.T) {
+	legacy := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, hasParams := req.Context().Value(pathParamsKey{}).(map[string]string)
+		fmt.Fprintf(rw, "legacy %s %s %t", req.Method, req.URL.Path, hasParams)
+	})
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("yagaw")
+	}
+	serve := func(router *Router, method HttpMethod, path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(method), path, nil))
+		return rw
+	}
+
+	router := NewRouter().SetFallback(legacy).EnableAutoOptions(true)
+	router.RegisterRoute(GET, "/users/{id}", handler)
+	router.Use(func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return next(req, params).SetHeader("X-Middleware", "yes")
+		}
+	})
+
+	tests := []struct {
+		method HttpMethod
+		path   string
+		status int
+		body   string
+	}{
+		{GET, "/users/7", http.StatusOK, "yagaw"},
+		{GET, "/legacy/page", http.StatusOK, "legacy GET /legacy/page false"},
+		{DELETE, "/users/7", http.StatusOK, "legacy DELETE /users/7 false"},
+		{OPTIONS, "/users/7", http.StatusNoContent, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.method)+tt.path, func(t *testing.T) {
+			rw := serve(router, tt.method, tt.path)
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+		})
+	}
+
+	t.Run("custom handlers take precedence", func(t *testing.T) {
+		router.SetNotFoundHandler(func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusNotFound).SetBody("custom 404")
+		})
+		if rw := serve(router, GET, "/legacy/page"); rw.Body.String() != "custom 404" {
+			t.Errorf("expected the custom 404, got %q", rw.Body.String())
+		}
+		if rw := serve(router, DELETE, "/users/7"); rw.Code != http.StatusOK {
+			t.Errorf("expected the fallback for the unknown method, got %d", rw.Code)
+		}
+
+		router.SetMethodNotAllowedHandler(func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusMethodNotAllowed).SetBody("custom 405")
+		})
+		if rw := serve(router, DELETE, "/users/7"); rw.Body.String() != "custom 405" {
+			t.Errorf("expected the custom 405, got %q", rw.Body.String())
+		}
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		router := NewRouter().SetFallback(legacy).SetFallback(nil)
+		if rw := serve(router, GET, "/legacy/page"); rw.Code != http.StatusNotFound || rw.Body.String() != "404 - Page not found" {
+			t.Errorf("expected the default 404, got %d %q", rw.Code, rw.Body.String())
+		}
+	})
+}
+
+func TestFallbackHandlerGetsOriginalRequest(t *testing.T) {
+	var received *http.Request
+	router := NewRouter().AllowMethodOverride(DELETE).SetFallback(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = req
+	}))
+
+	req := httptest.NewRequest(string(POST), "/legacy", nil)
+	req.Header.Set("X-HTTP-Method-Override", "DELETE")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if received != req {
+		t.Errorf("expected the fallback to get the original request, got %v", received)
+	}
+}
+
+func TestStrictSlash(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+	newRouter := func(strict bool) *Router {
+		router := NewRouter().StrictSlash(strict)
+		router.RegisterRoute(GET, "/users", handler("action"))
+		router.RegisterRoute(GET, "/users/", handler("collection"))
+		router.RegisterRoute(GET, "/docs/", handler("docs"))
+		router.RegisterRoute(GET, "/users/{id}", handler("user"))
+		router.RegisterRoute(GET, "/", handler("home"))
+		return router
+	}
+
+	tests := []struct {
+		strict bool
+		method HttpMethod
+		path   string
+		status int
+		body   string
+	}{
+		{true, GET, "/users", http.StatusOK, "action"},
+		{true, GET, "/users/", http.StatusOK, "collection"},
+		{true, GET, "/docs", http.StatusNotFound, "404 - Page not found"},
+		{true, GET, "/users/7/", http.StatusNotFound, "404 - Page not found"},
+		{false, GET, "/users", http.StatusOK, "collection"},
+		{false, GET, "/users/", http.StatusOK, "collection"},
+		{false, GET, "/docs", http.StatusOK, "docs"},
+		{false, GET, "/docs/", http.StatusOK, "docs"},
+		{false, GET, "/users/7/", http.StatusOK, "user"},
+		{false, GET, "/", http.StatusOK, "home"},
+		{false, POST, "/docs/", http.StatusMethodNotAllowed, "405 - Method not allowed"},
+	}
+
+	routers := map[bool]*Router{true: newRouter(true), false: newRouter(false)}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("strict=%t %s %s", tt.strict, tt.method, tt.path), func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			routers[tt.strict].ServeHTTP(rw, httptest.NewRequest(string(tt.method), tt.path, nil))
+
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+		})
+	}
+
+	if router := NewRouter().StrictSlash(false).OnDuplicate(DuplicateError); router.RegisterRoute(GET, "/a", handler("")).Err() != nil || router.RegisterRoute(GET, "/a/", handler("")).Err() == nil {
+		t.Error("expected `/a` and `/a/` to be duplicates without strict slashes")
+	}
+}
+
+func TestInvalidRegistrations(t *testing.T) {
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router := NewRouter()
+	tests := []struct {
+		name    string
+		route   func() *Route
+		message string
+	}{
+		{"unknown method", func() *Route { return router.RegisterRoute("GETT", "/users", handler) }, "invalid route `GETT /users`: unknown method `GETT`"},
+		{"lowercase method", func() *Route { return router.RegisterRoute("get", "/users", handler) }, "unknown method `get`"},
+		{"nil handler", func() *Route { return router.RegisterRoute(GET, "/users", nil) }, "invalid route `GET /users`: nil handler"},
+		{"nil http handler", func() *Route { return router.Handle(GET, "/users", nil) }, "nil handler"},
+		{"nil handler func", func() *Route { return router.HandleFunc(GET, "/users", nil) }, "nil handler"},
+		{"empty path", func() *Route { return router.RegisterRoute(GET, "", handler) }, "invalid route `GET`: empty path"},
+		{"missing slash", func() *Route { return router.RegisterRoute(POST, "users/{id}", handler) }, "invalid route `POST users/{id}`: path must start with `/`"},
+		{"any missing slash", func() *Route { return router.Any("users", handler) }, "path must start with `/`"},
+		{"alias missing slash", func() *Route { return router.RegisterRoute(GET, "/users/{id}", handler).Alias("members/{id}") }, "invalid route `GET members/{id}`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.route().Err()
+			if !errors.Is(err, ErrInvalidRoute) {
+				t.Fatalf("expected ErrInvalidRoute, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("expected error to contain %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+
+	if routes := router.Routes(); len(routes) != 1 || routes[0].Path != "/users/{id}" {
+		t.Errorf("expected only the valid route to be registered, got %v", routes)
+	}
+
+	defer func() {
+		if err, isError := recover().(error); !isError || !errors.Is(err, ErrInvalidRoute) {
+			t.Errorf("expected a panic with ErrInvalidRoute, got %v", err)
+		}
+	}()
+	router.MustRegisterRoute(GET, "/users", nil)
+}
+
+func TestMatchedPattern(t *testing.T) {
+	router := NewRouter()
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(req.Pattern)
+	}
+	router.RegisterRoute(GET, "/users/{id:int}", handler)
+	router.RegisterRoute(GET, "/health", handler)
+	router.Group("/api").RegisterRoute(GET, "/orders/{id}", handler)
+	router.Host("{tenant}.example.com").RegisterRoute(GET, "/files/{*rest}", handler)
+
+	tests := []struct {
+		name     string
+		host     string
+		path     string
+		expected string
+	}{
+		{"parameters", "", "/users/42", "/users/{id:int}"},
+		{"static", "", "/health", "/health"},
+		{"group", "", "/api/orders/7", "/api/orders/{id}"},
+		{"host", "acme.example.com", "/files/a/b", "/files/{*rest}"},
+		{"not found", "", "/missing", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(string(GET), tt.path, nil)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, req)
+
+			if req.Pattern != tt.expected {
+				t.Errorf("expected the received request pattern %q, got %q", tt.expected, req.Pattern)
+			}
+			if rw.Code == http.StatusOK && rw.Body.String() != tt.expected {
+				t.Errorf("expected the handler to see %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+}
@@ -1,11 +1,24 @@
 package yagaw
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Pho3b/tiny-logger/logs"
 	"github.com/Pho3b/tiny-logger/logs/log_level"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 var Log *logs.Logger = InitLogger(log_level.ErrorLvlName)
@@ -19,34 +32,515 @@ func InitLogger(logLevel log_level.LogLvlName) *logs.Logger {
 		AddDate(true)
 }
 
+// DefaultShutdownGracePeriod is how long RunWithContext waits for in-flight requests
+const DefaultShutdownGracePeriod = 10 * time.Second
+
+// ErrForcedShutdown is returned by Shutdown when the requests in flight outlived the
+// shutdown timeout and their connections were closed.
+var ErrForcedShutdown = errors.New("shutdown timeout expired, connections were closed")
+
 type Server struct {
-	address string
-	port    int
-	server  *http.Server
-	router  *Router
+	mu           sync.Mutex
+	address      string
+	port         int
+	server       *http.Server
+	router       atomic.Pointer[Router]
+	shutdown     chan struct{}
+	gracePeriod  time.Duration
+	shutdownWait time.Duration
+	tlsConfig    *tls.Config
+	clientAuth   tls.ClientAuthType
+	clientCAs    *x509.CertPool
+	autocert     *autocertSettings
+	h2c          bool
+	unixSocket   string
+	socketPerm   os.FileMode
+	listener     net.Listener
+	ready        chan struct{}
+	httpSettings httpSettings
+	hooks        []func(ctx context.Context) error
+	hooksRun     bool
+	startHooks   []func(addr net.Addr) error
+	stopHooks    []func()
+	middleware   []func(http.Handler) http.Handler
+	handler      atomic.Pointer[http.Handler]
+	health       *healthSettings
+	stats        *serverStats
+	accessLog    *accessLogSettings
+	conns        connTracker
+	draining     atomic.Bool
+	drain        drainSettings
+	proxies      proxySettings
+	concurrency  atomic.Pointer[concurrencyLimiter]
+	startedAt    time.Time
+	redirectPort int
+
+	suppressHandshakeErrors bool
+
+	extraListeners []listenerSpec
+	tagged         []*taggedListener
+
+	companionServer *http.Server
+}
+
+// Run starts the HTTP server and blocks until it stops, the error is nil when the server
+// was shut down and the listen or serve error otherwise.
+func (s *Server) Run() error {
+	server, shutdown := s.prepare()
+	return s.serve(server, shutdown, (*http.Server).Serve)
+}
+
+// RunTLS is like Run but serves HTTPS with the certificate and key of the given PEM files,
+// both may be empty when the TLS configuration already holds the certificates.
+func (s *Server) RunTLS(certFile string, keyFile string) error {
+	server, shutdown := s.prepare()
+	companionServer, err := s.listenCompanion()
+	if err != nil {
+		return err
+	}
+
+	err = s.serve(server, shutdown, func(server *http.Server, listener net.Listener) error {
+		return server.ServeTLS(listener, certFile, keyFile)
+	})
+	if err != nil && companionServer != nil {
+		companionServer.Close()
+	}
+	return err
+}
+
+// WithTLSConfig sets the TLS configuration used by RunTLS, e.g. for certificates held in
+// memory or to restrict MinVersion and CipherSuites. The configuration is cloned, updating
+// it afterwards has no effect.
+func (s *Server) WithTLSConfig(config *tls.Config) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tlsConfig = config.Clone()
+	return s
+}
+
+// RunWithContext is like Run but shuts the server down gracefully once the context is done,
+// typically by signal.NotifyContext. Connections still open after the grace period are
+// closed and the shutdown error is returned, a clean shutdown returns nil.
+func (s *Server) RunWithContext(ctx context.Context) error {
+	server, shutdown := s.prepare()
+	ran := make(chan error, 1)
+	go func() { ran <- s.serve(server, shutdown, (*http.Server).Serve) }()
+
+	select {
+	case err := <-ran:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	gracePeriod := s.gracePeriod
+	s.mu.Unlock()
+	graceCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	err := s.Shutdown(graceCtx)
+	if err != nil {
+		server.Close()
+	}
+	if serveErr := <-ran; serveErr != nil {
+		return serveErr
+	}
+	return err
+}
+
+// SetKeepAlivesEnabled turns HTTP keep-alives on or off, for the running server and the
+// next runs, e.g. off before a rolling restart so that connections drain faster.
+func (s *Server) SetKeepAlivesEnabled(enabled bool) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpSettings.disableKeepAlives = !enabled
+	if s.server != nil {
+		s.server.SetKeepAlivesEnabled(enabled)
+	}
+	return s
+}
+
+// ShutdownGracePeriod sets how long RunWithContext waits for in-flight requests before
+// closing their connections, DefaultShutdownGracePeriod unless set.
+func (s *Server) ShutdownGracePeriod(gracePeriod time.Duration) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gracePeriod = gracePeriod
+	return s
 }
 
-func (s *Server) Run() {
+// WithShutdownTimeout bounds how long Shutdown waits for the requests in flight, e.g.
+// streaming responses held open by clients: their connections are closed once it expires
+// and Shutdown returns ErrForcedShutdown, the OnShutdown hooks still running. Shutdown
+// waits as long as its context allows unless set.
+func (s *Server) WithShutdownTimeout(timeout time.Duration) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shutdownWait = timeout
+	return s
+}
+
+// EnableH2C makes Run serve HTTP/2 without TLS as well, for clients with prior knowledge
+// and for HTTP/1.1 requests asking to upgrade. Other HTTP/1.1 requests are served as usual.
+func (s *Server) EnableH2C() *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.h2c = true
+	return s
+}
+
+// Use wraps everything the server serves with standard net/http middleware, e.g. panic
+// recovery or access logging, outside the router and its own middleware. The first one is
+// the outermost, and middleware added while the server runs applies to the next requests.
+func (s *Server) Use(mw ...func(http.Handler) http.Handler) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.middleware = append(s.middleware, mw...)
+	s.composeHandler()
+	return s
+}
+
+// composeHandler wraps the router with the server middleware and swaps the result in, the
+// requests being served never wait for it.
+func (s *Server) composeHandler() {
+	var handler http.Handler = s.drainHandler(http.HandlerFunc(s.serveRouter))
+	if s.health != nil {
+		handler = s.healthHandler(handler, s.health)
+	}
+	if s.stats != nil {
+		handler = s.statsHandler(handler, s.stats.path)
+	}
+	for _, mw := range slices.Backward(s.middleware) {
+		handler = mw(handler)
+	}
+	// Counting and logging outside the server middleware sees the responses it writes itself
+	if s.stats != nil {
+		handler = s.stats.count(handler)
+	}
+	if s.accessLog != nil {
+		handler = s.accessLog.logAccess(handler)
+	}
+	// The client address is resolved first, for everything else to see it
+	handler = s.proxies.proxyHandler(handler)
+	s.handler.Store(&handler)
+}
+
+func (s *Server) serveHTTP(rw http.ResponseWriter, req *http.Request) {
+	(*s.handler.Load()).ServeHTTP(rw, req)
+}
+
+func (s *Server) serveRouter(rw http.ResponseWriter, req *http.Request) {
+	s.router.Load().ServeHTTP(rw, req)
+}
+
+// prepare creates the http.Server of a run, before serving so that a shutdown can't miss it
+func (s *Server) prepare() (*http.Server, chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var handler http.Handler = http.HandlerFunc(s.serveHTTP)
+	if s.h2c {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
 	s.server = &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", s.address, s.port),
-		Handler: s.router,
+		Addr:              fmt.Sprintf("%s:%d", s.address, s.port),
+		Handler:           handler,
+		TLSConfig:         s.serverTLSConfig(),
+		ReadTimeout:       s.httpSettings.readTimeout,
+		ReadHeaderTimeout: s.httpSettings.readHeaderTimeout,
+		WriteTimeout:      s.httpSettings.writeTimeout,
+		IdleTimeout:       s.httpSettings.idleTimeout,
+		MaxHeaderBytes:    s.httpSettings.maxHeaderBytes,
+		BaseContext:       s.httpSettings.baseContext,
+		ConnContext:       s.httpSettings.connContext,
+		ConnState:         s.trackConn,
+		ErrorLog:          newErrorLog(s.suppressHandshakeErrors),
+	}
+	if len(s.extraListeners) > 0 {
+		s.server.ConnContext = s.tagConnContext(s.httpSettings.connContext)
+	}
+	if s.httpSettings.disableKeepAlives {
+		s.server.SetKeepAlivesEnabled(false)
+	}
+	s.shutdown = make(chan struct{})
+	s.resetReady()
+	s.companionServer = nil
+	s.hooksRun = false
+	if s.health != nil {
+		s.health.closing.Store(false)
+	}
+	return s.server, s.shutdown
+}
+
+func (s *Server) serve(server *http.Server, shutdown chan struct{}, serve func(*http.Server, net.Listener) error) error {
+	// Conflicting routes are reported, they don't prevent the server from starting
+	for _, conflict := range s.GetRouter().Validate() {
+		if conflict.Severity == ConflictError {
+			Log.Error(conflict.String())
+		} else {
+			Log.Warn(conflict.String())
+		}
 	}
 
-	Log.Debug(fmt.Sprintf("Starting server on address `%s:%d`", s.address, s.port))
-	err := s.server.ListenAndServe()
+	listener, err := s.listen(server.Addr)
 	if err != nil {
-		Log.Error(err)
+		return err
+	}
+	tagged, err := s.listenExtra()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	if err := s.runStartHooks(listener.Addr()); err != nil {
+		listener.Close()
+		for _, extra := range tagged {
+			extra.Close()
+		}
+		return err
 	}
+	s.markListening(listener, tagged...)
+	defer s.runStopHooks()
+
+	listeners := []net.Listener{listener}
+	for _, extra := range tagged {
+		listeners = append(listeners, extra)
+	}
+	served := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		Log.Debug(fmt.Sprintf("Starting server on address `%s`", listener.Addr()))
+		go func() { served <- serve(server, listener) }()
+	}
+
+	// A listener failing closes the others, its error is the one returned
+	var serveErr error
+	for range listeners {
+		err := <-served
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && serveErr == nil {
+			serveErr = err
+			server.Close()
+		}
+	}
+	if serveErr != nil {
+		return serveErr
+	}
+	// The listeners are closed as soon as the shutdown starts, Run returns once it is over
+	<-shutdown
+	return nil
+}
+
+// markListening records the listeners of the run and signals that the server is ready
+func (s *Server) markListening(listener net.Listener, tagged ...*taggedListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener, s.tagged = listener, tagged
+	s.startedAt = time.Now()
+	select {
+	case <-s.ready:
+	default:
+		close(s.ready)
+	}
+}
+
+// Addr returns the address the server listens on, nil until the listener is established
+// and once the run is shut down. With port 0 it holds the port the system picked.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Ready returns a channel closed once the server listens, on every listener, Addr being set
+// by then. A run failing to listen never closes it, its error is returned by the run instead.
+// Every run gets its own channel, made when the previous one is shut down.
+func (s *Server) Ready() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ready
+}
+
+// resetReady replaces the channel of a run that listened, the caller holding the lock
+func (s *Server) resetReady() {
+	select {
+	case <-s.ready:
+		s.ready = make(chan struct{})
+		s.listener, s.tagged = nil, nil
+	default:
+	}
+}
+
+// listen opens the listener of a run, the Unix socket when one is set and TCP otherwise
+func (s *Server) listen(addr string) (net.Listener, error) {
+	s.mu.Lock()
+	socketPath, perm := s.unixSocket, s.socketPerm
+	s.mu.Unlock()
+	if socketPath != "" {
+		return listenUnix(socketPath, perm)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Shutdown stops the server gracefully: readiness fails first, then listeners are closed
+// once the drain delay has passed, the Unix socket file being removed, and the call waits
+// for the in-flight requests to complete, the context to be done or the shutdown timeout to
+// expire. The OnShutdown hooks run next, once per run. Shutting down a server that is not
+// running does nothing.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	server, shutdown, companionServer := s.server, s.shutdown, s.companionServer
+	var hooks []func(ctx context.Context) error
+	if server != nil && !s.hooksRun {
+		hooks, s.hooksRun = slices.Clone(s.hooks), true
+	}
+	s.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+
+	s.failReadiness(ctx)
+	err := s.shutdownServers(ctx, server, companionServer)
+	// A failing hook doesn't prevent the next ones from running
+	for _, hook := range hooks {
+		err = errors.Join(err, hook(ctx))
+	}
+	s.mu.Lock()
+	select {
+	case <-shutdown:
+	default:
+		close(shutdown)
+		// A run shut down before another one started, Ready waits for the next one
+		if s.shutdown == shutdown {
+			s.resetReady()
+		}
+	}
+	s.mu.Unlock()
+	return err
 }
 
+// shutdownServers waits for the requests in flight, closing their connections once the
+// shutdown timeout expires.
+func (s *Server) shutdownServers(ctx context.Context, servers ...*http.Server) error {
+	s.mu.Lock()
+	timeout := s.shutdownWait
+	s.mu.Unlock()
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var err error
+	for _, server := range servers {
+		if server != nil {
+			err = errors.Join(err, server.Shutdown(waitCtx))
+		}
+	}
+	// Only the shutdown timeout forces the close, the caller's context keeps its error
+	if err == nil || timeout <= 0 || ctx.Err() != nil || waitCtx.Err() == nil {
+		return err
+	}
+	for _, server := range servers {
+		if server != nil {
+			server.Close()
+		}
+	}
+	return ErrForcedShutdown
+}
+
+// OnShutdown registers a cleanup function run by Shutdown once the listeners are closed
+// and the in-flight requests completed, e.g. to flush metrics or close a database pool.
+// Hooks run in registration order with the shutdown context, which they should respect,
+// and their errors are joined to the one returned by Shutdown.
+func (s *Server) OnShutdown(hook func(ctx context.Context) error) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+	return s
+}
+
+// OnStart registers a function called once the listeners of a run are bound, before the
+// first request is served, with the address of the listener given to NewServer, e.g. to
+// register the port picked by the system with service discovery. Functions are called in
+// registration order, the first error aborts the startup and is returned by the run.
+func (s *Server) OnStart(fn func(addr net.Addr) error) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.startHooks = append(s.startHooks, fn)
+	return s
+}
+
+// OnStop registers a function called once a run has stopped serving, its connections
+// closed and the OnShutdown hooks run, before the run returns. Functions are called in
+// registration order.
+func (s *Server) OnStop(fn func()) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopHooks = append(s.stopHooks, fn)
+	return s
+}
+
+func (s *Server) runStartHooks(addr net.Addr) error {
+	s.mu.Lock()
+	hooks := slices.Clone(s.startHooks)
+	s.mu.Unlock()
+	for _, hook := range hooks {
+		if err := hook(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) runStopHooks() {
+	s.mu.Lock()
+	hooks := slices.Clone(s.stopHooks)
+	s.mu.Unlock()
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+// MustRun is like Run but panics when the server fails to start or to serve
+func (s *Server) MustRun() {
+	if err := s.Run(); err != nil {
+		panic(err)
+	}
+}
+
+// GetRouter returns the router currently served
 func (s *Server) GetRouter() *Router {
-	return s.router
+	return s.router.Load()
+}
+
+// SetRouter swaps the served router for the given one, e.g. rebuilt from a new
+// configuration: requests in flight complete on the previous router and the next ones use
+// the new one. Serving requests takes no lock, a nil router is ignored.
+func (s *Server) SetRouter(router *Router) *Server {
+	if router != nil {
+		s.router.Store(router)
+	}
+	return s
 }
 
-func NewServer(addr string, port int) *Server {
-	return &Server{
-		address: addr,
-		port:    port,
-		router:  NewRouter(),
+// NewServer creates a server listening on the address and port, an address like
+// `unix:///var/run/app.sock` listens on that Unix socket instead, the port being ignored.
+// The options are applied in order, before any run.
+func NewServer(addr string, port int, opts ...ServerOption) *Server {
+	server := &Server{
+		address:     addr,
+		port:        port,
+		gracePeriod: DefaultShutdownGracePeriod,
+		ready:       make(chan struct{}),
+		drain:       drainSettings{retryAfter: DefaultDrainRetryAfter},
+	}
+	server.router.Store(NewRouter())
+	for _, opt := range opts {
+		opt(server)
+	}
+	server.composeHandler()
+	if socketPath, isUnix := strings.CutPrefix(addr, "unix://"); isUnix {
+		server.WithUnixSocket(socketPath, DefaultUnixSocketPerm)
 	}
+	return server
 }
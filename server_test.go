@@ -0,0 +1,802 @@
+package yagaw
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// freePort returns a port nothing listens on at the time of the call
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// awaitReady waits for the server to listen and returns its address
+func awaitReady(t *testing.T, server *Server, ran chan error) string {
+	select {
+	case <-server.Ready():
+		return server.Addr().String()
+	case err := <-ran:
+		t.Fatalf("unexpected run error: %v", err)
+	}
+	return ""
+}
+
+// waitFor polls the condition until it holds or a second has passed
+func waitFor(t *testing.T, what string, condition func() bool) {
+	for deadline := time.Now().Add(time.Second); !condition(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestServerRunReturnsBindError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	server := NewServer("127.0.0.1", port)
+	err = server.Run()
+	if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Fatalf("expected address already in use, got %v", err)
+	}
+	select {
+	case <-server.Ready():
+		t.Error("expected Ready to stay open after a failed run")
+	default:
+	}
+	if server.Addr() != nil {
+		t.Errorf("expected no address, got %v", server.Addr())
+	}
+
+	defer func() {
+		if recovered := recover(); recovered == nil {
+			t.Error("expected MustRun to panic")
+		}
+	}()
+	NewServer("127.0.0.1", port).MustRun()
+}
+
+func TestServerAddr(t *testing.T) {
+	server := NewServer("127.0.0.1", 0)
+	server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("hello")
+	})
+	if server.Addr() != nil {
+		t.Errorf("expected no address before running, got %v", server.Addr())
+	}
+
+	ran := make(chan error, 1)
+	go func() { ran <- server.Run() }()
+	defer func() {
+		server.Shutdown(context.Background())
+		<-ran
+	}()
+	awaitReady(t, server, ran)
+
+	address, isTCP := server.Addr().(*net.TCPAddr)
+	if !isTCP || address.Port == 0 {
+		t.Fatalf("expected the port picked by the kernel, got %v", server.Addr())
+	}
+	res, err := http.Get("http://" + address.String() + "/hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	if body, _ := io.ReadAll(res.Body); string(body) != "hello" {
+		t.Errorf("expected the route to be served on the bound address, got %q", body)
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	t.Run("not running", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0)
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Errorf("expected shutting down a server not running to do nothing, got %v", err)
+		}
+	})
+
+	t.Run("after a failed run", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer listener.Close()
+
+		server := NewServer("127.0.0.1", listener.Addr().(*net.TCPAddr).Port)
+		if err := server.Run(); err == nil {
+			t.Fatal("expected the run to fail")
+		}
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Errorf("expected shutting down a failed server to do nothing, got %v", err)
+		}
+	})
+
+	t.Run("run again", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0)
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		awaitReady(t, server, ran)
+		server.Shutdown(context.Background())
+		<-ran
+
+		select {
+		case <-server.Ready():
+			t.Fatal("expected the server not to be ready once shut down")
+		default:
+		}
+		if server.Addr() != nil {
+			t.Errorf("expected no address once shut down, got %v", server.Addr())
+		}
+		go func() { ran <- server.Run() }()
+		defer func() {
+			server.Shutdown(context.Background())
+			<-ran
+		}()
+		address := awaitReady(t, server, ran)
+		res, err := http.Get("http://" + address + "/")
+		if err != nil {
+			t.Fatalf("expected the second run to listen, got %v", err)
+		}
+		res.Body.Close()
+	})
+
+	t.Run("hooks", func(t *testing.T) {
+		flushErr := errors.New("metrics backend unreachable")
+		calls := []string{}
+		server := NewServer("127.0.0.1", 0).
+			OnShutdown(func(ctx context.Context) error {
+				calls = append(calls, "flush metrics")
+				return flushErr
+			}).
+			OnShutdown(func(ctx context.Context) error {
+				if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+					t.Error("expected the shutdown context")
+				}
+				calls = append(calls, "close pool")
+				return nil
+			})
+
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		awaitReady(t, server, ran)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); !errors.Is(err, flushErr) {
+			t.Errorf("expected the hook error, got %v", err)
+		}
+		if err := <-ran; err != nil {
+			t.Errorf("expected Run to return nil, got %v", err)
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			t.Errorf("expected the hooks to run once, got %v", err)
+		}
+		if expected := []string{"flush metrics", "close pool"}; !slices.Equal(calls, expected) {
+			t.Errorf("expected %v, got %v", expected, calls)
+		}
+	})
+
+	t.Run("in-flight requests complete", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0)
+		started, release := make(chan struct{}), make(chan struct{})
+		server.GetRouter().RegisterRoute(GET, "/slow", func(req *http.Request, params Params) *HttpResponse {
+			close(started)
+			<-release
+			return NewHttpResponse(http.StatusOK).SetBody("done")
+		})
+
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		address := awaitReady(t, server, ran)
+
+		responded := make(chan string, 1)
+		go func() {
+			res, err := http.Get("http://" + address + "/slow")
+			if err != nil {
+				responded <- err.Error()
+				return
+			}
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			responded <- string(body)
+		}()
+		<-started
+
+		shutdown := make(chan error, 1)
+		go func() { shutdown <- server.Shutdown(context.Background()) }()
+		waitFor(t, "new connections to be refused", func() bool {
+			conn, err := net.Dial("tcp", address)
+			if err == nil {
+				conn.Close()
+			}
+			return err != nil
+		})
+
+		select {
+		case err := <-ran:
+			t.Fatalf("expected Run to wait for the shutdown, returned %v", err)
+		default:
+		}
+		close(release)
+
+		if body := <-responded; body != "done" {
+			t.Errorf("expected the in-flight request to complete, got %q", body)
+		}
+		if err := <-shutdown; err != nil {
+			t.Errorf("unexpected shutdown error: %v", err)
+		}
+		if err := <-ran; err != nil {
+			t.Errorf("expected Run to return nil, got %v", err)
+		}
+	})
+
+	t.Run("timeout forces the close", func(t *testing.T) {
+		hookRan := false
+		server := NewServer("127.0.0.1", 0).
+			WithShutdownTimeout(50 * time.Millisecond).
+			OnShutdown(func(ctx context.Context) error {
+				hookRan = true
+				return nil
+			})
+		started := make(chan struct{})
+		server.GetRouter().Mount("/stream", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte("event"))
+			http.NewResponseController(rw).Flush()
+			close(started)
+			// Streams until the client goes away
+			<-req.Context().Done()
+		}))
+
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		address := awaitReady(t, server, ran)
+
+		res, err := http.Get("http://" + address + "/stream")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+		<-started
+
+		began := time.Now()
+		if err := server.Shutdown(context.Background()); !errors.Is(err, ErrForcedShutdown) {
+			t.Errorf("expected ErrForcedShutdown, got %v", err)
+		}
+		if elapsed := time.Since(began); elapsed < 50*time.Millisecond {
+			t.Errorf("expected the shutdown to wait for the timeout, returned after %s", elapsed)
+		}
+		if !hookRan {
+			t.Error("expected the hooks to run")
+		}
+		if _, err := io.ReadAll(res.Body); err == nil {
+			t.Error("expected the stream to be cut")
+		}
+		if err := <-ran; err != nil {
+			t.Errorf("expected Run to return nil, got %v", err)
+		}
+	})
+}
+
+func TestServerLifecycleHooks(t *testing.T) {
+	t.Run("sequence", func(t *testing.T) {
+		mu := sync.Mutex{}
+		calls := []string{}
+		record := func(call string) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, call)
+		}
+		var started net.Addr
+		server := NewServer("127.0.0.1", 0).
+			OnStart(func(addr net.Addr) error {
+				started = addr
+				record("start 1")
+				return nil
+			}).
+			OnStart(func(addr net.Addr) error {
+				record("start 2")
+				return nil
+			}).
+			OnShutdown(func(ctx context.Context) error {
+				record("shutdown")
+				return nil
+			}).
+			OnStop(func() { record("stop 1") }).
+			OnStop(func() { record("stop 2") })
+		server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+			record("request")
+			return NewHttpResponse(http.StatusOK)
+		})
+
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		address := awaitReady(t, server, ran)
+		if started == nil || started.String() != address {
+			t.Errorf("expected OnStart to get the bound address %s, got %v", address, started)
+		}
+
+		res, err := http.Get("http://" + address + "/hello")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+		if err := server.Shutdown(context.Background()); err != nil {
+			t.Fatalf("unexpected shutdown error: %v", err)
+		}
+		if err := <-ran; err != nil {
+			t.Errorf("expected Run to return nil, got %v", err)
+		}
+
+		expected := []string{"start 1", "start 2", "request", "shutdown", "stop 1", "stop 2"}
+		if !slices.Equal(calls, expected) {
+			t.Errorf("expected %v, got %v", expected, calls)
+		}
+	})
+
+	t.Run("start error aborts the run", func(t *testing.T) {
+		registerErr := errors.New("service discovery unreachable")
+		var bound net.Addr
+		stopped := false
+		server := NewServer("127.0.0.1", 0).
+			OnStart(func(addr net.Addr) error {
+				bound = addr
+				return registerErr
+			}).
+			OnStart(func(addr net.Addr) error {
+				t.Error("expected the next hooks to be skipped")
+				return nil
+			}).
+			OnStop(func() { stopped = true })
+
+		if err := server.Run(); !errors.Is(err, registerErr) {
+			t.Fatalf("expected the hook error, got %v", err)
+		}
+		select {
+		case <-server.Ready():
+			t.Error("expected Ready to stay open")
+		default:
+		}
+		if stopped {
+			t.Error("expected OnStop to run for started runs only")
+		}
+		if _, err := net.Dial("tcp", bound.String()); err == nil {
+			t.Error("expected the listener to be closed")
+		}
+	})
+}
+
+func TestServerRunWithContext(t *testing.T) {
+	start := func(t *testing.T, server *Server) (context.CancelFunc, chan error, string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ran := make(chan error, 1)
+		go func() { ran <- server.RunWithContext(ctx) }()
+		return cancel, ran, awaitReady(t, server, ran)
+	}
+
+	t.Run("startup error", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer listener.Close()
+
+		err = NewServer("127.0.0.1", listener.Addr().(*net.TCPAddr).Port).RunWithContext(context.Background())
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			t.Errorf("expected address already in use, got %v", err)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		cancel, ran, address := start(t, NewServer("127.0.0.1", 0))
+		cancel()
+
+		if err := <-ran; err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+		if _, err := net.Dial("tcp", address); err == nil {
+			t.Error("expected the server to be stopped")
+		}
+	})
+
+	t.Run("context done before serving", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if err := NewServer("127.0.0.1", 0).RunWithContext(ctx); err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+	})
+
+	t.Run("grace period expired", func(t *testing.T) {
+		server := NewServer("127.0.0.1", 0).ShutdownGracePeriod(20 * time.Millisecond)
+		started, release := make(chan struct{}), make(chan struct{})
+		defer close(release)
+		server.GetRouter().RegisterRoute(GET, "/slow", func(req *http.Request, params Params) *HttpResponse {
+			close(started)
+			<-release
+			return NewHttpResponse(http.StatusOK)
+		})
+		cancel, ran, address := start(t, server)
+
+		requested := make(chan error, 1)
+		go func() {
+			res, err := http.Get("http://" + address + "/slow")
+			if err == nil {
+				res.Body.Close()
+			}
+			requested <- err
+		}()
+		<-started
+		cancel()
+
+		if err := <-ran; !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the grace period to expire, got %v", err)
+		}
+		if err := <-requested; err == nil {
+			t.Error("expected the connection to be closed")
+		}
+	})
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 with its PEM encoded certificate and key
+func selfSignedCert(t *testing.T) (tls.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "yagaw test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cert, certPEM, keyPEM
+}
+
+func TestServerRunTLS(t *testing.T) {
+	cert, certPEM, keyPEM := selfSignedCert(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get := func(address string, clientConfig *tls.Config) (string, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+		defer client.CloseIdleConnections()
+		res, err := client.Get("https://" + address + "/hello")
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return string(body), err
+	}
+
+	tests := []struct {
+		name     string
+		config   *tls.Config
+		certFile string
+		keyFile  string
+	}{
+		{"certificate files", nil, certFile, keyFile},
+		{"certificate in memory", &tls.Config{Certificates: []tls.Certificate{cert}}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer("127.0.0.1", 0)
+			if tt.config != nil {
+				server.WithTLSConfig(tt.config)
+			}
+			server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+				return NewHttpResponse(http.StatusOK).SetBody("hello " + req.TLS.ServerName)
+			})
+			ran := make(chan error, 1)
+			go func() { ran <- server.RunTLS(tt.certFile, tt.keyFile) }()
+			address := awaitReady(t, server, ran)
+
+			body, err := get(address, &tls.Config{InsecureSkipVerify: true, ServerName: "yagaw.test"})
+			if err != nil || body != "hello yagaw.test" {
+				t.Errorf("expected the route to be served over TLS, got %q %v", body, err)
+			}
+
+			if err := server.Shutdown(context.Background()); err != nil {
+				t.Errorf("unexpected shutdown error: %v", err)
+			}
+			if err := <-ran; err != nil {
+				t.Errorf("expected RunTLS to return nil, got %v", err)
+			}
+		})
+	}
+
+	t.Run("configuration is enforced", func(t *testing.T) {
+		config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS13}
+		server := NewServer("127.0.0.1", 0).WithTLSConfig(config)
+		config.MinVersion = tls.VersionTLS12
+		server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK)
+		})
+		ran := make(chan error, 1)
+		go func() { ran <- server.RunTLS("", "") }()
+		defer func() {
+			server.Shutdown(context.Background())
+			<-ran
+		}()
+
+		address := awaitReady(t, server, ran)
+
+		if _, err := get(address, &tls.Config{InsecureSkipVerify: true}); err != nil {
+			t.Errorf("expected a TLS 1.3 client to be served, got %v", err)
+		}
+		if _, err := get(address, &tls.Config{InsecureSkipVerify: true, MaxVersion: tls.VersionTLS12}); err == nil {
+			t.Error("expected a TLS 1.2 client to fail the handshake")
+		}
+	})
+
+	t.Run("missing certificate", func(t *testing.T) {
+		err := NewServer("127.0.0.1", 0).RunTLS(filepath.Join(dir, "missing.pem"), keyFile)
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected the missing certificate error, got %v", err)
+		}
+	})
+}
+
+func TestServerH2C(t *testing.T) {
+	server := NewServer("127.0.0.1", 0).EnableH2C()
+	server.GetRouter().Use(func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return next(req, params).SetHeader("X-Middleware", "true")
+		}
+	})
+	server.GetRouter().RegisterRoute(GET, "/users/{id:int}", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(fmt.Sprintf("%s user %s", req.Proto, params["id"]))
+	})
+	ran := make(chan error, 1)
+	go func() { ran <- server.Run() }()
+	defer func() {
+		server.Shutdown(context.Background())
+		<-ran
+	}()
+	address := awaitReady(t, server, ran)
+	url := "http://" + address + "/users/42"
+
+	get := func(t *testing.T, client *http.Client) (*http.Response, string) {
+		res, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	t.Run("prior knowledge", func(t *testing.T) {
+		transport := &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}
+		defer transport.CloseIdleConnections()
+
+		res, body := get(t, &http.Client{Transport: transport})
+		if res.ProtoMajor != 2 || body != "HTTP/2.0 user 42" || res.Header.Get("X-Middleware") != "true" {
+			t.Errorf("expected the route over HTTP/2, got %s %q %v", res.Proto, body, res.Header)
+		}
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		conn, err := net.Dial("tcp", address)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer conn.Close()
+
+		fmt.Fprintf(conn, "GET /users/42 HTTP/1.1\r\nHost: 127.0.0.1\r\nConnection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAARAAAAAAAIAAAAA\r\n\r\n")
+		status, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil || !strings.HasPrefix(status, "HTTP/1.1 101") {
+			t.Errorf("expected the connection to switch protocols, got %q %v", status, err)
+		}
+	})
+
+	t.Run("HTTP/1.1", func(t *testing.T) {
+		res, body := get(t, http.DefaultClient)
+		if res.ProtoMajor != 1 || body != "HTTP/1.1 user 42" || res.Header.Get("X-Middleware") != "true" {
+			t.Errorf("expected the route over HTTP/1.1, got %s %q %v", res.Proto, body, res.Header)
+		}
+	})
+}
+
+func TestServerUse(t *testing.T) {
+	mu := sync.Mutex{}
+	calls := []string{}
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, name)
+	}
+	trace := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				record(name)
+				next.ServeHTTP(rw, req)
+			})
+		}
+	}
+	server := NewServer("127.0.0.1", 0).Use(trace("recover"), trace("log"))
+	server.GetRouter().Use(func(next HttpRequestHandler) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			record("router")
+			return next(req, params)
+		}
+	})
+	server.GetRouter().RegisterRoute(GET, "/hello", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("hello")
+	})
+
+	ran := make(chan error, 1)
+	go func() { ran <- server.Run() }()
+	defer func() {
+		server.Shutdown(context.Background())
+		<-ran
+	}()
+	address := awaitReady(t, server, ran)
+	get := func(t *testing.T, path string) (int, []string) {
+		mu.Lock()
+		calls = nil
+		mu.Unlock()
+		res, err := http.Get("http://" + address + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		return res.StatusCode, calls
+	}
+
+	t.Run("outside the router middleware", func(t *testing.T) {
+		if _, trace := get(t, "/hello"); !slices.Equal(trace, []string{"recover", "log", "router"}) {
+			t.Errorf("expected the server middleware outermost, got %v", trace)
+		}
+	})
+
+	t.Run("unknown routes", func(t *testing.T) {
+		status, trace := get(t, "/missing")
+		if status != http.StatusNotFound {
+			t.Fatalf("expected 404, got %d", status)
+		}
+		if !slices.Equal(trace, []string{"recover", "log", "router"}) {
+			t.Errorf("expected the fallback to be wrapped, got %v", trace)
+		}
+	})
+
+	t.Run("added while running", func(t *testing.T) {
+		server.Use(trace("metrics"))
+		if _, trace := get(t, "/hello"); !slices.Equal(trace, []string{"recover", "log", "metrics", "router"}) {
+			t.Errorf("expected the new middleware to apply, got %v", trace)
+		}
+	})
+}
+
+func TestServerSetRouter(t *testing.T) {
+	newRouter := func(version string) *Router {
+		router := NewRouter()
+		router.RegisterRoute(GET, "/users/{id}", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(version)
+		})
+		return router
+	}
+	versions := []*Router{newRouter("v1"), newRouter("v2")}
+	server := NewServer("127.0.0.1", 0, WithRouter(versions[0]))
+
+	ran := make(chan error, 1)
+	go func() { ran <- server.Run() }()
+	defer func() {
+		server.Shutdown(context.Background())
+		<-ran
+	}()
+	address := awaitReady(t, server, ran)
+
+	stop := make(chan struct{})
+	served := make(chan map[string]int, 4)
+	for range cap(served) {
+		go func() {
+			bodies := map[string]int{}
+			defer func() { served <- bodies }()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				res, err := http.Get("http://" + address + "/users/42")
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				body, _ := io.ReadAll(res.Body)
+				res.Body.Close()
+				if res.StatusCode != http.StatusOK {
+					t.Errorf("expected 200 during the swaps, got %d", res.StatusCode)
+					return
+				}
+				bodies[string(body)]++
+			}
+		}()
+	}
+
+	for i := range 200 {
+		router := versions[i%2]
+		server.SetRouter(router)
+		if server.GetRouter() != router {
+			t.Fatal("expected GetRouter to return the router swapped in")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+
+	total := map[string]int{}
+	for range cap(served) {
+		for body, count := range <-served {
+			total[body] += count
+		}
+	}
+	if total["v1"] == 0 || total["v2"] == 0 {
+		t.Errorf("expected both routers to serve requests, got %v", total)
+	}
+}
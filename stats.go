@@ -0,0 +1,165 @@
+package yagaw
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ----------- STATS -----------
+
+// ServerStats is a snapshot of the counters of a server, served as JSON by the stats
+// endpoint. Responses are counted by status class, e.g. `2xx`.
+type ServerStats struct {
+	Uptime          string            `json:"uptime"`
+	UptimeSeconds   float64           `json:"uptimeSeconds"`
+	Requests        uint64            `json:"requests"`
+	InFlight        int64             `json:"inFlight"`
+	Connections     int64             `json:"connections"`
+	IdleConnections int64             `json:"idleConnections"`
+	Responses       map[string]uint64 `json:"responses"`
+	Concurrency     *ConcurrencyStats `json:"concurrency,omitempty"`
+	Goroutines      int               `json:"goroutines"`
+	HeapBytes       uint64            `json:"heapBytes"`
+	GCCycles        uint64            `json:"gcCycles"`
+}
+
+var statusClasses = [...]string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+
+type serverStats struct {
+	path      string
+	requests  atomic.Uint64
+	inFlight  atomic.Int64
+	responses [len(statusClasses)]atomic.Uint64
+}
+
+// EnableStats counts the requests served and answers GET requests to path with the
+// ServerStats as JSON, ahead of the router like the health endpoints. An empty path
+// counts without serving them, Stats still returning the snapshot.
+func (s *Server) EnableStats(path string) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stats == nil {
+		s.stats = &serverStats{}
+	}
+	s.stats.path = path
+	s.composeHandler()
+	return s
+}
+
+// Stats returns a snapshot of the counters, zero until EnableStats is called. The uptime
+// counts from the moment the server listens.
+func (s *Server) Stats() ServerStats {
+	s.mu.Lock()
+	stats, startedAt := s.stats, s.startedAt
+	s.mu.Unlock()
+
+	snapshot := ServerStats{Responses: map[string]uint64{}, Goroutines: runtime.NumGoroutine()}
+	snapshot.Connections, snapshot.IdleConnections = s.conns.open.Load(), s.conns.idle.Load()
+	if !startedAt.IsZero() {
+		uptime := time.Since(startedAt)
+		snapshot.Uptime, snapshot.UptimeSeconds = uptime.Round(time.Millisecond).String(), uptime.Seconds()
+	}
+	if concurrency := s.concurrency.Load(); concurrency != nil {
+		concurrencyStats := concurrency.stats()
+		snapshot.Concurrency = &concurrencyStats
+	}
+	if stats != nil {
+		snapshot.Requests, snapshot.InFlight = stats.requests.Load(), stats.inFlight.Load()
+		for i, class := range statusClasses {
+			snapshot.Responses[class] = stats.responses[i].Load()
+		}
+	}
+
+	// runtime/metrics is read without stopping the world, unlike runtime.ReadMemStats
+	samples := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}, {Name: "/gc/cycles/total:gc-cycles"}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		snapshot.HeapBytes = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		snapshot.GCCycles = samples[1].Value.Uint64()
+	}
+	return snapshot
+}
+
+// connTracker counts the open and idle connections from their state changes
+type connTracker struct {
+	mu     sync.Mutex
+	states map[net.Conn]http.ConnState
+	open   atomic.Int64
+	idle   atomic.Int64
+}
+
+// trackConn is the ConnState hook of every run, calling the one set by WithConnState too
+func (s *Server) trackConn(conn net.Conn, state http.ConnState) {
+	s.conns.update(conn, state)
+	if connState := s.httpSettings.connState; connState != nil {
+		connState(conn, state)
+	}
+}
+
+func (tracker *connTracker) update(conn net.Conn, state http.ConnState) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if tracker.states == nil {
+		tracker.states = map[net.Conn]http.ConnState{}
+	}
+	previous, known := tracker.states[conn]
+	if previous == http.StateIdle {
+		tracker.idle.Add(-1)
+	}
+
+	switch state {
+	case http.StateNew:
+		tracker.open.Add(1)
+	case http.StateIdle:
+		tracker.idle.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		if known {
+			tracker.open.Add(-1)
+		}
+		delete(tracker.states, conn)
+		return
+	}
+	tracker.states[conn] = state
+}
+
+// count updates the counters around every request served by next
+func (stats *serverStats) count(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		stats.inFlight.Add(1)
+		recorder := NewStatusRecorder(rw)
+		defer func() {
+			stats.inFlight.Add(-1)
+			stats.requests.Add(1)
+			if class := recorder.Status()/100 - 1; class >= 0 && class < len(statusClasses) {
+				stats.responses[class].Add(1)
+			}
+		}()
+		next.ServeHTTP(recorder, req)
+	})
+}
+
+// statsHandler answers the stats endpoint and passes the other requests to next
+func (s *Server) statsHandler(next http.Handler, path string) http.Handler {
+	if path == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != path || req.Method != http.MethodGet && req.Method != http.MethodHead {
+			next.ServeHTTP(rw, req)
+			return
+		}
+		body, _ := json.MarshalIndent(s.Stats(), "", "  ")
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Cache-Control", "no-store")
+		rw.Write(append(body, '\n'))
+	})
+}
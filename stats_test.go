@@ -0,0 +1,92 @@
+package yagaw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerStats(t *testing.T) {
+	server := NewServer("127.0.0.1", 0).EnableStats("/_stats")
+	started, release := make(chan struct{}), make(chan struct{})
+	server.GetRouter().RegisterRoute(GET, "/users/{id}", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	})
+	server.GetRouter().RegisterRoute(GET, "/slow", func(req *http.Request, params Params) *HttpResponse {
+		close(started)
+		<-release
+		return NewHttpResponse(http.StatusCreated)
+	})
+	server.GetRouter().RegisterRoute(GET, "/panic", func(req *http.Request, params Params) *HttpResponse {
+		panic("broken handler")
+	})
+	serve := func(path string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		server.serveHTTP(rw, httptest.NewRequest(string(GET), path, nil))
+		return rw
+	}
+	for _, path := range []string{"/users/1", "/users/2", "/missing", "/panic"} {
+		serve(path)
+	}
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		serve("/slow")
+	}()
+	<-started
+
+	rw := serve("/_stats")
+	if rw.Code != http.StatusOK || rw.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a JSON document, got %d %q", rw.Code, rw.Header().Get("Content-Type"))
+	}
+	stats := ServerStats{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Requests != 4 {
+		t.Errorf("expected 4 requests served, got %d", stats.Requests)
+	}
+	// The slow request and the stats one are in flight
+	if stats.InFlight != 2 {
+		t.Errorf("expected 2 requests in flight, got %d", stats.InFlight)
+	}
+	if stats.Responses["2xx"] != 2 || stats.Responses["4xx"] != 1 || stats.Responses["5xx"] != 1 {
+		t.Errorf("unexpected responses by class %v", stats.Responses)
+	}
+	if stats.Goroutines == 0 || stats.HeapBytes == 0 {
+		t.Errorf("expected the runtime stats, got %+v", stats)
+	}
+	if stats.Uptime != "" {
+		t.Errorf("expected no uptime before running, got %q", stats.Uptime)
+	}
+
+	close(release)
+	<-served
+	if stats := server.Stats(); stats.Requests != 6 || stats.InFlight != 0 || stats.Responses["2xx"] != 4 {
+		t.Errorf("unexpected stats after the slow request %+v", stats)
+	}
+
+	t.Run("uptime", func(t *testing.T) {
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		defer func() {
+			server.Shutdown(context.Background())
+			<-ran
+		}()
+		awaitReady(t, server, ran)
+		if stats := server.Stats(); stats.Uptime == "" || stats.UptimeSeconds < 0 {
+			t.Errorf("expected the uptime once running, got %+v", stats)
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		rw := httptest.NewRecorder()
+		NewServer("127.0.0.1", 0).serveHTTP(rw, httptest.NewRequest(string(GET), "/_stats", nil))
+		if rw.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", rw.Code)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package yagaw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ----------- TEST SERVER -----------
+
+// TestServer serves a router on a loopback httptest.Server through the same handler
+// composition as Run, server middleware and endpoints included, for end-to-end tests.
+type TestServer struct {
+	URL        string
+	server     *Server
+	httpServer *httptest.Server
+}
+
+// NewTestServer starts serving the router, configured by the server options, until Close.
+// A nil router serves a new one.
+func NewTestServer(router *Router, opts ...ServerOption) *TestServer {
+	server := NewServer("127.0.0.1", 0, append([]ServerOption{WithRouter(router)}, opts...)...)
+	httpServer := httptest.NewUnstartedServer(nil)
+
+	// The http.Server is the one Run would build, only the listener differs
+	config, _ := server.prepare()
+	config.Addr = ""
+	httpServer.Config = config
+	httpServer.Start()
+	server.markListening(httpServer.Listener)
+	return &TestServer{URL: httpServer.URL, server: server, httpServer: httpServer}
+}
+
+// NewTestServerT is like NewTestServer, the server being closed when the test ends
+func NewTestServerT(t testing.TB, router *Router, opts ...ServerOption) *TestServer {
+	t.Helper()
+	testServer := NewTestServer(router, opts...)
+	t.Cleanup(testServer.Close)
+	return testServer
+}
+
+// Server returns the server behind the test server, e.g. to add server middleware or to
+// enable the health endpoints while it runs.
+func (ts *TestServer) Server() *Server {
+	return ts.server
+}
+
+// Client returns an HTTP client sending its requests to the test server
+func (ts *TestServer) Client() *http.Client {
+	return ts.httpServer.Client()
+}
+
+// Close stops serving and blocks until the requests in flight complete
+func (ts *TestServer) Close() {
+	ts.httpServer.Close()
+}
+
+// Get sends a GET request for path, the response body must be closed
+func (ts *TestServer) Get(path string) (*http.Response, error) {
+	return ts.Client().Get(ts.URL + path)
+}
+
+// GetJSON sends a GET request for path accepting JSON and decodes a 2xx response body into
+// out. The body of the returned response is consumed and closed.
+func (ts *TestServer) GetJSON(path string, out any) (*http.Response, error) {
+	return ts.DoJSON(string(GET), path, nil, out)
+}
+
+// PostJSON is like GetJSON but POSTs the body encoded as JSON
+func (ts *TestServer) PostJSON(path string, body any, out any) (*http.Response, error) {
+	return ts.DoJSON(string(POST), path, body, out)
+}
+
+// DoJSON sends a request for path with the body encoded as JSON unless nil, and decodes a
+// 2xx response body into out unless nil. The body of the returned response is consumed
+// and closed.
+func (ts *TestServer) DoJSON(method string, path string, body any, out any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res, err
+	}
+	if out == nil || res.StatusCode < 200 || res.StatusCode > 299 {
+		return res, nil
+	}
+	if err := json.Unmarshal(content, out); err != nil {
+		return res, fmt.Errorf("decoding the `%s %s` response: %w", method, path, err)
+	}
+	return res, nil
+}
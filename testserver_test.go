@@ -0,0 +1,96 @@
+package yagaw
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTestServer(t *testing.T) {
+	type user struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	router := NewRouter()
+	router.RegisterRoute(GET, "/users/{id}", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).
+			SetHeader("Content-Type", "application/json").
+			SetBody(`{"id":"` + PathParam(req, "id") + `","name":"Ada"}`)
+	})
+	router.RegisterRoute(POST, "/users", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusCreated).SetBody(`{"id":"7","name":"` + req.Header.Get("Content-Type") + `"}`)
+	})
+	router.RegisterRoute(GET, "/broken", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody("not json")
+	})
+
+	ts := NewTestServerT(t, router, WithDisableKeepAlives())
+	ts.Server().Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("X-Server-Middleware", "true")
+			next.ServeHTTP(rw, req)
+		})
+	}).EnableHealth("/healthz", "")
+
+	t.Run("GetJSON", func(t *testing.T) {
+		got := user{}
+		res, err := ts.GetJSON("/users/42", &got)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != (user{ID: "42", Name: "Ada"}) {
+			t.Errorf("unexpected user %+v", got)
+		}
+		if res.Header.Get("X-Server-Middleware") != "true" {
+			t.Error("expected the server middleware to run")
+		}
+		if !res.Close {
+			t.Error("expected the server options to apply")
+		}
+	})
+
+	t.Run("PostJSON", func(t *testing.T) {
+		got := user{}
+		res, err := ts.PostJSON("/users", user{Name: "Grace"}, &got)
+		if err != nil || res.StatusCode != http.StatusCreated || got.Name != "application/json" {
+			t.Errorf("unexpected response %v %+v %v", res.StatusCode, got, err)
+		}
+	})
+
+	t.Run("error responses are not decoded", func(t *testing.T) {
+		got := user{}
+		res, err := ts.GetJSON("/missing", &got)
+		if err != nil || res.StatusCode != http.StatusNotFound || got != (user{}) {
+			t.Errorf("unexpected response %v %+v %v", res.StatusCode, got, err)
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		if _, err := ts.GetJSON("/broken", &user{}); err == nil || !strings.Contains(err.Error(), "GET /broken") {
+			t.Errorf("expected a decoding error, got %v", err)
+		}
+	})
+
+	t.Run("server endpoints", func(t *testing.T) {
+		res, err := ts.Get("/healthz")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("expected the health endpoint, got %d", res.StatusCode)
+		}
+		if addr := ts.Server().Addr(); addr == nil || "http://"+addr.String() != ts.URL {
+			t.Errorf("expected the address of the test server, got %v", addr)
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		other := NewTestServer(nil)
+		if err := other.Server().Shutdown(context.Background()); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		other.Close()
+	})
+}
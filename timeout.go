@@ -0,0 +1,133 @@
+package yagaw
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout limits how long the route handler may run, overriding the router default. The
+// request context carries the deadline, when it expires the client gets a 503 and whatever
+// the handler produces is discarded. A timeout of 0 disables the default for the route.
+func (rt *Route) Timeout(timeout time.Duration) *Route {
+	if rt.err != nil {
+		return rt
+	}
+	rt.router.mu.Lock()
+	defer rt.router.mu.Unlock()
+
+	for _, handlerPackage := range rt.handlerPackages {
+		handlerPackage.timeout = &timeout
+	}
+	return rt
+}
+
+// DefaultTimeout limits how long the handlers of the routes without their own timeout may
+// run, 0 means no limit.
+func (r *Router) DefaultTimeout(timeout time.Duration) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultTimeout = timeout
+	return r
+}
+
+func (r *Router) routeTimeout(handlerPackage *RequestHandlerPackage) time.Duration {
+	if handlerPackage.timeout != nil {
+		return *handlerPackage.timeout
+	}
+	return r.defaultTimeout
+}
+
+// withTimeout runs the handler in its own goroutine with a deadline on the request context.
+// Delegated responses are written to a buffer, replayed only if the deadline is met.
+func withTimeout(handler HttpRequestHandler, timeout time.Duration) HttpRequestHandler {
+	if timeout <= 0 {
+		return handler
+	}
+	return func(req *http.Request, params Params) *HttpResponse {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+
+		buffer := &timeoutWriter{header: make(http.Header)}
+		done := make(chan *HttpResponse, 1)
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					panicked <- recovered
+				}
+			}()
+			response := handler(req, params)
+			if writer := response.writer; writer != nil {
+				writer(buffer)
+				response.writer = buffer.replay
+			}
+			done <- response
+		}()
+
+		select {
+		case response := <-done:
+			return response
+		case recovered := <-panicked:
+			// Panics are raised again where ServeHTTP can recover them
+			panic(recovered)
+		case <-ctx.Done():
+			buffer.expire()
+			return timeoutHandler(req, params)
+		}
+	}
+}
+
+// timeoutWriter buffers a delegated response, writes after the deadline fail with
+// http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu      sync.Mutex
+	header  http.Header
+	body    bytes.Buffer
+	status  int
+	expired bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.expired {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.expired && w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *timeoutWriter) expire() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expired = true
+}
+
+func (w *timeoutWriter) replay(rw http.ResponseWriter) {
+	for key, values := range w.header {
+		rw.Header()[key] = values
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	rw.WriteHeader(w.status)
+	rw.Write(w.body.Bytes())
+}
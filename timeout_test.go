@@ -0,0 +1,97 @@
+package yagaw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteTimeout(t *testing.T) {
+	cancelled := make(chan error, 1)
+	lateWrite := make(chan error, 1)
+
+	sleeping := func(delay time.Duration) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			select {
+			case <-time.After(delay):
+				return NewHttpResponse(http.StatusOK).SetBody("done")
+			case <-req.Context().Done():
+				cancelled <- req.Context().Err()
+				return NewHttpResponse(http.StatusOK).SetBody("too late")
+			}
+		}
+	}
+
+	router := NewRouter().DefaultTimeout(20 * time.Millisecond)
+	router.RegisterRoute(GET, "/fast", sleeping(0))
+	router.RegisterRoute(GET, "/slow", sleeping(time.Second))
+	router.RegisterRoute(GET, "/report", sleeping(40*time.Millisecond)).Timeout(time.Second)
+	router.RegisterRoute(GET, "/unbounded", sleeping(40*time.Millisecond)).Timeout(0)
+	router.Handle(GET, "/std", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("X-Partial", "yes")
+		rw.Write([]byte("partial"))
+		<-req.Context().Done()
+		time.Sleep(5 * time.Millisecond)
+		_, err := rw.Write([]byte(" corrupted"))
+		lateWrite <- err
+	}))
+	router.HandleFunc(GET, "/std/fast", func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Add("X-Values", "a")
+		rw.Header().Add("X-Values", "b")
+		rw.WriteHeader(http.StatusCreated)
+		rw.Write([]byte("created"))
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/fast", http.StatusOK, "done"},
+		{"/slow", http.StatusServiceUnavailable, "503 - Service unavailable"},
+		{"/report", http.StatusOK, "done"},
+		{"/unbounded", http.StatusOK, "done"},
+		{"/std", http.StatusServiceUnavailable, "503 - Service unavailable"},
+		{"/std/fast", http.StatusCreated, "created"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+			if tt.path == "/std" && rw.Header().Get("X-Partial") != "" {
+				t.Error("expected the headers of the timed out handler to be discarded")
+			}
+			if tt.path == "/std/fast" && len(rw.Header().Values("X-Values")) != 2 {
+				t.Errorf("expected both header values to be replayed, got %v", rw.Header().Values("X-Values"))
+			}
+		})
+	}
+
+	if err := <-cancelled; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the handler context to expire, got %v", err)
+	}
+	if err := <-lateWrite; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("expected writes after the deadline to fail, got %v", err)
+	}
+}
+
+func TestRouteTimeoutPanics(t *testing.T) {
+	router := NewRouter().DefaultTimeout(time.Second)
+	router.RegisterRoute(GET, "/panic", func(req *http.Request, params Params) *HttpResponse {
+		panic("boom")
+	})
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/panic", nil))
+	if rw.Code != http.StatusInternalServerError {
+		t.Errorf("expected the panic to be recovered with a 500, got %d", rw.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package yagaw
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+)
+
+// RequireClientCert makes RunTLS refuse the handshake of clients without a certificate
+// signed by one of the pool authorities, handlers read it with ClientCert.
+func (s *Server) RequireClientCert(caPool *x509.CertPool) *Server {
+	return s.clientCert(tls.RequireAndVerifyClientCert, caPool)
+}
+
+// VerifyClientCertIfGiven is like RequireClientCert but lets clients without a certificate
+// in, for deployments where only some clients have one. Invalid certificates still fail.
+func (s *Server) VerifyClientCertIfGiven(caPool *x509.CertPool) *Server {
+	return s.clientCert(tls.VerifyClientCertIfGiven, caPool)
+}
+
+func (s *Server) clientCert(clientAuth tls.ClientAuthType, caPool *x509.CertPool) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clientAuth, s.clientCAs = clientAuth, caPool
+	return s
+}
+
+// serverTLSConfig returns the TLS configuration of a run, certificates managed by autocert
+// and client certificate verification apply over the one set with WithTLSConfig.
+func (s *Server) serverTLSConfig() *tls.Config {
+	config := s.tlsConfig.Clone()
+	if s.autocert != nil {
+		config = autocertTLSConfig(config, s.autocert.manager)
+	}
+	if s.clientAuth == tls.NoClientCert {
+		return config
+	}
+	if config == nil {
+		config = &tls.Config{}
+	}
+	config.ClientAuth, config.ClientCAs = s.clientAuth, s.clientCAs
+	return config
+}
+
+// ClientCert returns the verified certificate the client presented, nil for plain HTTP
+// requests and clients without one.
+func ClientCert(req *http.Request) *x509.Certificate {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return req.TLS.VerifiedChains[0][0]
+}
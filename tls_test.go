@@ -0,0 +1,151 @@
+package yagaw
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	cert, key := createCert(t, template, nil, nil)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns a client certificate for the common name signed by the authority
+func (ca *testCA) issue(t *testing.T, commonName string) tls.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, key := createCert(t, template, ca.cert, ca.key)
+	return tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// createCert signs the template with the parent, the template itself when parent is nil
+func createCert(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cert, key
+}
+
+func TestClientCertVerification(t *testing.T) {
+	serverCert, _, _ := selfSignedCert(t)
+	ca, otherCA := newTestCA(t, "yagaw ca"), newTestCA(t, "other ca")
+	trusted, untrusted := ca.issue(t, "billing"), otherCA.issue(t, "intruder")
+
+	start := func(t *testing.T, configure func(server *Server)) string {
+		server := NewServer("127.0.0.1", 0).WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{serverCert}})
+		configure(server)
+		server.GetRouter().RegisterRoute(GET, "/whoami", func(req *http.Request, params Params) *HttpResponse {
+			if cert := ClientCert(req); cert != nil {
+				return NewHttpResponse(http.StatusOK).SetBody(cert.Subject.CommonName)
+			}
+			return NewHttpResponse(http.StatusOK).SetBody("anonymous")
+		})
+		ran := make(chan error, 1)
+		go func() { ran <- server.RunTLS("", "") }()
+		t.Cleanup(func() {
+			server.Shutdown(context.Background())
+			<-ran
+		})
+		return awaitReady(t, server, ran)
+	}
+	get := func(address string, certs ...tls.Certificate) (string, error) {
+		// The certificate is sent even when the server would not accept its authority
+		clientConfig := &tls.Config{InsecureSkipVerify: true}
+		if len(certs) > 0 {
+			clientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) { return &certs[0], nil }
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+		defer client.CloseIdleConnections()
+		res, err := client.Get("https://" + address + "/whoami")
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		return string(body), err
+	}
+
+	tests := []struct {
+		name      string
+		configure func(server *Server)
+		certs     []tls.Certificate
+		expected  string
+	}{
+		{"required and trusted", func(server *Server) { server.RequireClientCert(ca.pool()) }, []tls.Certificate{trusted}, "billing"},
+		{"required and missing", func(server *Server) { server.RequireClientCert(ca.pool()) }, nil, ""},
+		{"required and untrusted", func(server *Server) { server.RequireClientCert(ca.pool()) }, []tls.Certificate{untrusted}, ""},
+		{"if given and trusted", func(server *Server) { server.VerifyClientCertIfGiven(ca.pool()) }, []tls.Certificate{trusted}, "billing"},
+		{"if given and missing", func(server *Server) { server.VerifyClientCertIfGiven(ca.pool()) }, nil, "anonymous"},
+		{"if given and untrusted", func(server *Server) { server.VerifyClientCertIfGiven(ca.pool()) }, []tls.Certificate{untrusted}, ""},
+		{"not verified", func(server *Server) {}, []tls.Certificate{trusted}, "anonymous"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := start(t, tt.configure)
+			body, err := get(address, tt.certs...)
+
+			if tt.expected == "" && err == nil {
+				t.Fatalf("expected the handshake to fail, got %q", body)
+			}
+			if tt.expected != "" && (err != nil || body != tt.expected) {
+				t.Errorf("expected %q, got %q %v", tt.expected, body, err)
+			}
+		})
+	}
+
+	t.Run("plain HTTP request", func(t *testing.T) {
+		if cert := ClientCert(httptest.NewRequest(string(GET), "/whoami", nil)); cert != nil {
+			t.Errorf("expected no certificate, got %v", cert.Subject)
+		}
+	})
+}
@@ -0,0 +1,323 @@
+package yagaw
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ----------- ROUTE TREE -----------
+type routeSegment struct {
+	literal       string
+	pattern       *regexp.Regexp
+	captures      []int
+	catchAll      bool
+	literalLen    int
+	constrained   bool
+	caseSensitive bool
+}
+
+type routeNode struct {
+	pattern     *regexp.Regexp
+	captures    []int
+	literalLen  int
+	constrained bool
+	static      map[string]*routeNode
+	exact       map[string]*routeNode
+	dynamic     []*routeNode
+	catchAll    *routeNode
+	handlers    map[HttpMethod]*RequestHandlerPackage
+}
+
+func (n *routeNode) insert(segments []routeSegment) *routeNode {
+	node := n
+	for _, segment := range segments {
+		node = node.child(segment)
+	}
+	return node
+}
+
+func (n *routeNode) child(segment routeSegment) *routeNode {
+	if segment.catchAll {
+		if n.catchAll == nil {
+			n.catchAll = newRouteNode(nil)
+		}
+		return n.catchAll
+	}
+
+	// Literal segments are stored lowercased unless the route is case sensitive
+	if segment.pattern == nil && segment.caseSensitive {
+		if n.exact[segment.literal] == nil {
+			n.exact[segment.literal] = newRouteNode(nil)
+		}
+		return n.exact[segment.literal]
+	}
+	if segment.pattern == nil {
+		key := strings.ToLower(segment.literal)
+		if n.static[key] == nil {
+			n.static[key] = newRouteNode(nil)
+		}
+		return n.static[key]
+	}
+
+	for _, child := range n.dynamic {
+		if child.pattern.String() == segment.pattern.String() {
+			return child
+		}
+	}
+	child := newRouteNode(segment.pattern)
+	child.captures = segment.captures
+	child.literalLen = segment.literalLen
+	child.constrained = segment.constrained
+
+	// Parametrized children are kept sorted from the most specific one, equally specific
+	// ones in registration order so that the winner never depends on map iteration
+	position, _ := slices.BinarySearchFunc(n.dynamic, child, func(registered, inserted *routeNode) int {
+		if registered.compareSpecificity(inserted) > 0 {
+			return 1
+		}
+		return -1
+	})
+	n.dynamic = slices.Insert(n.dynamic, position, child)
+
+	return child
+}
+
+// remove deletes the handler registered for the method at the end of the segments, nodes
+// left without handlers and children are pruned on the way back.
+func (n *routeNode) remove(segments []routeSegment, method HttpMethod) *RequestHandlerPackage {
+	if len(segments) == 0 {
+		handlerPackage := n.handlers[method]
+		delete(n.handlers, method)
+		return handlerPackage
+	}
+
+	segment := segments[0]
+	child := n.existingChild(segment)
+	if child == nil {
+		return nil
+	}
+	handlerPackage := child.remove(segments[1:], method)
+	if handlerPackage == nil || !child.empty() {
+		return handlerPackage
+	}
+
+	switch {
+	case segment.catchAll:
+		n.catchAll = nil
+	case segment.pattern == nil && segment.caseSensitive:
+		delete(n.exact, segment.literal)
+	case segment.pattern == nil:
+		delete(n.static, strings.ToLower(segment.literal))
+	default:
+		n.dynamic = slices.DeleteFunc(n.dynamic, func(dynamic *routeNode) bool { return dynamic == child })
+	}
+	return handlerPackage
+}
+
+func (n *routeNode) existingChild(segment routeSegment) *routeNode {
+	switch {
+	case segment.catchAll:
+		return n.catchAll
+	case segment.pattern == nil && segment.caseSensitive:
+		return n.exact[segment.literal]
+	case segment.pattern == nil:
+		return n.static[strings.ToLower(segment.literal)]
+	}
+	for _, child := range n.dynamic {
+		if child.pattern.String() == segment.pattern.String() {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *routeNode) empty() bool {
+	return len(n.handlers) == 0 && len(n.static) == 0 && len(n.exact) == 0 && len(n.dynamic) == 0 && n.catchAll == nil
+}
+
+// compareSpecificity is negative when n must be tried before other: segments with more
+// literal characters first, then segments with a constraint over the default pattern.
+func (n *routeNode) compareSpecificity(other *routeNode) int {
+	if n.literalLen != other.literalLen {
+		return other.literalLen - n.literalLen
+	}
+	if n.constrained != other.constrained {
+		if n.constrained {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// match walks the tree one path segment at a time, literal children are tried before
+// parametrized ones, catch-all ones come last. The walk backtracks when a branch has no
+// handler for the method.
+func (n *routeNode) match(method HttpMethod, path string, values []string, decode bool) (*RequestHandlerPackage, []string) {
+	segment, rest, hasRest := strings.Cut(path, "/")
+	literal := decodeSegment(segment, decode)
+
+	if child, found := n.exact[literal]; found {
+		if handlerPackage, captured := child.next(method, rest, hasRest, values, decode); handlerPackage != nil {
+			return handlerPackage, captured
+		}
+	}
+	if child, found := n.static[strings.ToLower(literal)]; found {
+		if handlerPackage, captured := child.next(method, rest, hasRest, values, decode); handlerPackage != nil {
+			return handlerPackage, captured
+		}
+	}
+
+	for _, child := range n.dynamic {
+		submatches := child.pattern.FindStringSubmatch(literal)
+		if submatches == nil {
+			continue
+		}
+		captured := values
+		for _, index := range child.captures {
+			captured = append(captured, submatches[index])
+		}
+		if handlerPackage, captured := child.next(method, rest, hasRest, captured, decode); handlerPackage != nil {
+			return handlerPackage, captured
+		}
+	}
+
+	if n.catchAll != nil {
+		if handlerPackage, found := n.catchAll.handlers[method]; found {
+			return handlerPackage, append(values, decodeSegment(path, decode))
+		}
+	}
+
+	return nil, nil
+}
+
+// matchEach is like match but yields every route matching the path, in precedence order,
+// until yield returns false.
+func (n *routeNode) matchEach(method HttpMethod, path string, values []string, decode bool, yield func(*RequestHandlerPackage, []string) bool) bool {
+	segment, rest, hasRest := strings.Cut(path, "/")
+	literal := decodeSegment(segment, decode)
+
+	for _, child := range []*routeNode{n.exact[literal], n.static[strings.ToLower(literal)]} {
+		if child != nil && !child.nextEach(method, rest, hasRest, values, decode, yield) {
+			return false
+		}
+	}
+
+	for _, child := range n.dynamic {
+		submatches := child.pattern.FindStringSubmatch(literal)
+		if submatches == nil {
+			continue
+		}
+		captured := slices.Clone(values)
+		for _, index := range child.captures {
+			captured = append(captured, submatches[index])
+		}
+		if !child.nextEach(method, rest, hasRest, captured, decode, yield) {
+			return false
+		}
+	}
+
+	if n.catchAll != nil {
+		if handlerPackage, found := n.catchAll.handlers[method]; found {
+			return yield(handlerPackage, append(slices.Clone(values), decodeSegment(path, decode)))
+		}
+	}
+	return true
+}
+
+func (n *routeNode) nextEach(method HttpMethod, rest string, hasRest bool, values []string, decode bool, yield func(*RequestHandlerPackage, []string) bool) bool {
+	if hasRest {
+		return n.matchEach(method, rest, values, decode, yield)
+	}
+	if handlerPackage, found := n.handlers[method]; found {
+		return yield(handlerPackage, values)
+	}
+	return true
+}
+
+func (n *routeNode) next(method HttpMethod, rest string, hasRest bool, values []string, decode bool) (*RequestHandlerPackage, []string) {
+	if hasRest {
+		return n.match(method, rest, values, decode)
+	}
+	if handlerPackage, found := n.handlers[method]; found {
+		return handlerPackage, values
+	}
+	return nil, nil
+}
+
+// collectMethods gathers the methods of every handler whose route matches the path.
+func (n *routeNode) collectMethods(path string, found map[HttpMethod]bool, decode bool) {
+	segment, rest, hasRest := strings.Cut(path, "/")
+	literal := decodeSegment(segment, decode)
+
+	children := []*routeNode{}
+	if child, exists := n.exact[literal]; exists {
+		children = append(children, child)
+	}
+	if child, exists := n.static[strings.ToLower(literal)]; exists {
+		children = append(children, child)
+	}
+	for _, child := range n.dynamic {
+		if child.pattern.MatchString(literal) {
+			children = append(children, child)
+		}
+	}
+
+	for _, child := range children {
+		if hasRest {
+			child.collectMethods(rest, found, decode)
+			continue
+		}
+		for method := range child.handlers {
+			found[method] = true
+		}
+	}
+
+	if n.catchAll != nil {
+		for method := range n.catchAll.handlers {
+			found[method] = true
+		}
+	}
+}
+
+func (n *routeNode) walk(fn func(method HttpMethod, handlerPackage *RequestHandlerPackage)) {
+	for method, handlerPackage := range n.handlers {
+		fn(method, handlerPackage)
+	}
+	for _, child := range n.static {
+		child.walk(fn)
+	}
+	for _, child := range n.exact {
+		child.walk(fn)
+	}
+	for _, child := range n.dynamic {
+		child.walk(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.walk(fn)
+	}
+}
+
+// decodeSegment percent-decodes a segment of an escaped path, segments are decoded only
+// after splitting so that an encoded slash stays inside its segment. Literal segments and
+// parameter patterns are both matched against the decoded segment, decoded exactly once.
+func decodeSegment(segment string, decode bool) string {
+	if !decode || strings.IndexByte(segment, '%') < 0 {
+		return segment
+	}
+	if decoded, err := url.PathUnescape(segment); err == nil {
+		return decoded
+	}
+	return segment
+}
+
+func newRouteNode(pattern *regexp.Regexp) *routeNode {
+	return &routeNode{
+		pattern:  pattern,
+		static:   make(map[string]*routeNode),
+		exact:    make(map[string]*routeNode),
+		handlers: make(map[HttpMethod]*RequestHandlerPackage),
+	}
+}
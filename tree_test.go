@@ -0,0 +1,443 @@
+package yagaw
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteTreeBacktracking(t *testing.T) {
+	router := NewRouter()
+
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+
+	router.RegisterRoute(GET, "/users/me/settings", handlerFor("settings"))
+	router.RegisterRoute(GET, "/users/{id}/posts", handlerFor("posts"))
+	router.RegisterRoute(POST, "/users/{id}/avatar", handlerFor("avatar"))
+	router.RegisterRoute(GET, "/users/{id}/{section}", handlerFor("section"))
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"literal branch", "/users/me/settings", "settings"},
+		{"literal branch without leaf falls back to parameter", "/users/me/posts", "posts"},
+		{"parameter branch", "/users/42/posts", "posts"},
+		{"handler registered for another method is skipped", "/users/42/avatar", "section"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rw.Code)
+			}
+			if rw.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouteTreeParamsInsideSegment(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "from") + ":" + PathParam(req, "to"))
+	}
+
+	router.RegisterRoute(GET, "/range/{from}-to-{to}", handler)
+
+	rw := httptest.NewRecorder()
+	router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/range/1-to-10", nil))
+
+	if rw.Body.String() != "1:10" {
+		t.Errorf("expected '1:10', got %q", rw.Body.String())
+	}
+}
+
+func BenchmarkServeHTTPPatternThousandRoutes(b *testing.B) {
+	router := NewRouter()
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+	for i := 0; i < 1000; i++ {
+		router.RegisterRoute(GET, fmt.Sprintf("/resource%d/{id}/items/{itemId}", i), handler)
+	}
+
+	req := httptest.NewRequest(string(GET), "/resource999/123/items/456", nil)
+	rw := httptest.NewRecorder()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(rw, req)
+	}
+}
+
+func TestCatchAllParameter(t *testing.T) {
+	router := NewRouter()
+
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + ":" + PathParam(req, "filepath"))
+		}
+	}
+
+	router.RegisterRoute(GET, "/static/{*filepath}", handlerFor("catch-all"))
+	router.RegisterRoute(GET, "/static/css/{filepath}", handlerFor("css"))
+	router.RegisterRoute(GET, "/static/favicon.ico", handlerFor("favicon"))
+	router.RegisterRoute(GET, "/repos/{owner}/{*filepath}", func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK).SetBody(PathParam(req, "owner") + ":" + PathParam(req, "filepath"))
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		expected string
+	}{
+		{"single segment", "/static/app.js", http.StatusOK, "catch-all:app.js"},
+		{"slashes included", "/static/js/vendor/lib.min.js", http.StatusOK, "catch-all:js/vendor/lib.min.js"},
+		{"empty remainder", "/static/", http.StatusOK, "catch-all:"},
+		{"more specific parameter wins", "/static/css/site", http.StatusOK, "css:site"},
+		{"static route wins", "/static/favicon.ico", http.StatusOK, "favicon:"},
+		{"catch-all after a deeper miss", "/static/css/site/print", http.StatusOK, "catch-all:css/site/print"},
+		{"alongside other parameters", "/repos/golang/src/net/http/server.go", http.StatusOK, "golang:src/net/http/server.go"},
+		{"prefix without slash", "/static", http.StatusNotFound, "404 - Page not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if rw.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestCatchAllParameterMustBeFinalSegment(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	for _, path := range []string{"/static/{*filepath}/edit", "/static/file-{*name}", "/static/{*name}.js"} {
+		t.Run(path, func(t *testing.T) {
+			if err := router.RegisterRoute(GET, path, handler).Err(); err == nil {
+				t.Errorf("expected an error registering %q", path)
+			}
+		})
+	}
+
+	if len(*router.RegisteredRoutes()) != 0 {
+		t.Error("invalid routes should not be registered")
+	}
+}
+
+func TestParameterConstraints(t *testing.T) {
+	router := NewRouter()
+
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + ":" + joinParams(req, "id", "slug", "code"))
+		}
+	}
+
+	router.RegisterRoute(GET, "/orders/{id:[0-9]+}", handlerFor("numeric"))
+	router.RegisterRoute(GET, "/orders/{slug:[a-z]+}", handlerFor("alpha"))
+	router.RegisterRoute(GET, "/items/{id:[0-9]+}/{slug}", handlerFor("mixed"))
+	router.RegisterRoute(GET, "/codes/{code:[A-Z]{2}(-[0-9]{2})?}/{id}", handlerFor("groups"))
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		expected string
+	}{
+		{"numeric constraint", "/orders/123", http.StatusOK, "numeric:123,,"},
+		{"other constraint on the same position", "/orders/abc", http.StatusOK, "alpha:,abc,"},
+		{"no constraint satisfied", "/orders/abc123", http.StatusNotFound, ""},
+		{"constrained and unconstrained params", "/items/42/blue-shirt", http.StatusOK, "mixed:42,blue-shirt,"},
+		{"constrained param rejects", "/items/forty-two/blue-shirt", http.StatusNotFound, ""},
+		{"groups inside constraints", "/codes/IT-39/7", http.StatusOK, "groups:7,,IT-39"},
+		{"nested quantifier braces", "/codes/ITA/7", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, rw.Code)
+			}
+			if tt.status == http.StatusOK && rw.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestParameterConstraintsInvalidRegex(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	err := router.RegisterRoute(GET, "/orders/{id:[0-9+}", handler).Err()
+	if err == nil {
+		t.Fatal("expected an error for an invalid constraint")
+	}
+	if !strings.Contains(err.Error(), "invalid constraint for parameter `id`") {
+		t.Errorf("expected the error to name the parameter, got %q", err.Error())
+	}
+}
+
+func joinParams(req *http.Request, names ...string) string {
+	values := make([]string, len(names))
+	for i, name := range names {
+		values[i] = PathParam(req, name)
+	}
+	return strings.Join(values, ",")
+}
+
+func TestTypedParameterConstraints(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	router.RegisterRoute(GET, "/users/{id:int}", handler)
+	router.RegisterRoute(GET, "/docs/{ref:uuid}", handler)
+	router.RegisterRoute(GET, "/tags/{name:alpha}", handler)
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/users/0", http.StatusOK},
+		{"/users/123", http.StatusOK},
+		{"/users/-5", http.StatusOK},
+		{"/users/abc", http.StatusNotFound},
+		{"/users/12a", http.StatusNotFound},
+		{"/docs/3f2504e0-4f89-11d3-9a0c-0305e82c3301", http.StatusOK},
+		{"/docs/3F2504E0-4F89-11D3-9A0C-0305E82C3301", http.StatusOK},
+		{"/docs/3f2504e0-4f89-11d3-9a0c", http.StatusNotFound},
+		{"/tags/golang", http.StatusOK},
+		{"/tags/go1", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rw.Code)
+			}
+		})
+	}
+
+	t.Run("declared types are recorded", func(t *testing.T) {
+		routes := *router.RegisteredRoutes()
+		if paramType := routes[GET]["/users/{id:int}"].ParamType("id"); paramType != "int" {
+			t.Errorf("expected type 'int', got %q", paramType)
+		}
+		if paramType := routes[GET]["/docs/{ref:uuid}"].ParamType("ref"); paramType != "uuid" {
+			t.Errorf("expected type 'uuid', got %q", paramType)
+		}
+	})
+}
+
+func TestTypedParameterConstraintsUnknownName(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(req *http.Request, params Params) *HttpResponse {
+		return NewHttpResponse(http.StatusOK)
+	}
+
+	err := router.RegisterRoute(GET, "/users/{id:integer}", handler).Err()
+	if err == nil {
+		t.Fatal("expected an error for an unknown constraint name")
+	}
+	if !strings.Contains(err.Error(), "unknown constraint `integer`") {
+		t.Errorf("unexpected error %q", err.Error())
+	}
+}
+
+func TestMatchingPrecedenceIsIndependentOfRegistrationOrder(t *testing.T) {
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+
+	routes := []string{
+		"/users/me",
+		"/users/{id}",
+		"/users/{id:[0-9]+}",
+		"/users/v{version}",
+		"/users/{*rest}",
+	}
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/users/me", "/users/me"},
+		{"/users/42", "/users/{id:[0-9]+}"},
+		{"/users/john", "/users/{id}"},
+		{"/users/v2", "/users/v{version}"},
+		{"/users/john/posts", "/users/{*rest}"},
+	}
+
+	// Every rotation of the registration order must give the same winners
+	for rotation := range routes {
+		router := NewRouter()
+		for i := range routes {
+			route := routes[(rotation+i)%len(routes)]
+			router.RegisterRoute(GET, route, handlerFor(route))
+		}
+
+		for _, tt := range tests {
+			for iteration := 0; iteration < 20; iteration++ {
+				rw := httptest.NewRecorder()
+				router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+				if rw.Body.String() != tt.expected {
+					t.Fatalf("rotation %d: expected %q to hit %q, got %q", rotation, tt.path, tt.expected, rw.Body.String())
+				}
+			}
+		}
+	}
+}
+
+func TestMatchingPrecedenceIsDecidedSegmentBySegment(t *testing.T) {
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+
+	routes := []string{"/{section}/settings", "/users/{id}", "/{*rest}"}
+	for _, order := range [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0}} {
+		router := NewRouter()
+		for _, i := range order {
+			router.RegisterRoute(GET, routes[i], handlerFor(routes[i]))
+		}
+
+		rw := httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/users/settings", nil))
+
+		if rw.Body.String() != "/users/{id}" {
+			t.Errorf("order %v: expected the literal first segment to win, got %q", order, rw.Body.String())
+		}
+
+		rw = httptest.NewRecorder()
+		router.ServeHTTP(rw, httptest.NewRequest(string(GET), "/admin/settings", nil))
+
+		if rw.Body.String() != "/{section}/settings" {
+			t.Errorf("order %v: expected the parameter route to win over the catch-all, got %q", order, rw.Body.String())
+		}
+	}
+}
+
+func TestRoutePriorityOverride(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + joinParams(req, "id", "slug"))
+		}
+	}
+
+	router := NewRouter()
+	router.RegisterRoute(GET, "/users/me", handler("me "))
+	router.RegisterRoute(GET, "/users/{id:int}", handler("user "))
+	router.RegisterRoute(GET, "/users/{slug}", handler("legacy ")).Priority(10)
+	router.RegisterRoute(GET, "/posts/{id}", handler("post "))
+	router.RegisterRoute(GET, "/posts/{*slug}", handler("archive ")).Priority(1)
+	router.RegisterRoute(GET, "/posts/latest", handler("latest "))
+	router.RegisterRoute(GET, "/tags/{id:int}", handler("tag "))
+	router.RegisterRoute(GET, "/tags/{slug}", handler("tag slug "))
+	router.RegisterRoute(GET, "/tags/{id:int}", handler("tag lower ")).Priority(-1)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/users/me", "legacy ,me"},
+		{"/users/42", "legacy ,42"},
+		{"/posts/7", "archive ,7"},
+		{"/posts/latest", "archive ,latest"},
+		{"/posts/a/b", "archive ,a/b"},
+		{"/tags/3", "tag slug ,3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouteTreeStableOrderForOverlappingPatterns(t *testing.T) {
+	handlerFor := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body)
+		}
+	}
+	newRouter := func(paths ...string) *Router {
+		router := NewRouter()
+		for _, path := range paths {
+			router.RegisterRoute(GET, path, handlerFor(path))
+		}
+		return router
+	}
+
+	tests := []struct {
+		paths    []string
+		path     string
+		expected string
+	}{
+		{[]string{"/items/{id:[0-9]+}", "/items/{code:[0-9a-z]+}"}, "/items/42", "/items/{id:[0-9]+}"},
+		{[]string{"/items/{code:[0-9a-z]+}", "/items/{id:[0-9]+}"}, "/items/42", "/items/{code:[0-9a-z]+}"},
+		{[]string{"/items/{a}-x", "/items/1-{b}", "/items/{c}"}, "/items/1-x", "/items/{a}-x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.paths, ","), func(t *testing.T) {
+			router := newRouter(tt.paths...)
+			for i := 0; i < 100; i++ {
+				if i%10 == 0 {
+					router = newRouter(tt.paths...)
+				}
+				rw := httptest.NewRecorder()
+				router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+				if rw.Body.String() != tt.expected {
+					t.Fatalf("expected %q to win at call %d, got %q", tt.expected, i, rw.Body.String())
+				}
+			}
+		})
+	}
+}
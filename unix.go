@@ -0,0 +1,57 @@
+package yagaw
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+)
+
+// DefaultUnixSocketPerm is the file mode of the sockets created for `unix://` addresses
+const DefaultUnixSocketPerm os.FileMode = 0o660
+
+// WithUnixSocket makes the server listen on the Unix socket at path instead of TCP, created
+// with the given file mode. A stale socket left at path by a previous run is removed first,
+// the socket is removed on shutdown.
+func (s *Server) WithUnixSocket(path string, perm os.FileMode) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unixSocket, s.socketPerm = path, perm
+	return s
+}
+
+func listenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// removeStaleSocket removes the socket at path unless a server still accepts connections
+// on it, files that are not sockets are left alone for net.Listen to fail on.
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil || info.Mode().Type() != fs.ModeSocket {
+		return err
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("listen unix %s: %w", path, syscall.EADDRINUSE)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,137 @@
+package yagaw
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// socketPath returns a path for a socket, short enough for the platform limit
+func socketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "yagaw")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "app.sock")
+}
+
+func TestServerUnixSocket(t *testing.T) {
+	start := func(t *testing.T, server *Server) chan error {
+		server.GetRouter().Use(func(next HttpRequestHandler) HttpRequestHandler {
+			return func(req *http.Request, params Params) *HttpResponse {
+				return next(req, params).SetHeader("X-Middleware", "true")
+			}
+		})
+		server.GetRouter().RegisterRoute(GET, "/users/{id}", func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody("user " + PathParam(req, "id"))
+		})
+		ran := make(chan error, 1)
+		go func() { ran <- server.Run() }()
+		awaitReady(t, server, ran)
+		return ran
+	}
+	get := func(t *testing.T, path string) (*http.Response, string) {
+		transport := &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		}}
+		defer transport.CloseIdleConnections()
+		res, err := (&http.Client{Transport: transport}).Get("http://app/users/42")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	tests := []struct {
+		name   string
+		server func(path string) *Server
+		perm   os.FileMode
+	}{
+		{"unix address", func(path string) *Server { return NewServer("unix://"+path, 0) }, DefaultUnixSocketPerm},
+		{"socket option", func(path string) *Server { return NewServer("", 0).WithUnixSocket(path, 0o600) }, 0o600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := socketPath(t)
+			server := tt.server(path)
+			ran := start(t, server)
+
+			res, body := get(t, path)
+			if body != "user 42" || res.Header.Get("X-Middleware") != "true" {
+				t.Errorf("expected the route through the middleware, got %q %v", body, res.Header)
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.Mode().Perm() != tt.perm {
+				t.Errorf("expected the socket mode %v, got %v %v", tt.perm, info.Mode().Perm(), err)
+			}
+
+			if err := server.Shutdown(context.Background()); err != nil {
+				t.Fatalf("unexpected shutdown error: %v", err)
+			}
+			if err := <-ran; err != nil {
+				t.Errorf("expected Run to return nil, got %v", err)
+			}
+			if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+				t.Errorf("expected the socket to be removed, got %v", err)
+			}
+		})
+	}
+
+	t.Run("stale socket", func(t *testing.T) {
+		path := socketPath(t)
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		listener.(*net.UnixListener).SetUnlinkOnClose(false)
+		listener.Close()
+
+		server := NewServer("unix://"+path, 0)
+		ran := start(t, server)
+		defer func() {
+			server.Shutdown(context.Background())
+			<-ran
+		}()
+		if _, body := get(t, path); body != "user 42" {
+			t.Errorf("expected the route to be served, got %q", body)
+		}
+	})
+
+	t.Run("socket in use", func(t *testing.T) {
+		path := socketPath(t)
+		listener, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer listener.Close()
+
+		if err := NewServer("unix://"+path, 0).Run(); !errors.Is(err, syscall.EADDRINUSE) {
+			t.Errorf("expected address already in use, got %v", err)
+		}
+	})
+
+	t.Run("regular file", func(t *testing.T) {
+		path := socketPath(t)
+		if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := NewServer("unix://"+path, 0).Run(); err == nil {
+			t.Error("expected the run to fail")
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != "data" {
+			t.Errorf("expected the file to be left alone, got %q %v", data, err)
+		}
+	})
+}
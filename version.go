@@ -0,0 +1,73 @@
+package yagaw
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Version registers the routes added by fn under the `/{version}` prefix, e.g. `/v2`. The
+// routes of the default version are also served without the prefix.
+func (r *Router) Version(version string, fn func(g *Group)) *Group {
+	version = strings.Trim(version, "/")
+	g := &Group{router: r, prefix: "/" + version, version: version}
+	if fn != nil {
+		fn(g)
+	}
+	return g
+}
+
+// DefaultVersion makes the routes registered through Version for the given version also
+// reachable without the version prefix, routes registered without the prefix still win.
+func (r *Router) DefaultVersion(version string) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultVersion = strings.Trim(version, "/")
+	return r
+}
+
+// Deprecated marks the routes of the group and of its nested groups as deprecated, the
+// router adds a `Deprecation: true` header to their responses.
+func (g *Group) Deprecated() *Group {
+	g.router.mu.Lock()
+	defer g.router.mu.Unlock()
+	g.deprecated = true
+	return g
+}
+
+// matchDefaultVersion matches the path as if it had the default version prefix, only the
+// routes registered for that version are considered.
+func (r *Router) matchDefaultVersion(req *http.Request, method HttpMethod, path string) (routeMatch, bool) {
+	if r.defaultVersion == "" {
+		return routeMatch{}, false
+	}
+	match, found := r.match(req, method, joinPaths("/"+r.defaultVersion, path))
+	if !found || match.handlerPackage.routeVersion() != r.defaultVersion {
+		return routeMatch{}, false
+	}
+	return match, true
+}
+
+// routeVersion returns the version of the innermost version group of the route
+func (p *RequestHandlerPackage) routeVersion() string {
+	if p.version != "" {
+		return p.version
+	}
+	for group := p.group; group != nil; group = group.parent {
+		if group.version != "" {
+			return group.version
+		}
+	}
+	return ""
+}
+
+func (p *RequestHandlerPackage) isDeprecated() bool {
+	if p.deprecated {
+		return true
+	}
+	for group := p.group; group != nil; group = group.parent {
+		if group.deprecated {
+			return true
+		}
+	}
+	return false
+}
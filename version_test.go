@@ -0,0 +1,80 @@
+package yagaw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionGroups(t *testing.T) {
+	handler := func(body string) HttpRequestHandler {
+		return func(req *http.Request, params Params) *HttpResponse {
+			return NewHttpResponse(http.StatusOK).SetBody(body + PathParam(req, "id"))
+		}
+	}
+
+	router := NewRouter().DefaultVersion("v2")
+	router.Version("v1", func(g *Group) {
+		g.RegisterRoute(GET, "/users/{id}", handler("v1 user "))
+		g.RegisterRoute(GET, "/legacy", handler("v1 legacy"))
+	}).Deprecated()
+	router.Version("/v2/", func(g *Group) {
+		g.RegisterRoute(GET, "/users/{id}", handler("v2 user "))
+		g.RegisterRoute(GET, "/status", handler("v2 status"))
+		g.Group("/admin").RegisterRoute(GET, "/stats", handler("v2 stats"))
+	})
+	router.RegisterRoute(GET, "/status", handler("unversioned status"))
+	router.RegisterRoute(GET, "/v2/manual", handler("manual"))
+
+	tests := []struct {
+		path        string
+		status      int
+		body        string
+		deprecation string
+	}{
+		{"/v1/users/7", http.StatusOK, "v1 user 7", "true"},
+		{"/v2/users/7", http.StatusOK, "v2 user 7", ""},
+		{"/users/7", http.StatusOK, "v2 user 7", ""},
+		{"/admin/stats", http.StatusOK, "v2 stats", ""},
+		{"/status", http.StatusOK, "unversioned status", ""},
+		{"/v2/status", http.StatusOK, "v2 status", ""},
+		{"/legacy", http.StatusNotFound, "404 - Page not found", ""},
+		{"/manual", http.StatusNotFound, "404 - Page not found", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			router.ServeHTTP(rw, httptest.NewRequest(string(GET), tt.path, nil))
+
+			if rw.Code != tt.status || rw.Body.String() != tt.body {
+				t.Errorf("expected %d %q, got %d %q", tt.status, tt.body, rw.Code, rw.Body.String())
+			}
+			if deprecation := rw.Header().Get("Deprecation"); deprecation != tt.deprecation {
+				t.Errorf("expected Deprecation %q, got %q", tt.deprecation, deprecation)
+			}
+		})
+	}
+
+	versions := map[string]string{}
+	for _, route := range router.Routes() {
+		versions[route.Path] = route.Version
+		if route.Deprecated != (route.Version == "v1") {
+			t.Errorf("expected only the v1 routes to be deprecated, got %+v", route)
+		}
+	}
+	expected := map[string]string{
+		"/v1/users/{id}":  "v1",
+		"/v1/legacy":      "v1",
+		"/v2/users/{id}":  "v2",
+		"/v2/status":      "v2",
+		"/v2/admin/stats": "v2",
+		"/status":         "",
+		"/v2/manual":      "",
+	}
+	for path, version := range expected {
+		if versions[path] != version {
+			t.Errorf("expected %s to belong to version %q, got %q", path, version, versions[path])
+		}
+	}
+}